@@ -0,0 +1,119 @@
+// Package gql 提供图书领域的 GraphQL 接口
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+var ProviderSet = wire.NewSet(NewHandler)
+
+// errMutationOverGET 表示用 GET 请求执行 mutation。GET 可能被缓存、预取，
+// 也可以由其他网站的链接或图片直接触发，mutation 只能用 POST
+var errMutationOverGET = errors.New("mutations must be sent with POST")
+
+type request struct {
+	Query         string                 `json:"query" form:"query"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler 处理 GET 和 POST 的 GraphQL 请求，GET 只能执行查询
+type Handler struct {
+	Schema      graphql.Schema
+	BookService service.BookService
+	// MaxDepth 和 MaxComplexity 为 0 时不限制
+	MaxDepth      int
+	MaxComplexity int
+}
+
+func NewHandler(cfg config.Config, b service.BookService) (*Handler, error) {
+	schema, err := NewSchema(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		Schema:        schema,
+		BookService:   b,
+		MaxDepth:      cfg.GraphQLMaxDepth,
+		MaxComplexity: cfg.GraphQLMaxComplexity,
+	}, nil
+}
+
+func (h *Handler) Serve(c *gin.Context) {
+	var req request
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if v := c.Query("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, errorResult(err))
+				return
+			}
+		}
+	} else if err := c.BindJSON(&req); err != nil {
+		return
+	}
+
+	// 和 graphql.Do 的流程一样，只是在校验之后、执行之前加上深度和复杂度检查
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if v := graphql.ValidateDocument(&h.Schema, doc, nil); !v.IsValid {
+		c.JSON(http.StatusBadRequest, &graphql.Result{Errors: v.Errors})
+		return
+	}
+	if c.Request.Method == http.MethodGet && isMutation(doc, req.OperationName) {
+		c.Header("Allow", http.MethodPost)
+		c.JSON(http.StatusMethodNotAllowed, errorResult(errMutationOverGET))
+		return
+	}
+	if err := checkLimits(doc, req.OperationName, req.Variables, h.MaxDepth, h.MaxComplexity); err != nil {
+		c.JSON(http.StatusBadRequest, errorResult(err))
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.Schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoaders(c.Request.Context(), h.BookService),
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+// isMutation 判断请求要执行的是不是 mutation，没有指定 operationName 时文档里有 mutation 就算
+func isMutation(doc *ast.Document, operationName string) bool {
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok || op.Operation != ast.OperationTypeMutation {
+			continue
+		}
+		if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+			return true
+		}
+	}
+	return false
+}
+
+func errorResult(err error) *graphql.Result {
+	return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+}
@@ -0,0 +1,121 @@
+package gql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// listCosts 是没有 first 参数的列表字段的预估长度，用于计算复杂度
+var listCosts = map[string]int{
+	"books":       defaultPageSize,
+	"history":     10,
+	"changes":     5,
+	"adjustments": 5,
+}
+
+// queryCost 在执行前计算操作的深度和复杂度。每个字段的复杂度是 1 加上子字段复杂度乘以列表长度，
+// 列表长度优先取 first 参数。内省字段不计入
+type queryCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// checkLimits 在文档通过校验后调用，超出限制时返回错误
+func checkLimits(doc *ast.Document, operationName string, variables map[string]interface{},
+	maxDepth, maxComplexity int) error {
+	q := queryCost{fragments: make(map[string]*ast.FragmentDefinition), variables: variables}
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.FragmentDefinition:
+			q.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				op = d
+			}
+		}
+	}
+	if op == nil {
+		return nil
+	}
+
+	complexity, depth := q.selectionSet(op.SelectionSet)
+	if maxDepth > 0 && depth > maxDepth {
+		return fmt.Errorf("query depth %d exceeds limit %d", depth, maxDepth)
+	}
+	if maxComplexity > 0 && complexity > maxComplexity {
+		return fmt.Errorf("query complexity %d exceeds limit %d", complexity, maxComplexity)
+	}
+	return nil
+}
+
+func (q queryCost) selectionSet(set *ast.SelectionSet) (complexity, depth int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, sel := range set.Selections {
+		var c, d int
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			c, d = q.selectionSet(s.SelectionSet)
+			c, d = 1+q.listSize(s)*c, d+1
+		case *ast.InlineFragment:
+			c, d = q.selectionSet(s.SelectionSet)
+		case *ast.FragmentSpread:
+			// 校验阶段已经排除了片段循环
+			if f, ok := q.fragments[s.Name.Value]; ok {
+				c, d = q.selectionSet(f.SelectionSet)
+			}
+		}
+		complexity += c
+		if d > depth {
+			depth = d
+		}
+	}
+	return complexity, depth
+}
+
+// listSize 返回字段的预估列表长度，first 限制在 [0, maxPageSize] 内，
+// 超出范围的 first 在执行时会被拒绝，不能让复杂度变成负数或者溢出
+func (q queryCost) listSize(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil {
+				return clampPageSize(n)
+			}
+		case *ast.Variable:
+			switch n := q.variables[v.Name.Value].(type) {
+			case float64:
+				// 先在浮点数上限制范围，过大的值转换成 int 会溢出
+				return int(math.Max(math.Min(n, maxPageSize), 0))
+			case int:
+				return clampPageSize(n)
+			}
+		}
+	}
+	if n, ok := listCosts[field.Name.Value]; ok {
+		return n
+	}
+	return 1
+}
+
+func clampPageSize(n int) int {
+	if n < 0 {
+		return 0
+	}
+	if n > maxPageSize {
+		return maxPageSize
+	}
+	return n
+}
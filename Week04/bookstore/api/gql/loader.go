@@ -0,0 +1,126 @@
+package gql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+// loader 把同一层字段的 Load 合并成一次 fetch，结果在请求内缓存。
+// resolver 返回 Load 得到的 thunk，graphql 会在同一层所有字段都解析完后才调用 thunk，
+// 第一个被调用的 thunk 负责取回当时积攒的所有 key
+type loader struct {
+	fetch func(keys []interface{}) (map[interface{}]interface{}, error)
+
+	mu      sync.Mutex
+	pending []interface{}
+	results map[interface{}]interface{}
+	errs    map[interface{}]error
+}
+
+func newLoader(fetch func(keys []interface{}) (map[interface{}]interface{}, error)) *loader {
+	return &loader{
+		fetch:   fetch,
+		results: make(map[interface{}]interface{}),
+		errs:    make(map[interface{}]error),
+	}
+}
+
+func (l *loader) Load(key interface{}) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.results[key]; !ok {
+		l.pending = append(l.pending, key)
+		// 先占位，同一个 key 不会重复进入 pending
+		l.results[key] = nil
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if len(l.pending) > 0 {
+			keys := l.pending
+			l.pending = nil
+			values, err := l.fetch(keys)
+			for _, k := range keys {
+				l.results[k], l.errs[k] = values[k], err
+			}
+		}
+		return l.results[key], l.errs[key]
+	}
+}
+
+// loaders 是一次请求内共用的 loader
+type loaders struct {
+	mu      sync.Mutex
+	books   map[uint]model.Book
+	history *loader
+	quotes  *loader
+}
+
+type quoteKey struct {
+	bookID uint
+	coupon string
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, bookService service.BookService) context.Context {
+	l := &loaders{books: make(map[uint]model.Book)}
+
+	l.history = newLoader(func(keys []interface{}) (map[interface{}]interface{}, error) {
+		books := make([]model.Book, len(keys))
+		for i, k := range keys {
+			books[i] = l.book(k.(uint))
+		}
+		byBook, err := bookService.Histories(books)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[interface{}]interface{}, len(keys))
+		for _, k := range keys {
+			values[k] = byBook[k.(uint)]
+		}
+		return values, nil
+	})
+
+	l.quotes = newLoader(func(keys []interface{}) (map[interface{}]interface{}, error) {
+		byCoupon := make(map[string][]model.Book)
+		for _, k := range keys {
+			qk := k.(quoteKey)
+			byCoupon[qk.coupon] = append(byCoupon[qk.coupon], l.book(qk.bookID))
+		}
+		values := make(map[interface{}]interface{}, len(keys))
+		for coupon, books := range byCoupon {
+			quotes, err := bookService.Quotes(ctx, books, coupon)
+			if err != nil {
+				return nil, err
+			}
+			for i, q := range quotes {
+				values[quoteKey{bookID: books[i].ID, coupon: coupon}] = q
+			}
+		}
+		return values, nil
+	})
+
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// remember 记下 resolver 见过的图书，loader 批量取数时使用
+func (l *loaders) remember(book model.Book) {
+	l.mu.Lock()
+	l.books[book.ID] = book
+	l.mu.Unlock()
+}
+
+func (l *loaders) book(id uint) model.Book {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.books[id]
+}
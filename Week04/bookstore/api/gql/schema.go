@@ -0,0 +1,412 @@
+package gql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/pricing"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+const (
+	// defaultPageSize 是 books 没有传 first 时的分页大小
+	defaultPageSize = 20
+	// maxPageSize 是 books 的 first 上限
+	maxPageSize = 100
+)
+
+var (
+	errInvalidID     = errors.New("invalid id")
+	errInvalidCursor = errors.New("invalid cursor")
+	errBookNotFound  = errors.New("book not found")
+)
+
+type bookEdge struct {
+	cursor string
+	book   model.Book
+}
+
+type bookConnection struct {
+	edges       []bookEdge
+	hasNextPage bool
+}
+
+// resolve 把只依赖 source 的取值函数包装成 resolver
+func resolve(get func(source interface{}) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source), nil
+	}
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func encodeCursor(id uint) string {
+	return base64.URLEncoding.EncodeToString([]byte("book:" + strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCursor(cursor string) (uint, error) {
+	b, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(b), "book:") {
+		return 0, errInvalidCursor
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(string(b), "book:"), 10, 64)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	return uint(id), nil
+}
+
+func parseID(v interface{}) (uint, error) {
+	s, _ := v.(string)
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errInvalidID
+	}
+	return uint(id), nil
+}
+
+// jsonString 把变更记录里任意类型的值编码成 JSON 字符串
+func jsonString(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+// NewSchema 构建图书的 GraphQL schema，所有操作都限定在 context 中的店铺内
+func NewSchema(books service.BookService) (graphql.Schema, error) {
+	fieldChangeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FieldChange",
+		Fields: graphql.Fields{
+			"field":  {Type: graphql.NewNonNull(graphql.String), Resolve: resolve(func(s interface{}) interface{} { return s.(model.FieldChange).Field })},
+			"before": {Type: graphql.String, Description: "变更前的值，JSON 编码", Resolve: resolve(func(s interface{}) interface{} { return jsonString(s.(model.FieldChange).Before) })},
+			"after":  {Type: graphql.String, Description: "变更后的值，JSON 编码", Resolve: resolve(func(s interface{}) interface{} { return jsonString(s.(model.FieldChange).After) })},
+		},
+	})
+
+	revisionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Revision",
+		Fields: graphql.Fields{
+			"version":   {Type: graphql.NewNonNull(graphql.Int), Resolve: resolve(func(s interface{}) interface{} { return s.(dto.RevisionDTO).Version })},
+			"action":    {Type: graphql.NewNonNull(graphql.String), Resolve: resolve(func(s interface{}) interface{} { return s.(dto.RevisionDTO).Action })},
+			"actor":     {Type: graphql.NewNonNull(graphql.String), Resolve: resolve(func(s interface{}) interface{} { return s.(dto.RevisionDTO).Actor })},
+			"createdAt": {Type: graphql.NewNonNull(graphql.String), Resolve: resolve(func(s interface{}) interface{} { return formatTime(s.(dto.RevisionDTO).CreatedAt) })},
+			"changes": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(fieldChangeType))),
+				Resolve: resolve(func(s interface{}) interface{} {
+					changes := s.(dto.RevisionDTO).Changes
+					list := make([]interface{}, len(changes))
+					for i, c := range changes {
+						list[i] = c
+					}
+					return list
+				}),
+			},
+		},
+	})
+
+	adjustmentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Adjustment",
+		Fields: graphql.Fields{
+			"ruleId":   {Type: graphql.NewNonNull(graphql.ID), Resolve: resolve(func(s interface{}) interface{} { return strconv.FormatUint(uint64(s.(pricing.Adjustment).RuleID), 10) })},
+			"name":     {Type: graphql.NewNonNull(graphql.String), Resolve: resolve(func(s interface{}) interface{} { return s.(pricing.Adjustment).Name })},
+			"kind":     {Type: graphql.NewNonNull(graphql.String), Resolve: resolve(func(s interface{}) interface{} { return s.(pricing.Adjustment).Kind })},
+			"amount":   {Type: graphql.NewNonNull(graphql.Float), Resolve: resolve(func(s interface{}) interface{} { return s.(pricing.Adjustment).Amount })},
+			"discount": {Type: graphql.NewNonNull(graphql.Float), Resolve: resolve(func(s interface{}) interface{} { return s.(pricing.Adjustment).Discount })},
+		},
+	})
+
+	quoteType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Quote",
+		Fields: graphql.Fields{
+			"basePrice":      {Type: graphql.NewNonNull(graphql.Float), Resolve: resolve(func(s interface{}) interface{} { return s.(pricing.Quote).BasePrice })},
+			"effectivePrice": {Type: graphql.NewNonNull(graphql.Float), Resolve: resolve(func(s interface{}) interface{} { return s.(pricing.Quote).EffectivePrice })},
+			"adjustments": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(adjustmentType))),
+				Resolve: resolve(func(s interface{}) interface{} {
+					adjustments := s.(pricing.Quote).Adjustments
+					list := make([]interface{}, len(adjustments))
+					for i, a := range adjustments {
+						list[i] = a
+					}
+					return list
+				}),
+			},
+		},
+	})
+
+	bookType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Book",
+		Fields: graphql.Fields{
+			"id":          {Type: graphql.NewNonNull(graphql.ID), Resolve: resolve(func(s interface{}) interface{} { return strconv.FormatUint(uint64(s.(model.Book).ID), 10) })},
+			"isbn":        {Type: graphql.NewNonNull(graphql.String), Resolve: resolve(func(s interface{}) interface{} { return s.(model.Book).ISBN })},
+			"title":       {Type: graphql.String, Resolve: resolve(func(s interface{}) interface{} { return s.(model.Book).Title })},
+			"description": {Type: graphql.String, Resolve: resolve(func(s interface{}) interface{} { return s.(model.Book).Description })},
+			"author":      {Type: graphql.String, Resolve: resolve(func(s interface{}) interface{} { return s.(model.Book).Author })},
+			"publisher":   {Type: graphql.String, Resolve: resolve(func(s interface{}) interface{} { return s.(model.Book).Publisher })},
+			"category":    {Type: graphql.String, Resolve: resolve(func(s interface{}) interface{} { return s.(model.Book).Category })},
+			"price":       {Type: graphql.NewNonNull(graphql.Float), Resolve: resolve(func(s interface{}) interface{} { return s.(model.Book).Price })},
+			"coverUpdatedAt": {Type: graphql.String, Resolve: resolve(func(s interface{}) interface{} {
+				if t := s.(model.Book).CoverUpdatedAt; t != nil {
+					return formatTime(*t)
+				}
+				return nil
+			})},
+			"averageRating": {Type: graphql.NewNonNull(graphql.Float), Resolve: resolve(func(s interface{}) interface{} { return s.(model.Book).AverageRating() })},
+			"ratingCount":   {Type: graphql.NewNonNull(graphql.Int), Resolve: resolve(func(s interface{}) interface{} { return s.(model.Book).RatingCount })},
+			"createdAt":     {Type: graphql.NewNonNull(graphql.String), Resolve: resolve(func(s interface{}) interface{} { return formatTime(s.(model.Book).CreatedAt) })},
+			"updatedAt":     {Type: graphql.NewNonNull(graphql.String), Resolve: resolve(func(s interface{}) interface{} { return formatTime(s.(model.Book).UpdatedAt) })},
+			"history": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(revisionType))),
+				Description: "按版本排序的变更记录",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					book := p.Source.(model.Book)
+					l := loadersFrom(p.Context)
+					l.remember(book)
+					thunk := l.history.Load(book.ID)
+					return func() (interface{}, error) {
+						v, err := thunk()
+						if err != nil {
+							return nil, err
+						}
+						revs, _ := v.([]model.BookRevision)
+						list := make([]interface{}, len(revs))
+						for i, rev := range revs {
+							list[i] = dto.ToRevisionDTO(rev)
+						}
+						return list, nil
+					}, nil
+				},
+			},
+			"quote": {
+				Type:        graphql.NewNonNull(quoteType),
+				Description: "按当前生效的优惠规则计算的报价",
+				Args: graphql.FieldConfigArgument{
+					"coupon": {Type: graphql.String, DefaultValue: ""},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					book := p.Source.(model.Book)
+					coupon, _ := p.Args["coupon"].(string)
+					l := loadersFrom(p.Context)
+					l.remember(book)
+					return l.quotes.Load(quoteKey{bookID: book.ID, coupon: coupon}), nil
+				},
+			},
+		},
+	})
+
+	bookEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BookEdge",
+		Fields: graphql.Fields{
+			"cursor": {Type: graphql.NewNonNull(graphql.String), Resolve: resolve(func(s interface{}) interface{} { return s.(bookEdge).cursor })},
+			"node":   {Type: graphql.NewNonNull(bookType), Resolve: resolve(func(s interface{}) interface{} { return s.(bookEdge).book })},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": {Type: graphql.NewNonNull(graphql.Boolean), Resolve: resolve(func(s interface{}) interface{} { return s.(bookConnection).hasNextPage })},
+			"endCursor": {Type: graphql.String, Resolve: resolve(func(s interface{}) interface{} {
+				edges := s.(bookConnection).edges
+				if len(edges) == 0 {
+					return nil
+				}
+				return edges[len(edges)-1].cursor
+			})},
+		},
+	})
+
+	bookConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BookConnection",
+		Fields: graphql.Fields{
+			"edges": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookEdgeType))),
+				Resolve: resolve(func(s interface{}) interface{} {
+					edges := s.(bookConnection).edges
+					list := make([]interface{}, len(edges))
+					for i, e := range edges {
+						list[i] = e
+					}
+					return list
+				}),
+			},
+			"pageInfo": {Type: graphql.NewNonNull(pageInfoType), Resolve: resolve(func(s interface{}) interface{} { return s })},
+			"totalCount": {
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return books.Count(p.Context)
+				},
+			},
+		},
+	})
+
+	bookInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "BookInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"isbn":        {Type: graphql.NewNonNull(graphql.String)},
+			"title":       {Type: graphql.String},
+			"description": {Type: graphql.String},
+			"author":      {Type: graphql.String},
+			"publisher":   {Type: graphql.String},
+			"category":    {Type: graphql.String},
+			"price":       {Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"book": {
+				Type: bookType,
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := parseID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					book, err := books.GetByID(p.Context, id)
+					if err == repository.ErrNotFound {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, err
+					}
+					return book, nil
+				},
+			},
+			"books": {
+				Type:        graphql.NewNonNull(bookConnectionType),
+				Description: "按 ID 顺序分页的图书列表",
+				Args: graphql.FieldConfigArgument{
+					"first": {Type: graphql.Int, DefaultValue: defaultPageSize},
+					"after": {Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					first, _ := p.Args["first"].(int)
+					if first < 0 || first > maxPageSize {
+						return nil, errors.New("first must be between 0 and " + strconv.Itoa(maxPageSize))
+					}
+					var afterID uint
+					if after, ok := p.Args["after"].(string); ok && after != "" {
+						var err error
+						if afterID, err = decodeCursor(after); err != nil {
+							return nil, err
+						}
+					}
+					// 多取一本用来判断是否还有下一页
+					list, err := books.List(p.Context, afterID, first+1)
+					if err != nil {
+						return nil, err
+					}
+					conn := bookConnection{hasNextPage: len(list) > first}
+					if conn.hasNextPage {
+						list = list[:first]
+					}
+					for _, book := range list {
+						conn.edges = append(conn.edges, bookEdge{cursor: encodeCursor(book.ID), book: book})
+					}
+					return conn, nil
+				},
+			},
+		},
+	})
+
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createBook": {
+				Type: graphql.NewNonNull(bookType),
+				Args: graphql.FieldConfigArgument{
+					"input": {Type: graphql.NewNonNull(bookInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					book := applyInput(model.Book{}, p.Args["input"])
+					return books.Save(p.Context, book)
+				},
+			},
+			"updateBook": {
+				Type: graphql.NewNonNull(bookType),
+				Args: graphql.FieldConfigArgument{
+					"id":    {Type: graphql.NewNonNull(graphql.ID)},
+					"input": {Type: graphql.NewNonNull(bookInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := parseID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					book, err := books.GetByID(p.Context, id)
+					if err == repository.ErrNotFound {
+						return nil, errBookNotFound
+					}
+					if err != nil {
+						return nil, err
+					}
+					return books.Save(p.Context, applyInput(book, p.Args["input"]))
+				},
+			},
+			"deleteBook": {
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := parseID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					book, err := books.GetByID(p.Context, id)
+					if err == repository.ErrNotFound {
+						return false, nil
+					}
+					if err != nil {
+						return nil, err
+					}
+					if err := books.Delete(p.Context, book); err != nil {
+						return nil, err
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    queryType,
+		Mutation: mutationType,
+	})
+}
+
+// applyInput 用 BookInput 覆盖图书的业务字段，没有传的可选字段会被清空，和 REST 的 PUT 一致
+func applyInput(book model.Book, v interface{}) model.Book {
+	input, _ := v.(map[string]interface{})
+	str := func(key string) string {
+		s, _ := input[key].(string)
+		return s
+	}
+	book.ISBN = str("isbn")
+	book.Title = str("title")
+	book.Description = str("description")
+	book.Author = str("author")
+	book.Publisher = str("publisher")
+	book.Category = str("category")
+	if price, ok := input["price"].(float64); ok {
+		book.Price = float32(price)
+	}
+	return book
+}
@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/breaker"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+var (
+	errBookNotFound = i18n.NewError("book.not_found")
+	errInternal     = errors.New(http.StatusText(http.StatusInternalServerError))
+)
+
+// Batch 在一个事务中按顺序执行一组图书的新建、修改和删除，返回每个操作的结果。
+// atomic 模式下有操作失败时整个请求返回这个操作的状态码，best_effort 模式总是返回 200
+func (b *BookAPI) Batch(c *gin.Context) {
+	var req dto.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	ops := make([]service.BookOperation, len(req.Operations))
+	for i, op := range req.Operations {
+		ops[i] = service.BookOperation{Action: op.Op, ID: op.ID}
+		if op.Book != nil {
+			book := dto.ToBook(*op.Book)
+			ops[i].Book = &book
+		}
+	}
+	atomic := req.Mode != dto.BatchBestEffort
+	results, err := b.BookService.Batch(c.Request.Context(), ops, atomic)
+	if err == service.ErrInvalidBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c,
+			i18n.NewError("batch.invalid_size", b.BookService.MaxBatchOperations))})
+		return
+	}
+
+	status := http.StatusOK
+	if err != nil {
+		status, _ = batchStatus(err, "")
+		if status >= http.StatusInternalServerError {
+			internalError(c, err)
+			return
+		}
+	}
+
+	locales := i18n.LocalesFromContext(c.Request.Context())
+	resp := make([]dto.BatchResultDTO, len(results))
+	for i, result := range results {
+		resp[i] = dto.BatchResultDTO{Index: i, Op: req.Operations[i].Op}
+		var opErr error
+		resp[i].Status, opErr = batchStatus(result.Err, req.Operations[i].Op)
+		if resp[i].Status == http.StatusInternalServerError {
+			log.Println(result.Err)
+		}
+		if result.Err == nil {
+			bookDTO := dto.ToBookDTO(result.Book)
+			resp[i].Book = &bookDTO
+			continue
+		}
+		resp[i].Error, _ = i18n.Localize(locales, opErr)
+	}
+	mode := dto.BatchAtomic
+	if !atomic {
+		mode = dto.BatchBestEffort
+	}
+	c.JSON(status, gin.H{"mode": mode, "committed": err == nil, "results": resp})
+}
+
+// batchStatus 返回单个操作的结果对应的状态码和返回给客户端的错误，未知错误不返回细节
+func batchStatus(err error, op string) (int, error) {
+	var open *breaker.OpenError
+	switch {
+	case err == nil && op == model.ActionCreate:
+		return http.StatusCreated, nil
+	case err == nil:
+		return http.StatusOK, nil
+	case err == service.ErrBatchAborted:
+		return http.StatusFailedDependency, err
+	case err == service.ErrInvalidOperation:
+		return http.StatusBadRequest, err
+	case err == repository.ErrNotFound:
+		return http.StatusNotFound, errBookNotFound
+	case err == service.ErrBookQuotaExceeded:
+		return http.StatusForbidden, err
+	case errors.As(err, &open):
+		return http.StatusServiceUnavailable, err
+	default:
+		return http.StatusInternalServerError, errInternal
+	}
+}
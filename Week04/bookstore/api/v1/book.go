@@ -5,36 +5,152 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
 
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
 )
 
+var errInvalidCategoryFilter = i18n.NewError("book.invalid_category_filter")
+
 type BookAPI struct {
-	BookService service.BookService
+	BookService     service.BookService
+	CategoryService service.CategoryService
+	// TranslationService 按 Accept-Language 替换 GetAll 和 GetByID 返回的书名和简介
+	TranslationService service.TranslationService
+	// Encoders 决定 GetAll、GetByID 和 Create 的响应格式
+	Encoders *BookEncoders
+}
+
+func NewBookAPI(b service.BookService, c service.CategoryService, t service.TranslationService,
+	enc *BookEncoders) BookAPI {
+	return BookAPI{BookService: b, CategoryService: c, TranslationService: t, Encoders: enc}
 }
 
-func NewBookAPI(b service.BookService) BookAPI {
-	return BookAPI{BookService: b}
+// negotiate 根据 Accept 请求头选择编码器，不支持时直接返回 406。
+// 响应格式随 Accept 变化，包括 406 和 304 在内都要告诉缓存
+func (b *BookAPI) negotiate(c *gin.Context) (negotiated, bool) {
+	c.Writer.Header().Add("Vary", "Accept")
+	enc, mediaType, ok := b.Encoders.Negotiate(c.GetHeader("Accept"))
+	if !ok {
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": localize(c, i18n.NewError("request.unacceptable", strings.Join(b.Encoders.Types(), ", ")))})
+	}
+	return negotiated{BookEncoder: enc, mediaType: mediaType}, ok
 }
 
+type negotiated struct {
+	BookEncoder
+	mediaType string
+}
+
+// render 只给文本格式加上 charset，MessagePack 这样的二进制格式没有字符集
+func (n negotiated) render(c *gin.Context, r render.Render) {
+	contentType := n.mediaType
+	if textual(n.mediaType) {
+		contentType += "; charset=utf-8"
+	}
+	c.Header("Content-Type", contentType)
+	c.Render(http.StatusOK, r)
+}
+
+// GetAll 返回店铺的所有图书，?category=1,2 只返回属于这些分类子树的图书
 func (b *BookAPI) GetAll(c *gin.Context) {
-	books := b.BookService.GetAll()
+	enc, ok := b.negotiate(c)
+	if !ok {
+		return
+	}
+	var books []model.Book
+	var err error
+	if v := c.Query("category"); v != "" {
+		ids, ok := parseIDs(strings.Split(v, ","))
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, errInvalidCategoryFilter)})
+			return
+		}
+		books, err = b.CategoryService.Books(c.Request.Context(), ids)
+	} else {
+		books, err = b.BookService.GetAll(c.Request.Context())
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"books": dto.ToBookDTOs(books)})
+	lastModified, err := b.BookService.LastModified(c.Request.Context())
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	l, err := b.TranslationService.Localize(c.Request.Context(), books)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if l.LastModified.After(lastModified) {
+		lastModified = l.LastModified
+	}
+	setContentLanguage(c, l.Locales)
+	bookDTOs := dto.ToBookDTOs(l.Books)
+	etag, err := contentETag(lastModified, enc.mediaType, bookDTOs)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if notModified(c, etag, lastModified) {
+		return
+	}
+	enc.render(c, enc.Books(bookDTOs))
 }
 
 func (b *BookAPI) GetByID(c *gin.Context) {
+	enc, ok := b.negotiate(c)
+	if !ok {
+		return
+	}
 	id, _ := strconv.Atoi(c.Param("id"))
-	book := b.BookService.GetByID(uint(id))
+	book, err := b.BookService.GetByID(c.Request.Context(), uint(id))
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"book": dto.ToBookDTO(book)})
+	l, err := b.TranslationService.Localize(c.Request.Context(), []model.Book{book})
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	lastModified := book.UpdatedAt
+	if l.LastModified.After(lastModified) {
+		lastModified = l.LastModified
+	}
+	setContentLanguage(c, l.Locales)
+	bookDTO := dto.ToBookDTO(l.Books[0])
+	etag, err := contentETag(lastModified, enc.mediaType, bookDTO)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if notModified(c, etag, lastModified) {
+		return
+	}
+	enc.render(c, enc.Book(bookDTO))
 }
 
 func (b *BookAPI) Create(c *gin.Context) {
+	enc, ok := b.negotiate(c)
+	if !ok {
+		return
+	}
 	var bookDTO dto.BookDTO
 	err := c.BindJSON(&bookDTO)
 	if err != nil {
@@ -43,9 +159,17 @@ func (b *BookAPI) Create(c *gin.Context) {
 		return
 	}
 
-	createBook := b.BookService.Save(dto.ToBook(bookDTO))
+	createBook, err := b.BookService.Save(c.Request.Context(), dto.ToBook(bookDTO))
+	if err == service.ErrBookQuotaExceeded {
+		c.JSON(http.StatusForbidden, gin.H{"error": localize(c, err)})
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"book": dto.ToBookDTO(createBook)})
+	enc.render(c, enc.Book(dto.ToBookDTO(createBook)))
 }
 
 func (b *BookAPI) Update(c *gin.Context) {
@@ -59,30 +183,107 @@ func (b *BookAPI) Update(c *gin.Context) {
 
 	id, err := strconv.Atoi(c.Param("id"))
 
-	book := b.BookService.GetByID(uint(id))
+	book, err := b.BookService.GetByID(c.Request.Context(), uint(id))
 	log.Println(book)
-	if book == (model.Book{}) {
+	if err == repository.ErrNotFound {
 		c.Status(http.StatusBadRequest)
 		return
 	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
 
 	book.ISBN = bookDTO.ISBN
+	book.Title = bookDTO.Title
+	book.Description = bookDTO.Description
+	book.Author = bookDTO.Author
+	book.Publisher = bookDTO.Publisher
+	book.Category = bookDTO.Category
 	book.Price = bookDTO.Price
 	log.Println(book)
-	b.BookService.Save(book)
+	if _, err := b.BookService.Save(c.Request.Context(), book); err != nil {
+		internalError(c, err)
+		return
+	}
 
 	c.Status(http.StatusOK)
 }
 
 func (b *BookAPI) Delete(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	book := b.BookService.GetByID(uint(id))
-	if book == (model.Book{}) {
-		c.Status(http.StatusBadRequest)
+	book, err := b.BookService.GetByID(c.Request.Context(), uint(id))
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
 		return
 	}
 	fmt.Println(book)
-	b.BookService.Delete(book)
+	err = b.BookService.Delete(c.Request.Context(), book)
+	// 读出图书之后被其他请求删除了
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
 
 	c.Status(http.StatusOK)
 }
+
+func (b *BookAPI) History(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	revs, err := b.BookService.History(c.Request.Context(), uint(id))
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": dto.ToRevisionDTOs(revs)})
+}
+
+func (b *BookAPI) Revert(c *gin.Context) {
+	var req dto.RevertRequest
+	if err := c.BindJSON(&req); err != nil {
+		log.Println(err)
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	book, err := b.BookService.Revert(c.Request.Context(), uint(id), req.Version)
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"book": dto.ToBookDTO(book)})
+}
+
+func (b *BookAPI) Price(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	quote, err := b.BookService.Price(c.Request.Context(), uint(id), c.Query("coupon"))
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"price": dto.ToPriceDTO(quote)})
+}
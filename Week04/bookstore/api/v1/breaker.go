@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/breaker"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+)
+
+// internalError 记录错误并返回 500，熔断器打开导致的失败返回 503 和 Retry-After，
+// 数据库调用超时返回 503
+func internalError(c *gin.Context, err error) {
+	var open *breaker.OpenError
+	if errors.As(err, &open) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(open.RetryAfter.Seconds()))))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": localize(c, err)})
+		return
+	}
+	var timeout *breaker.TimeoutError
+	if errors.As(err, &timeout) {
+		log.Println(err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": localize(c, err)})
+		return
+	}
+	log.Println(err)
+	c.Status(http.StatusInternalServerError)
+}
+
+type BreakerAPI struct {
+	Breaker *breaker.Breaker
+}
+
+func NewBreakerAPI(b *breaker.Breaker) BreakerAPI {
+	return BreakerAPI{Breaker: b}
+}
+
+// Get 返回数据库熔断器的状态，供监控使用
+func (b *BreakerAPI) Get(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.ToBreakerDTO(b.Breaker.Stats()))
+}
@@ -0,0 +1,180 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+var errCategoryNameUsed = i18n.NewError("category.name_exists")
+
+type CategoryAPI struct {
+	CategoryService service.CategoryService
+}
+
+func NewCategoryAPI(c service.CategoryService) CategoryAPI {
+	return CategoryAPI{CategoryService: c}
+}
+
+// writeCategoryError 把分类相关的错误转换成响应
+func writeCategoryError(c *gin.Context, err error) {
+	switch err {
+	case repository.ErrNotFound:
+		c.Status(http.StatusNotFound)
+	case repository.ErrDuplicate:
+		c.JSON(http.StatusConflict, gin.H{"error": localize(c, errCategoryNameUsed)})
+	case service.ErrInvalidParent, service.ErrInvalidCategory:
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+	case service.ErrCategoryNotEmpty:
+		c.JSON(http.StatusConflict, gin.H{"error": localize(c, err)})
+	default:
+		internalError(c, err)
+	}
+}
+
+// parseIDs 解析 ID 列表，任意一个不是正整数时返回 false
+func parseIDs(values []string) ([]uint, bool) {
+	ids := make([]uint, 0, len(values))
+	for _, v := range values {
+		id, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		if err != nil || id == 0 {
+			return nil, false
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, true
+}
+
+// Roots 返回根分类和它们的图书数
+func (a *CategoryAPI) Roots(c *gin.Context) {
+	a.browse(c, 0)
+}
+
+// Get 返回分类、从根到它的路径和它的子分类，图书数都包括整棵子树
+func (a *CategoryAPI) Get(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if id <= 0 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	a.browse(c, uint(id))
+}
+
+func (a *CategoryAPI) browse(c *gin.Context, id uint) {
+	browse, err := a.CategoryService.Browse(c.Request.Context(), id)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	resp := gin.H{
+		"ancestors": dto.ToCategoryDTOs(browse.Ancestors),
+		"children":  dto.ToCategoryCountDTOs(browse.Children),
+	}
+	if id != 0 {
+		resp["category"] = dto.ToCategoryCountDTO(browse.Category)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Books 返回分类整棵子树中的图书
+func (a *CategoryAPI) Books(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if _, err := a.CategoryService.Get(c.Request.Context(), uint(id)); err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+	books, err := a.CategoryService.Books(c.Request.Context(), []uint{uint(id)})
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"books": dto.ToBookDTOs(books)})
+}
+
+func (a *CategoryAPI) Create(c *gin.Context) {
+	var req dto.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	category, err := a.CategoryService.Create(c.Request.Context(), req.Name, req.ParentID)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"category": dto.ToCategoryDTO(category)})
+}
+
+// Update 重命名分类，parent_id 变化时移动整棵子树
+func (a *CategoryAPI) Update(c *gin.Context) {
+	var req dto.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	category, err := a.CategoryService.Update(c.Request.Context(), uint(id), req.Name, req.ParentID)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"category": dto.ToCategoryDTO(category)})
+}
+
+func (a *CategoryAPI) Delete(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := a.CategoryService.Delete(c.Request.Context(), uint(id)); err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// BookCategories 返回图书直接所属的分类
+func (a *CategoryAPI) BookCategories(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	cs, err := a.CategoryService.BookCategories(c.Request.Context(), uint(id))
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": dto.ToCategoryDTOs(cs)})
+}
+
+// SetBookCategories 替换图书的分类
+func (a *CategoryAPI) SetBookCategories(c *gin.Context) {
+	var req dto.BookCategoriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+	ids, ok := parseIDs(req.CategoryIDs)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, service.ErrInvalidCategory)})
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	cs, err := a.CategoryService.SetBookCategories(c.Request.Context(), uint(id), ids)
+	if err != nil {
+		writeCategoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": dto.ToCategoryDTOs(cs)})
+}
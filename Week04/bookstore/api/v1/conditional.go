@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const cacheControlKey = "cache_control"
+
+// CacheControl 配置路由成功响应的 Cache-Control，handler 在成功时通过 setCacheControl 写入，
+// 错误响应不会带上缓存策略
+func CacheControl(value string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(cacheControlKey, value)
+		c.Next()
+	}
+}
+
+func setCacheControl(c *gin.Context) {
+	if v := c.GetString(cacheControlKey); v != "" {
+		c.Header("Cache-Control", v)
+	}
+}
+
+// contentETag 由修改时间、响应格式和内容的哈希生成强 ETag
+func contentETag(lastModified time.Time, mediaType string, content interface{}) (string, error) {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(strconv.FormatInt(lastModified.UnixNano(), 10)))
+	h.Write([]byte(mediaType))
+	h.Write(data)
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+// notModified 写入 ETag、Last-Modified 和 Cache-Control，客户端缓存仍然有效时返回 304。
+// 和 RFC 7232 一致，有 If-None-Match 时忽略 If-Modified-Since
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	setCacheControl(c)
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if !etagMatch(inm, etag) {
+			return false
+		}
+	} else {
+		ims, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+		if err != nil || lastModified.IsZero() || lastModified.Truncate(time.Second).After(ims) {
+			return false
+		}
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatch 按弱比较判断 If-None-Match 是否包含 etag
+func etagMatch(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/cover"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+// coverTypes 是允许上传的 content type，实际格式还会按文件内容再检查一次
+var coverTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+type CoverAPI struct {
+	CoverService service.CoverService
+	// MaxSize 是封面文件的字节数上限
+	MaxSize int64
+}
+
+func NewCoverAPI(cfg config.Config, c service.CoverService) CoverAPI {
+	return CoverAPI{CoverService: c, MaxSize: cfg.MaxCoverSize}
+}
+
+// Upload 接收 multipart 表单里名为 cover 的文件
+func (a *CoverAPI) Upload(c *gin.Context) {
+	// 给表单的其它部分留出余量，超出后读取 body 会直接失败
+	limit := a.MaxSize + 1<<20
+	tooLarge := gin.H{"error": localize(c, i18n.NewError("cover.too_large", a.MaxSize))}
+	if c.Request.ContentLength > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	fh, err := c.FormFile("cover")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+	if fh.Size > a.MaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	if !coverTypes[fh.Header.Get("Content-Type")] {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": localize(c, cover.ErrUnsupportedType)})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	book, err := a.CoverService.Upload(c.Request.Context(), uint(id), data)
+	switch {
+	case err == repository.ErrNotFound:
+		c.Status(http.StatusNotFound)
+		return
+	case err == cover.ErrUnsupportedType:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": localize(c, err)})
+		return
+	case err == cover.ErrInvalidImage:
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	case err != nil:
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"book": dto.ToBookDTO(book)})
+}
+
+// Get 返回封面，?size= 可以选择 small、medium、large 缩略图，默认返回原图
+func (a *CoverAPI) Get(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	size := c.Query("size")
+	img, err := a.CoverService.Get(c.Request.Context(), uint(id), size)
+	switch {
+	case err == repository.ErrNotFound || err == service.ErrNoCover:
+		c.Status(http.StatusNotFound)
+		return
+	case err == service.ErrUnknownCoverSize:
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	case err != nil:
+		internalError(c, err)
+		return
+	}
+
+	// 封面更新后 ETag 会变化，缓存时间由路由的 Cache-Control 配置决定
+	setCacheControl(c)
+	h := c.Writer.Header()
+	h.Set("Content-Type", img.ContentType)
+	h.Set("ETag", fmt.Sprintf(`"%d-%s-%d"`, id, size, img.ModTime.UnixNano()))
+	http.ServeContent(c.Writer, c.Request, "", img.ModTime, bytes.NewReader(img.Data))
+}
+
+func (a *CoverAPI) Delete(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	err := a.CoverService.Delete(c.Request.Context(), uint(id))
+	if err == repository.ErrNotFound || err == service.ErrNoCover {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,209 @@
+package v1
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+)
+
+// BookEncoder 把单本图书或图书列表转换成某种格式的 render，
+// 响应的 Content-Type 由协商结果决定，render 自己设置的只作为缺省值
+type BookEncoder interface {
+	Book(book dto.BookDTO) render.Render
+	Books(books []dto.BookDTO) render.Render
+}
+
+// BookEncoders 是按 media type 注册的图书编码器，根据 Accept 请求头选择
+type BookEncoders struct {
+	types    []string
+	encoders map[string]BookEncoder
+}
+
+// NewBookEncoders 注册 JSON、XML、CSV 和 MessagePack，Accept 为空或者 */* 时使用 JSON
+func NewBookEncoders() *BookEncoders {
+	e := &BookEncoders{encoders: make(map[string]BookEncoder)}
+	e.Register(gin.MIMEJSON, jsonBookEncoder{})
+	e.Register(gin.MIMEXML, xmlBookEncoder{})
+	e.Register(gin.MIMEXML2, xmlBookEncoder{})
+	e.Register("text/csv", csvBookEncoder{})
+	e.Register("application/msgpack", msgpackBookEncoder{})
+	e.Register("application/x-msgpack", msgpackBookEncoder{})
+	return e
+}
+
+// Register 注册或替换一个 media type 的编码器
+func (e *BookEncoders) Register(mediaType string, enc BookEncoder) {
+	if _, ok := e.encoders[mediaType]; !ok {
+		e.types = append(e.types, mediaType)
+	}
+	e.encoders[mediaType] = enc
+}
+
+// Types 按注册顺序返回支持的 media type
+func (e *BookEncoders) Types() []string {
+	return append([]string(nil), e.types...)
+}
+
+// textual 判断 media type 是不是文本格式：text/*、JSON、XML 以及 +json、+xml 后缀的类型
+func textual(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == gin.MIMEJSON || mediaType == gin.MIMEXML ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// Negotiate 按 q 值从高到低匹配 Accept 里的 media type，支持 type/* 和 */* 通配，
+// 返回编码器和选中的 media type
+func (e *BookEncoders) Negotiate(accept string) (BookEncoder, string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return e.encoders[e.types[0]], e.types[0], true
+	}
+	for _, r := range parseAccept(accept) {
+		for _, t := range e.types {
+			if matchMediaType(r, t) {
+				return e.encoders[t], t, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		r := acceptRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		if r.mediaType != "" && r.q > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+	// q 值相同时保持客户端给出的顺序
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	return ranges
+}
+
+func matchMediaType(r acceptRange, mediaType string) bool {
+	if r.mediaType == "*/*" || r.mediaType == mediaType {
+		return true
+	}
+	return strings.HasSuffix(r.mediaType, "/*") &&
+		strings.HasPrefix(mediaType, strings.TrimSuffix(r.mediaType, "*"))
+}
+
+type jsonBookEncoder struct{}
+
+func (jsonBookEncoder) Book(book dto.BookDTO) render.Render {
+	return render.JSON{Data: gin.H{"book": book}}
+}
+
+func (jsonBookEncoder) Books(books []dto.BookDTO) render.Render {
+	return render.JSON{Data: gin.H{"books": books}}
+}
+
+type msgpackBookEncoder struct{}
+
+func (msgpackBookEncoder) Book(book dto.BookDTO) render.Render {
+	return render.MsgPack{Data: gin.H{"book": book}}
+}
+
+func (msgpackBookEncoder) Books(books []dto.BookDTO) render.Render {
+	return render.MsgPack{Data: gin.H{"books": books}}
+}
+
+type xmlBook struct {
+	XMLName xml.Name `xml:"book"`
+	dto.BookDTO
+}
+
+type xmlBookList struct {
+	XMLName xml.Name      `xml:"books"`
+	Books   []dto.BookDTO `xml:"book"`
+}
+
+type xmlBookEncoder struct{}
+
+func (xmlBookEncoder) Book(book dto.BookDTO) render.Render {
+	return render.XML{Data: xmlBook{BookDTO: book}}
+}
+
+func (xmlBookEncoder) Books(books []dto.BookDTO) render.Render {
+	return render.XML{Data: xmlBookList{Books: books}}
+}
+
+// csvHeader 是 CSV 的列，单本图书也输出表头，方便直接导入表格
+var csvHeader = []string{"id", "isbn", "title", "description", "author", "publisher", "category", "price", "cover_updated_at",
+	"average_rating", "rating_count"}
+
+type csvBookEncoder struct{}
+
+func (csvBookEncoder) Book(book dto.BookDTO) render.Render {
+	return csvRender{books: []dto.BookDTO{book}}
+}
+
+func (csvBookEncoder) Books(books []dto.BookDTO) render.Render {
+	return csvRender{books: books}
+}
+
+type csvRender struct {
+	books []dto.BookDTO
+}
+
+func (r csvRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, b := range r.books {
+		var coverUpdatedAt string
+		if b.CoverUpdatedAt != nil {
+			coverUpdatedAt = b.CoverUpdatedAt.UTC().Format(time.RFC3339)
+		}
+		record := []string{
+			strconv.FormatUint(uint64(b.ID), 10),
+			b.ISBN,
+			b.Title,
+			b.Description,
+			b.Author,
+			b.Publisher,
+			b.Category,
+			strconv.FormatFloat(float64(b.Price), 'f', -1, 32),
+			coverUpdatedAt,
+			strconv.FormatFloat(b.AverageRating, 'f', -1, 64),
+			strconv.Itoa(b.RatingCount),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r csvRender) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if val := header["Content-Type"]; len(val) == 0 {
+		header["Content-Type"] = []string{"text/csv; charset=utf-8"}
+	}
+}
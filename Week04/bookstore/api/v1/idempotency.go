@@ -0,0 +1,163 @@
+package v1
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+var (
+	errIdempotencyKeyReused  = i18n.NewError("idempotency.key_reused")
+	errIdempotencyInProgress = i18n.NewError("idempotency.in_progress")
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed 出现在重放的响应中
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// idempotencyStaleAfter 之后仍未完成的记录认为处理它的进程已经退出
+	idempotencyStaleAfter = time.Minute
+	// idempotencyWaitTimeout 是重复请求等待首个请求完成的最长时间
+	idempotencyWaitTimeout = 30 * time.Second
+	idempotencyPollEvery   = 50 * time.Millisecond
+)
+
+// Idempotency 为带 Idempotency-Key 头的请求提供幂等保证，key 按店铺和操作者隔离：
+// 首个请求的响应和请求指纹一起保存 window 时长，之后相同 key 的请求直接重放保存的响应；
+// 指纹不同返回 422；首个请求尚未完成时，重复请求等待它完成。
+// 首个请求返回 5xx 时不保存响应，客户端可以用同一个 key 重试
+func Idempotency(repo repository.IdempotencyRepository, window time.Duration, clk clock.Clock) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		// 不同店铺、不同操作者的 key 互不影响，操作者可以包含任意字符，转义之后再拼接
+		key = url.QueryEscape(service.ActorFromContext(c.Request.Context())) + ":" + key
+		if t, ok := tenant.FromContext(c.Request.Context()); ok {
+			key = t.Slug + ":" + key
+		}
+
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			log.Println(err)
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(c.Request, body)
+
+		deadline := clk.Now().Add(idempotencyWaitTimeout)
+		for {
+			now := clk.Now()
+			record, reserved, err := repo.Reserve(model.IdempotencyRecord{
+				Key:         key,
+				Fingerprint: fingerprint,
+				ExpiresAt:   now.Add(window),
+				CreatedAt:   now,
+			}, now.Add(-idempotencyStaleAfter))
+			if err != nil {
+				log.Println(err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			if reserved {
+				handleFirst(c, repo, key, clk)
+				return
+			}
+			if record.Fingerprint != fingerprint {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity,
+					gin.H{"error": localize(c, errIdempotencyKeyReused)})
+				return
+			}
+			if record.CompletedAt != nil {
+				replay(c, record)
+				return
+			}
+
+			// 首个请求还在处理中，等待它完成或者释放 key
+			if clk.Now().After(deadline) {
+				c.AbortWithStatusJSON(http.StatusConflict,
+					gin.H{"error": localize(c, errIdempotencyInProgress)})
+				return
+			}
+			select {
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			case <-time.After(idempotencyPollEvery):
+			}
+		}
+	}
+}
+
+func handleFirst(c *gin.Context, repo repository.IdempotencyRepository, key string, clk clock.Clock) {
+	recorder := &bodyRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+
+	status := c.Writer.Status()
+	if status >= http.StatusInternalServerError {
+		if err := repo.Release(key); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+	err := repo.Complete(key, status, c.Writer.Header().Get("Content-Type"), recorder.body.Bytes(), clk.Now())
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func replay(c *gin.Context, record model.IdempotencyRecord) {
+	c.Header(HeaderIdempotentReplayed, "true")
+	if record.ContentType == "" {
+		c.AbortWithStatus(record.StatusCode)
+		return
+	}
+	c.Data(record.StatusCode, record.ContentType, []byte(record.Body))
+	c.Abort()
+}
+
+// requestFingerprint 用方法、路径、查询参数和请求体计算请求指纹
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bodyRecorder 在写响应的同时保存一份响应体
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *bodyRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+)
+
+// Locale 按 Accept-Language 生成语言回退链放进 request context，
+// handler 写出错误时用它把错误翻译成最匹配的语言
+func Locale(defaultLocale string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locales := i18n.Chain(c.GetHeader("Accept-Language"), defaultLocale)
+		c.Request = c.Request.WithContext(i18n.WithLocales(c.Request.Context(), locales))
+		c.Next()
+	}
+}
+
+// setContentLanguage 标明响应内容的语言，内容随 Accept-Language 变化，需要告诉缓存。
+// Vary 用 Add 追加，不能覆盖内容协商已经写入的 Accept
+func setContentLanguage(c *gin.Context, locales []string) {
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	if len(locales) > 0 && locales[0] != "" {
+		c.Header("Content-Language", strings.Join(locales, ", "))
+	}
+}
+
+// localize 按请求的语言回退链翻译 err，用于写出响应中的 error 字段，翻译成功时标明响应的语言。
+// 没有 message key 的错误返回原文
+func localize(c *gin.Context, err error) string {
+	msg, locale := i18n.Localize(i18n.LocalesFromContext(c.Request.Context()), err)
+	if locale != i18n.SourceLocale {
+		c.Header("Content-Language", locale)
+	}
+	return msg
+}
@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+var (
+	errInvalidTenantToken = i18n.NewError("tenant.invalid_token")
+	errMissingTenant      = i18n.NewError("tenant.missing")
+	errUnknownTenant      = i18n.NewError("tenant.unknown")
+)
+
+const (
+	// HeaderActor 标明本次请求的操作人，会记录到图书的变更历史中
+	HeaderActor = "X-Actor"
+	// HeaderTenant 是店铺的 slug，也可以通过 Bearer token 的 tenant claim 传递
+	HeaderTenant = "X-Tenant-ID"
+	// HeaderAdminToken 是调用管理接口需要携带的 token
+	HeaderAdminToken = "X-Admin-Token"
+)
+
+// Actor 把请求头里的操作人放进 request context，供 service 层使用
+func Actor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := service.WithActor(c.Request.Context(), c.GetHeader(HeaderActor))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// ReadYourWrites 让请求在写过主库之后的读也走主库，避免读到副本上还没同步的旧数据
+func ReadYourWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := repository.WithReadYourWrites(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Tenant 从请求头或者 token 中解析店铺并放进 request context，
+// 两者都有时必须一致，解析不出店铺的请求直接拒绝
+func Tenant(tenants service.TenantService, tokenSecret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slug := c.GetHeader(HeaderTenant)
+		if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			claim, err := tenant.ClaimFromToken(tokenSecret, strings.TrimPrefix(auth, "Bearer "))
+			if err != nil || (slug != "" && claim != slug) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": localize(c, errInvalidTenantToken)})
+				return
+			}
+			slug = claim
+		}
+		if slug == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": localize(c, errMissingTenant)})
+			return
+		}
+
+		t, err := tenants.GetBySlug(slug)
+		if err == repository.ErrNotFound {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": localize(c, errUnknownTenant)})
+			return
+		}
+		if err != nil {
+			internalError(c, err)
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), t))
+		c.Next()
+	}
+}
+
+// TenantRateLimit 按店铺的 RequestsPerSecond 限流，必须放在 Tenant 之后
+func TenantRateLimit() gin.HandlerFunc {
+	var mu sync.Mutex
+	buckets := make(map[uint]*tokenBucket)
+
+	return func(c *gin.Context) {
+		t, ok := tenant.FromContext(c.Request.Context())
+		if !ok || t.RequestsPerSecond <= 0 {
+			c.Next()
+			return
+		}
+
+		mu.Lock()
+		b, ok := buckets[t.ID]
+		if !ok {
+			b = &tokenBucket{tokens: float64(t.RequestsPerSecond), last: time.Now()}
+			buckets[t.ID] = b
+		}
+		allowed := b.take(float64(t.RequestsPerSecond), time.Now())
+		mu.Unlock()
+
+		if !allowed {
+			c.Header("Retry-After", "1")
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
+
+// tokenBucket 是容量和速率都等于 rate 的令牌桶
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(rate float64, now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > rate {
+		b.tokens = rate
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// AdminAuth 校验管理接口的 token，token 为空时拒绝所有请求
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := c.GetHeader(HeaderAdminToken)
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
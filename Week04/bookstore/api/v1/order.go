@@ -0,0 +1,178 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/payment"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+type CartAPI struct {
+	CartService service.CartService
+}
+
+func NewCartAPI(c service.CartService) CartAPI {
+	return CartAPI{CartService: c}
+}
+
+type OrderAPI struct {
+	OrderService service.OrderService
+}
+
+func NewOrderAPI(o service.OrderService) OrderAPI {
+	return OrderAPI{OrderService: o}
+}
+
+// writeOrderError 把购物车和订单相关的错误转换成响应
+func writeOrderError(c *gin.Context, err error) {
+	switch err {
+	case repository.ErrNotFound:
+		c.Status(http.StatusNotFound)
+	case service.ErrCustomerRequired:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": localize(c, err)})
+	case service.ErrInvalidQuantity, service.ErrEmptyCart:
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+	case service.ErrBookUnavailable, service.ErrInvalidTransition, service.ErrPaymentInProgress,
+		repository.ErrConflict, payment.ErrInvalidState:
+		c.JSON(http.StatusConflict, gin.H{"error": localize(c, err)})
+	default:
+		internalError(c, err)
+	}
+}
+
+func (a *CartAPI) Get(c *gin.Context) {
+	cart, err := a.CartService.Get(c.Request.Context(), c.Query("coupon"))
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cart": dto.ToCartDTO(cart)})
+}
+
+// Add 把图书加入购物车，已经在购物车里时累加数量
+func (a *CartAPI) Add(c *gin.Context) {
+	var req dto.CartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	if _, err := a.CartService.Add(c.Request.Context(), req.BookID, req.Quantity); err != nil {
+		writeOrderError(c, err)
+		return
+	}
+	a.Get(c)
+}
+
+// SetQuantity 修改购物车中图书的数量，数量为 0 时移除
+func (a *CartAPI) SetQuantity(c *gin.Context) {
+	var req dto.QuantityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	bookID, _ := strconv.Atoi(c.Param("bookID"))
+	if _, err := a.CartService.SetQuantity(c.Request.Context(), uint(bookID), *req.Quantity); err != nil {
+		writeOrderError(c, err)
+		return
+	}
+	a.Get(c)
+}
+
+func (a *CartAPI) Remove(c *gin.Context) {
+	bookID, _ := strconv.Atoi(c.Param("bookID"))
+	if err := a.CartService.Remove(c.Request.Context(), uint(bookID)); err != nil {
+		writeOrderError(c, err)
+		return
+	}
+	a.Get(c)
+}
+
+func (a *CartAPI) Clear(c *gin.Context) {
+	if err := a.CartService.Clear(c.Request.Context()); err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Checkout 把购物车结算成待支付的订单
+func (o *OrderAPI) Checkout(c *gin.Context) {
+	order, err := o.OrderService.Checkout(c.Request.Context(), c.Query("coupon"))
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"order": dto.ToOrderDTO(order)})
+}
+
+func (o *OrderAPI) List(c *gin.Context) {
+	orders, err := o.OrderService.List(c.Request.Context())
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": dto.ToOrderDTOs(orders)})
+}
+
+func (o *OrderAPI) Get(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	order, err := o.OrderService.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order": dto.ToOrderDTO(order)})
+}
+
+// Cancel 取消自己还没有支付的订单
+func (o *OrderAPI) Cancel(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	order, err := o.OrderService.Cancel(c.Request.Context(), uint(id))
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order": dto.ToOrderDTO(order)})
+}
+
+// ListAll 是店铺管理用的列表，可以用 ?status= 过滤
+func (o *OrderAPI) ListAll(c *gin.Context) {
+	orders, err := o.OrderService.ListAll(c.Request.Context(), c.Query("status"))
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": dto.ToOrderDTOs(orders)})
+}
+
+// UpdateStatus 推进订单状态：pending -> paid -> shipped，shipped 之前都可以取消
+func (o *OrderAPI) UpdateStatus(c *gin.Context) {
+	var req dto.OrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	order, err := o.OrderService.UpdateStatus(c.Request.Context(), uint(id), req.Status)
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order": dto.ToOrderDTO(order)})
+}
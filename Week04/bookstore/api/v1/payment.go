@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/payment"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+// maxCallbackSize 是网关回调请求体的字节数上限
+const maxCallbackSize = 1 << 20
+
+type PaymentAPI struct {
+	PaymentService service.PaymentService
+}
+
+func NewPaymentAPI(p service.PaymentService) PaymentAPI {
+	return PaymentAPI{PaymentService: p}
+}
+
+// Pay 支付自己的订单，被拒绝时返回 402 和这次支付
+func (p *PaymentAPI) Pay(c *gin.Context) {
+	var req dto.PayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	pay, err := p.PaymentService.Pay(c.Request.Context(), uint(id), req.Source)
+	if err == service.ErrPaymentDeclined {
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": localize(c, err), "payment": dto.ToPaymentDTO(pay)})
+		return
+	}
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"payment": dto.ToPaymentDTO(pay)})
+}
+
+// Get 返回订单最新的一次支付，网关返回 pending 时可以轮询这里等待结果
+func (p *PaymentAPI) Get(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	pay, err := p.PaymentService.Latest(c.Request.Context(), uint(id))
+	if err != nil {
+		writeOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payment": dto.ToPaymentDTO(pay)})
+}
+
+// Callback 接收网关的回调，非 2xx 的响应会让网关重试
+func (p *PaymentAPI) Callback(c *gin.Context) {
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxCallbackSize))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	err = p.PaymentService.HandleWebhook(c.Request.Context(), c.Request.Header, body)
+	switch err {
+	case nil:
+		c.Status(http.StatusOK)
+	case payment.ErrInvalidSignature:
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+	case repository.ErrNotFound:
+		c.Status(http.StatusNotFound)
+	default:
+		internalError(c, err)
+	}
+}
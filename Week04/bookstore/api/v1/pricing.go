@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+// PricingAPI 是管理店铺优惠规则的管理接口
+type PricingAPI struct {
+	PricingService service.PricingService
+}
+
+func NewPricingAPI(p service.PricingService) PricingAPI {
+	return PricingAPI{PricingService: p}
+}
+
+func (p *PricingAPI) GetAll(c *gin.Context) {
+	rules, err := p.PricingService.GetAll(c.Request.Context())
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": dto.ToPricingRuleDTOs(rules)})
+}
+
+func (p *PricingAPI) Create(c *gin.Context) {
+	var ruleDTO dto.PricingRuleDTO
+	if err := c.BindJSON(&ruleDTO); err != nil {
+		log.Println(err)
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	rule, err := p.PricingService.Save(c.Request.Context(), dto.ToPricingRule(ruleDTO))
+	if err == service.ErrInvalidPricingRule {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rule": dto.ToPricingRuleDTO(rule)})
+}
+
+func (p *PricingAPI) Update(c *gin.Context) {
+	var ruleDTO dto.PricingRuleDTO
+	if err := c.BindJSON(&ruleDTO); err != nil {
+		log.Println(err)
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	rule := dto.ToPricingRule(ruleDTO)
+	rule.ID = uint(id)
+	rule, err := p.PricingService.Save(c.Request.Context(), rule)
+	switch {
+	case err == service.ErrInvalidPricingRule:
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	case err == repository.ErrNotFound:
+		c.Status(http.StatusNotFound)
+		return
+	case err != nil:
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rule": dto.ToPricingRuleDTO(rule)})
+}
+
+func (p *PricingAPI) Delete(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	rule, err := p.PricingService.GetByID(c.Request.Context(), uint(id))
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if err := p.PricingService.Delete(c.Request.Context(), rule); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,7 @@
+package v1
+
+import "github.com/google/wire"
+
+var ProviderSet = wire.NewSet(NewBookAPI, NewBookEncoders, NewWebhookAPI, NewTenantAPI, NewPricingAPI, NewCoverAPI,
+	NewReviewAPI, NewBreakerAPI, NewStreamAPI, NewCartAPI, NewOrderAPI, NewPaymentAPI,
+	NewRecommendationAPI, NewCategoryAPI, NewTranslationAPI)
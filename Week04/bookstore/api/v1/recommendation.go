@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+// defaultRecommendations 是没有指定 limit 时返回的推荐数
+const defaultRecommendations = 10
+
+type RecommendationAPI struct {
+	RecommendationService service.RecommendationService
+}
+
+func NewRecommendationAPI(r service.RecommendationService) RecommendationAPI {
+	return RecommendationAPI{RecommendationService: r}
+}
+
+// List 返回买了这本书的顾客也买了的书，?limit= 最大为 service.MaxRecommendations
+func (r *RecommendationAPI) List(c *gin.Context) {
+	limit := defaultRecommendations
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > service.MaxRecommendations {
+			c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, i18n.NewError("recommendation.invalid_limit", service.MaxRecommendations))})
+			return
+		}
+		limit = n
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	recs, err := r.RecommendationService.Related(c.Request.Context(), uint(id), limit)
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	// 推荐由定时任务更新，短时间缓存即可
+	setCacheControl(c)
+	c.JSON(http.StatusOK, gin.H{"recommendations": dto.ToRecommendationDTOs(recs)})
+}
@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+var errAlreadyReviewed = i18n.NewError("review.duplicate")
+
+type ReviewAPI struct {
+	ReviewService service.ReviewService
+}
+
+func NewReviewAPI(r service.ReviewService) ReviewAPI {
+	return ReviewAPI{ReviewService: r}
+}
+
+func reviewIDs(c *gin.Context) (bookID, id uint) {
+	b, _ := strconv.Atoi(c.Param("id"))
+	r, _ := strconv.Atoi(c.Param("reviewID"))
+	return uint(b), uint(r)
+}
+
+// writeReviewError 把评论相关的错误转换成响应
+func writeReviewError(c *gin.Context, err error) {
+	switch err {
+	case repository.ErrNotFound:
+		c.Status(http.StatusNotFound)
+	case repository.ErrDuplicate:
+		c.JSON(http.StatusConflict, gin.H{"error": localize(c, errAlreadyReviewed)})
+	case service.ErrInvalidReview:
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+	case service.ErrReviewAuthorRequired:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": localize(c, err)})
+	case service.ErrReviewForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"error": localize(c, err)})
+	default:
+		internalError(c, err)
+	}
+}
+
+// List 只返回审核通过的评论
+func (r *ReviewAPI) List(c *gin.Context) {
+	bookID, _ := reviewIDs(c)
+	reviews, err := r.ReviewService.List(c.Request.Context(), bookID, model.ReviewApproved)
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": dto.ToReviewDTOs(reviews)})
+}
+
+// ListAll 是审核用的列表，可以用 ?status= 过滤
+func (r *ReviewAPI) ListAll(c *gin.Context) {
+	bookID, _ := reviewIDs(c)
+	reviews, err := r.ReviewService.List(c.Request.Context(), bookID, c.Query("status"))
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": dto.ToReviewDTOs(reviews)})
+}
+
+func (r *ReviewAPI) Create(c *gin.Context) {
+	var req dto.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	bookID, _ := reviewIDs(c)
+	review, err := r.ReviewService.Create(c.Request.Context(), bookID, req.Rating, req.Text)
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"review": dto.ToReviewDTO(review)})
+}
+
+func (r *ReviewAPI) Update(c *gin.Context) {
+	var req dto.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	bookID, id := reviewIDs(c)
+	review, err := r.ReviewService.Update(c.Request.Context(), bookID, id, req.Rating, req.Text)
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"review": dto.ToReviewDTO(review)})
+}
+
+func (r *ReviewAPI) Delete(c *gin.Context) {
+	bookID, id := reviewIDs(c)
+	if err := r.ReviewService.Delete(c.Request.Context(), bookID, id, true); err != nil {
+		writeReviewError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Moderate 修改评论的审核状态，只有 approved 的评论会公开并计入评分
+func (r *ReviewAPI) Moderate(c *gin.Context) {
+	var req dto.ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	bookID, id := reviewIDs(c)
+	review, err := r.ReviewService.Moderate(c.Request.Context(), bookID, id, req.Status)
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"review": dto.ToReviewDTO(review)})
+}
@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/feed"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+var errInvalidLastEventID = i18n.NewError("stream.invalid_last_event_id")
+
+// eventReset 告诉客户端 Last-Event-ID 之后的事件已经无法补齐，需要重新拉取图书列表
+const eventReset = "reset"
+
+type StreamAPI struct {
+	Hub       *feed.Hub
+	Heartbeat time.Duration
+}
+
+func NewStreamAPI(cfg config.Config, hub *feed.Hub) StreamAPI {
+	return StreamAPI{Hub: hub, Heartbeat: cfg.StreamHeartbeat}
+}
+
+// Books 以 Server-Sent Events 推送当前店铺的图书事件。
+// types 参数按逗号分隔过滤事件类型，重连时通过 Last-Event-ID 请求头补发错过的事件
+func (s *StreamAPI) Books(c *gin.Context) {
+	types, ok := parseEventTypes(c.Query("types"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, i18n.NewError("stream.invalid_types", strings.Join(model.BookEventTypes, ", ")))})
+		return
+	}
+	var (
+		epoch  string
+		lastID uint64
+	)
+	if v := c.GetHeader("Last-Event-ID"); v != "" {
+		var err error
+		if epoch, lastID, err = feed.ParseEventID(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, errInvalidLastEventID)})
+			return
+		}
+	}
+
+	t, _ := tenant.FromContext(c.Request.Context())
+	sub, backlog, resumed := s.Hub.Subscribe(t.ID, epoch, lastID)
+	defer sub.Close()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关掉 nginx 的响应缓冲
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if !resumed {
+		fmt.Fprintf(c.Writer, "event: %s\ndata: {}\n\n", eventReset)
+	}
+	for _, e := range backlog {
+		writeEvent(c, e, types)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(s.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-sub.C():
+			// 订阅被关闭：服务在退出，或者客户端太慢，客户端可以带着 Last-Event-ID 重连
+			if !ok {
+				return
+			}
+			writeEvent(c, e, types)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeEvent(c *gin.Context, e feed.Event, types map[string]bool) {
+	if len(types) > 0 && !types[e.Type] {
+		return
+	}
+	// payload 是一行 JSON，不需要拆成多个 data 字段
+	fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", e.EventID(), e.Type, e.Data)
+}
+
+// parseEventTypes 解析逗号分隔的事件类型，为空表示不过滤
+func parseEventTypes(s string) (map[string]bool, bool) {
+	types := make(map[string]bool)
+	if s == "" {
+		return types, true
+	}
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if !model.IsBookEventType(t) {
+			return nil, false
+		}
+		types[t] = true
+	}
+	return types, true
+}
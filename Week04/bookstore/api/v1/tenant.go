@@ -0,0 +1,97 @@
+package v1
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+var errTenantSlugExists = i18n.NewError("tenant.slug_exists")
+
+// TenantAPI 是开通和管理店铺的管理接口
+type TenantAPI struct {
+	TenantService service.TenantService
+}
+
+func NewTenantAPI(t service.TenantService) TenantAPI {
+	return TenantAPI{TenantService: t}
+}
+
+func (t *TenantAPI) GetAll(c *gin.Context) {
+	tenants, err := t.TenantService.GetAll()
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tenants": dto.ToTenantDTOs(tenants)})
+}
+
+func (t *TenantAPI) Create(c *gin.Context) {
+	var tenantDTO dto.TenantDTO
+	if err := c.BindJSON(&tenantDTO); err != nil {
+		log.Println(err)
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	created, err := t.TenantService.Save(dto.ToTenant(tenantDTO))
+	if !t.handleSaveError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tenant": dto.ToTenantDTO(created)})
+}
+
+func (t *TenantAPI) Update(c *gin.Context) {
+	var tenantDTO dto.TenantDTO
+	if err := c.BindJSON(&tenantDTO); err != nil {
+		log.Println(err)
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	tenant, err := t.TenantService.GetByID(uint(id))
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	tenant.Slug = tenantDTO.Slug
+	tenant.Name = tenantDTO.Name
+	tenant.MaxBooks = tenantDTO.MaxBooks
+	tenant.RequestsPerSecond = tenantDTO.RequestsPerSecond
+	updated, err := t.TenantService.Save(tenant)
+	if !t.handleSaveError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tenant": dto.ToTenantDTO(updated)})
+}
+
+// handleSaveError 写出保存店铺失败时的响应，没有错误时返回 true
+func (t *TenantAPI) handleSaveError(c *gin.Context, err error) bool {
+	switch err {
+	case nil:
+		return true
+	case service.ErrInvalidTenantSlug, service.ErrInvalidTenantLimit:
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+	case repository.ErrDuplicate:
+		c.JSON(http.StatusConflict, gin.H{"error": localize(c, errTenantSlugExists)})
+	default:
+		internalError(c, err)
+	}
+	return false
+}
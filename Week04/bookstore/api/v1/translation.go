@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+type TranslationAPI struct {
+	TranslationService service.TranslationService
+}
+
+func NewTranslationAPI(t service.TranslationService) TranslationAPI {
+	return TranslationAPI{TranslationService: t}
+}
+
+// writeTranslationError 把翻译相关的错误转换成响应
+func writeTranslationError(c *gin.Context, err error) {
+	switch err {
+	case repository.ErrNotFound:
+		c.Status(http.StatusNotFound)
+	case repository.ErrConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": localize(c, err)})
+	case service.ErrInvalidLocale, service.ErrEmptyTranslation:
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+	default:
+		internalError(c, err)
+	}
+}
+
+func (a *TranslationAPI) List(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	ts, err := a.TranslationService.List(c.Request.Context(), uint(id))
+	if err != nil {
+		writeTranslationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"translations": dto.ToTranslationDTOs(ts)})
+}
+
+// Put 新建或者覆盖图书在路径中指定语言下的翻译
+func (a *TranslationAPI) Put(c *gin.Context) {
+	var req dto.TranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	t, err := a.TranslationService.Put(c.Request.Context(), model.BookTranslation{
+		BookID:      uint(id),
+		Locale:      c.Param("locale"),
+		Title:       req.Title,
+		Description: req.Description,
+	})
+	if err != nil {
+		writeTranslationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"translation": dto.ToTranslationDTO(t)})
+}
+
+func (a *TranslationAPI) Delete(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := a.TranslationService.Delete(c.Request.Context(), uint(id), c.Param("locale")); err != nil {
+		writeTranslationError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
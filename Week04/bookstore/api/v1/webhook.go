@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+type WebhookAPI struct {
+	WebhookService service.WebhookService
+}
+
+func NewWebhookAPI(w service.WebhookService) WebhookAPI {
+	return WebhookAPI{WebhookService: w}
+}
+
+func (w *WebhookAPI) Create(c *gin.Context) {
+	var subDTO dto.SubscriptionDTO
+	if err := c.BindJSON(&subDTO); err != nil {
+		log.Println(err)
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	sub, err := w.WebhookService.Subscribe(c.Request.Context(), dto.ToSubscription(subDTO))
+	if err == service.ErrInvalidWebhookURL || err == service.ErrUnknownEventType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": localize(c, err)})
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	created := dto.ToSubscriptionDTO(sub)
+	created.Secret = sub.Secret
+	c.JSON(http.StatusOK, gin.H{"webhook": created})
+}
+
+func (w *WebhookAPI) GetAll(c *gin.Context) {
+	subs, err := w.WebhookService.GetAll(c.Request.Context(), c.Query("event_type"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": dto.ToSubscriptionDTOs(subs)})
+}
+
+func (w *WebhookAPI) Delete(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	sub, err := w.WebhookService.GetByID(c.Request.Context(), uint(id))
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	if err := w.WebhookService.Unsubscribe(c.Request.Context(), sub); err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (w *WebhookAPI) Deliveries(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	_, err := w.WebhookService.GetByID(c.Request.Context(), uint(id))
+	if err == repository.ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	deliveries, err := w.WebhookService.Deliveries(uint(id), c.Query("status"))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": dto.ToDeliveryDTOs(deliveries)})
+}
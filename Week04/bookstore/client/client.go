@@ -0,0 +1,249 @@
+// Package client 是 Books REST API 的 Go 客户端，方法和 service.BookService 一一对应。
+// 幂等的请求（GET、PUT、DELETE，以及带 Idempotency-Key 的创建）在网络错误、
+// 429 和 5xx 时按指数退避自动重试；非 2xx 响应被解析成 *Error
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 和 api/v1 中的请求头保持一致，这里不直接引用，避免客户端依赖 gin
+const (
+	headerTenant         = "X-Tenant-ID"
+	headerActor          = "X-Actor"
+	headerIdempotencyKey = "Idempotency-Key"
+)
+
+const (
+	defaultMaxRetries  = 3
+	defaultBaseBackoff = 100 * time.Millisecond
+	defaultMaxBackoff  = 2 * time.Second
+)
+
+// Client 的导出字段可以在 New 之后修改，但不要在并发使用时修改
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// Tenant 通过 X-Tenant-ID 传递，Token 通过 Authorization: Bearer 传递，二选一即可
+	Tenant string
+	Token  string
+	// Actor 会记录到图书的变更历史中
+	Actor string
+
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// New 构造客户端，baseURL 形如 http://localhost:8080
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:     strings.TrimRight(baseURL, "/"),
+		HTTPClient:  http.DefaultClient,
+		MaxRetries:  defaultMaxRetries,
+		BaseBackoff: defaultBaseBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+	}
+}
+
+func (c *Client) GetAll(ctx context.Context) ([]Book, error) {
+	var resp struct {
+		Books []Book `json:"books"`
+	}
+	err := c.do(ctx, http.MethodGet, "/api/v1/books", nil, nil, true, &resp)
+	return resp.Books, err
+}
+
+func (c *Client) GetByID(ctx context.Context, id uint) (Book, error) {
+	var resp struct {
+		Book Book `json:"book"`
+	}
+	err := c.do(ctx, http.MethodGet, bookPath(id), nil, nil, true, &resp)
+	return resp.Book, err
+}
+
+// Create 创建图书。每次调用生成一个 Idempotency-Key，重试不会产生重复的图书
+func (c *Client) Create(ctx context.Context, book Book) (Book, error) {
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return Book{}, err
+	}
+	return c.CreateWithKey(ctx, book, key)
+}
+
+// CreateWithKey 使用调用方指定的 Idempotency-Key 创建图书
+func (c *Client) CreateWithKey(ctx context.Context, book Book, key string) (Book, error) {
+	var resp struct {
+		Book Book `json:"book"`
+	}
+	header := http.Header{headerIdempotencyKey: []string{key}}
+	err := c.do(ctx, http.MethodPost, "/api/v1/books", header, book, true, &resp)
+	return resp.Book, err
+}
+
+// Update 用 book 的 ISBN、书名、简介、作者、出版社、分类和价格整体替换 book.ID 对应的图书，
+// 为空的字段会被清空，需要保留原值时先用 GetByID 读出完整的图书再修改
+func (c *Client) Update(ctx context.Context, book Book) error {
+	return c.do(ctx, http.MethodPut, bookPath(book.ID), nil, book, true, nil)
+}
+
+// Delete 删除图书，图书不存在时返回 ErrNotFound。重试时收到 404 说明之前的请求已经删除成功，
+// 返回 nil
+func (c *Client) Delete(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, bookPath(id), nil, nil, true, nil)
+}
+
+func (c *Client) History(ctx context.Context, id uint) ([]Revision, error) {
+	var resp struct {
+		History []Revision `json:"history"`
+	}
+	err := c.do(ctx, http.MethodGet, bookPath(id)+"/history", nil, nil, true, &resp)
+	return resp.History, err
+}
+
+// Revert 不是幂等操作，失败时不会重试
+func (c *Client) Revert(ctx context.Context, id uint, version int) (Book, error) {
+	var resp struct {
+		Book Book `json:"book"`
+	}
+	err := c.do(ctx, http.MethodPost, bookPath(id)+"/revert", nil, revertRequest{Version: version}, false, &resp)
+	return resp.Book, err
+}
+
+func bookPath(id uint) string {
+	return "/api/v1/books/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// do 发送请求并把响应解析到 out，retryable 为 true 时按需重试
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, in interface{}, retryable bool, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, header, body)
+		if err == nil && resp.StatusCode < 300 {
+			return decodeBody(resp, out)
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			respBody, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if attempt > 0 && method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+				return nil
+			}
+			err = decodeError(resp.StatusCode, respBody)
+			if !retryableStatus(resp.StatusCode) {
+				return err
+			}
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !retryable || attempt >= c.MaxRetries {
+			return err
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body []byte) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Tenant != "" {
+		req.Header.Set(headerTenant, c.Tenant)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.Actor != "" {
+		req.Header.Set(headerActor, c.Actor)
+	}
+	return c.HTTPClient.Do(req)
+}
+
+// backoff 返回第 attempt 次重试前的等待时间：BaseBackoff * 2^attempt，不超过 MaxBackoff
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.BaseBackoff
+	for i := 0; i < attempt && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	return d
+}
+
+func decodeBody(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	if out == nil {
+		_, err := io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("bookstore: decode response: %w", err)
+	}
+	return nil
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusBadGateway ||
+		code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// parseRetryAfter 只支持秒数形式的 Retry-After
+func parseRetryAfter(v string) time.Duration {
+	seconds, err := strconv.Atoi(v)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,199 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	v1 "github.com/yngwiewang/Go-000/Week04/bookstore/api/v1"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/client"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository/memory"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/routers"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+)
+
+// newRouter 用内存仓储和假时钟构建真实的路由，并开通一个 slug 为 demo 的店铺
+func newRouter(t *testing.T) http.Handler {
+	gin.SetMode(gin.TestMode)
+	clk := clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := memory.NewStore(clk)
+
+	bookService := service.NewBookService(config.Config{}, store.Books, store.History, store.PricingRules,
+		store.Categories, store, nil, clk)
+	webhookService := service.NewWebhookService(store.Subscriptions, store.Deliveries, clk)
+	tenantService := service.NewTenantService(store.Tenants)
+	if _, err := tenantService.Save(model.Tenant{Slug: "demo", Name: "Demo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Config{IdempotencyWindow: time.Hour}
+	apis := routers.APIs{
+		Book: v1.NewBookAPI(bookService, service.NewCategoryService(store.Categories, store.Books),
+			service.NewTranslationService(config.Config{}, store.Books, store.Translations), v1.NewBookEncoders()),
+		Webhook: v1.NewWebhookAPI(webhookService),
+		Tenant:  v1.NewTenantAPI(tenantService),
+		Pricing: v1.NewPricingAPI(service.NewPricingService(store.PricingRules, store.Categories)),
+	}
+	return routers.NewRouter(cfg, clk, apis, tenantService, store.Idempotency)
+}
+
+func newClient(url string) *client.Client {
+	c := client.New(url)
+	c.Tenant = "demo"
+	c.Actor = "alice"
+	c.BaseBackoff = time.Millisecond
+	c.MaxBackoff = 10 * time.Millisecond
+	return c
+}
+
+func TestBookLifecycle(t *testing.T) {
+	srv := httptest.NewServer(newRouter(t))
+	defer srv.Close()
+	c := newClient(srv.URL)
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, client.Book{ISBN: "978-7-111", Price: 59.9})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ID == 0 || created.ISBN != "978-7-111" {
+		t.Fatalf("Create returned %+v", created)
+	}
+
+	created.Price = 49.9
+	if err := c.Update(ctx, created); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err := c.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Price != 49.9 {
+		t.Fatalf("price after update = %v, want 49.9", got.Price)
+	}
+
+	history, err := c.History(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 2 || history[1].Action != client.ActionUpdate || history[1].Actor != "alice" {
+		t.Fatalf("History returned %+v", history)
+	}
+
+	reverted, err := c.Revert(ctx, created.ID, 1)
+	if err != nil {
+		t.Fatalf("Revert: %v", err)
+	}
+	if reverted.Price != 59.9 {
+		t.Fatalf("price after revert = %v, want 59.9", reverted.Price)
+	}
+
+	books, err := c.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(books) != 1 {
+		t.Fatalf("GetAll returned %d books, want 1", len(books))
+	}
+
+	if err := c.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := c.GetByID(ctx, created.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("GetByID after delete err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCreateRetriesWithoutDuplicates(t *testing.T) {
+	router := newRouter(t)
+	// 第一次创建请求到达服务端并成功处理，但客户端收到的是 503，模拟响应丢失
+	var dropped int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && atomic.CompareAndSwapInt32(&dropped, 0, 1) {
+			router.ServeHTTP(httptest.NewRecorder(), r)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	c := newClient(srv.URL)
+	ctx := context.Background()
+
+	if _, err := c.Create(ctx, client.Book{ISBN: "978-7-222", Price: 10}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	books, err := c.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(books) != 1 {
+		t.Fatalf("GetAll returned %d books after retry, want 1", len(books))
+	}
+}
+
+func TestDeleteRetriesAfterSuccess(t *testing.T) {
+	router := newRouter(t)
+	// 第一次删除请求到达服务端并成功处理，但客户端收到的是 503，重试时图书已经不存在
+	var dropped int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete && atomic.CompareAndSwapInt32(&dropped, 0, 1) {
+			router.ServeHTTP(httptest.NewRecorder(), r)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	c := newClient(srv.URL)
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, client.Book{ISBN: "978-7-444", Price: 10})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := c.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := c.Delete(ctx, created.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("Delete of a deleted book err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	srv := httptest.NewServer(newRouter(t))
+	defer srv.Close()
+	ctx := context.Background()
+
+	c := newClient(srv.URL)
+	c.Tenant = ""
+	_, err := c.GetAll(ctx)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrUnauthorized) || apiErr.Message != "missing tenant" {
+		t.Fatalf("GetAll without tenant err = %v, want ErrUnauthorized", err)
+	}
+
+	c.Tenant = "demo"
+	created, err := c.Create(ctx, client.Book{ISBN: "978-7-333", Price: 1})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := c.Revert(ctx, created.ID, 9); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("Revert to unknown version err = %v, want ErrNotFound", err)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.GetAll(cctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetAll with canceled context err = %v, want context.Canceled", err)
+	}
+}
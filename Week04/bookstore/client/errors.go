@@ -0,0 +1,45 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Error 是 API 返回的非 2xx 响应，可以用 errors.Is 和下面的哨兵错误比较
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("bookstore: %d %s", e.StatusCode, e.Message)
+}
+
+// Is 按状态码比较，errors.Is(err, client.ErrNotFound) 即可判断是否是 404
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.StatusCode == e.StatusCode
+}
+
+var (
+	ErrBadRequest          = &Error{StatusCode: http.StatusBadRequest}
+	ErrUnauthorized        = &Error{StatusCode: http.StatusUnauthorized}
+	ErrForbidden           = &Error{StatusCode: http.StatusForbidden}
+	ErrNotFound            = &Error{StatusCode: http.StatusNotFound}
+	ErrConflict            = &Error{StatusCode: http.StatusConflict}
+	ErrUnprocessableEntity = &Error{StatusCode: http.StatusUnprocessableEntity}
+	ErrTooManyRequests     = &Error{StatusCode: http.StatusTooManyRequests}
+	ErrServiceUnavailable  = &Error{StatusCode: http.StatusServiceUnavailable}
+)
+
+// decodeError 解析形如 {"error": "..."} 的错误响应，没有响应体时使用状态码的描述
+func decodeError(statusCode int, body []byte) *Error {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error == "" {
+		resp.Error = http.StatusText(statusCode)
+	}
+	return &Error{StatusCode: statusCode, Message: resp.Error}
+}
@@ -0,0 +1,52 @@
+package client
+
+import "time"
+
+// 下面的类型和 internal/dto 中的 JSON 格式保持一致。客户端单独声明，不引用服务端的包，
+// 使用客户端不需要依赖 gorm 和服务端的模型
+
+// Book 对应 dto.BookDTO
+type Book struct {
+	ID          uint    `json:"id,string,omitempty"`
+	ISBN        string  `json:"isbn"`
+	Title       string  `json:"title,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Author      string  `json:"author,omitempty"`
+	Publisher   string  `json:"publisher,omitempty"`
+	Category    string  `json:"category,omitempty"`
+	Price       float32 `json:"price,string"`
+	// CoverUpdatedAt 只在有封面时返回，只读
+	CoverUpdatedAt *time.Time `json:"cover_updated_at,omitempty"`
+	// AverageRating 和 RatingCount 由审核通过的评论计算，只读
+	AverageRating float64 `json:"average_rating"`
+	RatingCount   int     `json:"rating_count"`
+}
+
+// 图书变更动作，对应 Revision.Action
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionRevert = "revert"
+	ActionEnrich = "enrich"
+)
+
+// Revision 对应 dto.RevisionDTO，是图书的一次变更
+type Revision struct {
+	Version   int           `json:"version"`
+	Action    string        `json:"action"`
+	Actor     string        `json:"actor"`
+	Changes   []FieldChange `json:"changes"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// FieldChange 是一个字段变更前后的值
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type revertRequest struct {
+	Version int `json:"version"`
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/wire"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/app"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/feed"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/outbox"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/scheduler"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/webhook"
+)
+
+// backgroundSet 提供后台任务
+var backgroundSet = wire.NewSet(newDispatcher, webhook.NewWorker, newScheduler, feed.NewTailer)
+
+// newDispatcher 只投递给 webhook，变更流由每个实例的 feed.Tailer 直接读取 outbox 表
+func newDispatcher(repo repository.OutboxRepository, clk clock.Clock,
+	webhookService service.WebhookService) *outbox.Dispatcher {
+	return outbox.NewDispatcher(repo, clk, outbox.LogPublisher{}, outbox.PublisherFunc(webhookService.Enqueue))
+}
+
+// newScheduler 注册维护和计算推荐的任务，cfg.Jobs 中没有配置 cron 表达式的任务不运行
+func newScheduler(cfg config.Config, leases repository.LeaseRepository, clk clock.Clock,
+	maintenance service.MaintenanceService, recommendations service.RecommendationService) (*scheduler.Scheduler, error) {
+	s := scheduler.New(leases, clk)
+	jobs := []scheduler.Job{
+		{Name: "books.purge", Timeout: 30 * time.Minute, Run: maintenance.PurgeDeletedBooks},
+		{Name: "idempotency.expire", Timeout: time.Minute, Run: maintenance.ExpireIdempotency},
+		{Name: "ratings.recompute", Timeout: 10 * time.Minute, Run: maintenance.RecomputeRatings},
+		{Name: "recommendations.compute", Timeout: 10 * time.Minute, Run: recommendations.Compute},
+	}
+	for _, job := range jobs {
+		if job.Spec = cfg.Jobs[job.Name]; job.Spec == "" {
+			continue
+		}
+		if err := s.Add(job); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// newApp 注册各个组件，数据库由 wire 的 cleanup 在所有组件停止后关闭
+func newApp(cfg config.Config, s *http.Server, dispatcher *outbox.Dispatcher, worker *webhook.Worker,
+	jobs *scheduler.Scheduler, enricher *service.Enricher, hub *feed.Hub, tailer *feed.Tailer) *app.App {
+	a := app.New()
+	a.StopTimeout = cfg.StopTimeout
+	a.Background("outbox", func(ctx context.Context) error {
+		dispatcher.Run(ctx)
+		return nil
+	})
+	a.Background("webhook", func(ctx context.Context) error {
+		worker.Run(ctx)
+		return nil
+	})
+	a.Background("enricher", func(ctx context.Context) error {
+		enricher.Run(ctx)
+		return nil
+	})
+	a.Background("scheduler", func(ctx context.Context) error {
+		jobs.Run(ctx)
+		return nil
+	})
+	a.Background("feed", func(ctx context.Context) error {
+		tailer.Run(ctx)
+		return nil
+	})
+	// Shutdown 会等待所有请求结束，先断开变更流的长连接
+	s.RegisterOnShutdown(hub.Close)
+	a.Server("http", s)
+	return a
+}
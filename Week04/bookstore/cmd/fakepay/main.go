@@ -0,0 +1,47 @@
+// fakepay 单独运行 payment.FakeGateway，用于在本地对接使用 MySQL 的书店
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/payment"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "listen address")
+	apiKey := flag.String("api-key", "", "API key required from clients, empty to accept any")
+	secret := flag.String("secret", "local", "secret used to sign callbacks")
+	callback := flag.String("callback", "http://127.0.0.1:8080/api/v1/payments/callback", "callback URL")
+	delay := flag.Duration("delay", 3*time.Second, "time before delayed payments are settled")
+	latency := flag.Duration("latency", 0, "latency added to every request")
+	flag.Parse()
+
+	fake := payment.NewFakeGateway(*apiKey, *secret, *callback)
+	fake.Delay = *delay
+	fake.Latency = *latency
+	srv := &http.Server{Addr: *addr, Handler: fake}
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("shutdown err: %v", err)
+		}
+	}()
+
+	log.Printf("fake payment gateway listening on %s", *addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("listen err: %v", err)
+	}
+	fake.Close()
+}
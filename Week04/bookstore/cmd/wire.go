@@ -1,15 +1,38 @@
+//go:build wireinject
+// +build wireinject
+
 package main
 
 import (
-	"github.com/jinzhu/gorm"
-
 	"github.com/google/wire"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/api/gql"
 	v1 "github.com/yngwiewang/Go-000/Week04/bookstore/api/v1"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/app"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/blob"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/feed"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/metadata"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/payment"
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository/memory"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/routers"
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
 )
 
-func initBookAPI(db *gorm.DB) v1.BookAPI {
-	wire.Build(repository.NewBookRepository, service.NewBookService, v1.NewBookAPI)
-	return v1.BookAPI{}
+// appSet 是和存储、时钟无关的部分
+var appSet = wire.NewSet(config.ProviderSet, blob.ProviderSet, service.ProviderSet, v1.ProviderSet,
+	gql.ProviderSet, feed.ProviderSet, routers.ProviderSet, backgroundSet, newApp)
+
+// initApp 构建使用 MySQL 的应用
+func initApp() (*app.App, func(), error) {
+	wire.Build(appSet, repository.MySQLSet, metadata.ProviderSet, payment.ProviderSet, clock.RealSet)
+	return nil, nil, nil
+}
+
+// initLocalApp 构建使用内存仓储、假的图书信息接口和进程内支付网关的应用，用于本地运行
+func initLocalApp() (*app.App, func(), error) {
+	wire.Build(appSet, memory.ProviderSet, metadata.FakeSet, payment.FakeSet, clock.RealSet)
+	return nil, nil, nil
 }
@@ -1,22 +1,247 @@
 // Code generated by Wire. DO NOT EDIT.
 
 //go:generate wire
-//+build !wireinject
+//go:build !wireinject
+// +build !wireinject
 
 package main
 
 import (
-	"github.com/jinzhu/gorm"
+	"github.com/google/wire"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/api/gql"
 	"github.com/yngwiewang/Go-000/Week04/bookstore/api/v1"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/app"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/blob"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/feed"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/metadata"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/payment"
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository/memory"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/routers"
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/webhook"
 )
 
 // Injectors from wire.go:
 
-func InitBookAPI(db *gorm.DB) v1.BookAPI {
-	bookRepository := repository.NewBookRepository(db)
-	bookService := service.NewBookService(bookRepository)
-	bookAPI := v1.NewBookAPI(bookService)
-	return bookAPI
+func initApp() (*app.App, func(), error) {
+	configConfig, err := config.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	clockClock := clock.NewReal()
+	db, cleanup, err := repository.NewDB(configConfig, clockClock)
+	if err != nil {
+		return nil, nil, err
+	}
+	cluster, cleanup2, err := repository.NewCluster(configConfig, db, clockClock)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	breaker := repository.NewBreaker(configConfig, clockClock)
+	bookRepository := repository.NewBookRepositoryWithBreaker(cluster, breaker)
+	historyRepository := repository.NewHistoryRepository(db)
+	pricingRuleRepository := repository.NewPricingRuleRepository(cluster)
+	categoryRepository := repository.NewCategoryRepository(db)
+	transactor := repository.NewTransactor(db, breaker)
+	openLibrary := metadata.NewOpenLibrary(configConfig)
+	cache := metadata.NewCache(configConfig, openLibrary, clockClock)
+	local, err := blob.NewLocal(configConfig)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	coverService := service.NewCoverService(bookRepository, transactor, local, clockClock)
+	enricher := service.NewEnricher(configConfig, cache, transactor, coverService)
+	bookService := service.NewBookService(configConfig, bookRepository, historyRepository, pricingRuleRepository, categoryRepository, transactor, enricher, clockClock)
+	categoryService := service.NewCategoryService(categoryRepository, bookRepository)
+	translationRepository := repository.NewTranslationRepository(db)
+	translationService := service.NewTranslationService(configConfig, bookRepository, translationRepository)
+	bookEncoders := v1.NewBookEncoders()
+	bookAPI := v1.NewBookAPI(bookService, categoryService, translationService, bookEncoders)
+	subscriptionRepository := repository.NewSubscriptionRepository(db)
+	deliveryRepository := repository.NewDeliveryRepository(db)
+	webhookService := service.NewWebhookService(subscriptionRepository, deliveryRepository, clockClock)
+	webhookAPI := v1.NewWebhookAPI(webhookService)
+	tenantRepository := repository.NewTenantRepositoryWithBreaker(db, breaker)
+	tenantService := service.NewTenantService(tenantRepository)
+	tenantAPI := v1.NewTenantAPI(tenantService)
+	pricingService := service.NewPricingService(pricingRuleRepository, categoryRepository)
+	pricingAPI := v1.NewPricingAPI(pricingService)
+	coverAPI := v1.NewCoverAPI(configConfig, coverService)
+	reviewRepository := repository.NewReviewRepository(db)
+	reviewService := service.NewReviewService(bookRepository, reviewRepository, transactor)
+	reviewAPI := v1.NewReviewAPI(reviewService)
+	handler, err := gql.NewHandler(configConfig, bookService)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	breakerAPI := v1.NewBreakerAPI(breaker)
+	hub := feed.NewHub(configConfig)
+	streamAPI := v1.NewStreamAPI(configConfig, hub)
+	cartRepository := repository.NewCartRepository(db)
+	cartService := service.NewCartService(bookRepository, cartRepository, bookService)
+	cartAPI := v1.NewCartAPI(cartService)
+	orderRepository := repository.NewOrderRepository(db)
+	gateway := payment.NewGateway(configConfig, clockClock)
+	paymentRepository := repository.NewPaymentRepository(db)
+	paymentService := service.NewPaymentService(configConfig, gateway, paymentRepository, orderRepository, tenantRepository, transactor)
+	orderService := service.NewOrderService(orderRepository, transactor, paymentService, bookService)
+	orderAPI := v1.NewOrderAPI(orderService)
+	paymentAPI := v1.NewPaymentAPI(paymentService)
+	recommendationRepository := repository.NewRecommendationRepository(db)
+	recommendationService := service.NewRecommendationService(configConfig, bookRepository, tenantRepository, recommendationRepository, clockClock)
+	recommendationAPI := v1.NewRecommendationAPI(recommendationService)
+	categoryAPI := v1.NewCategoryAPI(categoryService)
+	translationAPI := v1.NewTranslationAPI(translationService)
+	apIs := routers.APIs{
+		Book:           bookAPI,
+		Webhook:        webhookAPI,
+		Tenant:         tenantAPI,
+		Pricing:        pricingAPI,
+		Cover:          coverAPI,
+		Review:         reviewAPI,
+		GraphQL:        handler,
+		Breaker:        breakerAPI,
+		Stream:         streamAPI,
+		Cart:           cartAPI,
+		Order:          orderAPI,
+		Payment:        paymentAPI,
+		Recommendation: recommendationAPI,
+		Category:       categoryAPI,
+		Translation:    translationAPI,
+	}
+	idempotencyRepository := repository.NewIdempotencyRepository(db)
+	engine := routers.NewRouter(configConfig, clockClock, apIs, tenantService, idempotencyRepository)
+	server := routers.NewHTTPServer(configConfig, engine)
+	outboxRepository := repository.NewOutboxRepository(db)
+	dispatcher := newDispatcher(outboxRepository, clockClock, webhookService)
+	worker := webhook.NewWorker(configConfig, subscriptionRepository, deliveryRepository, clockClock)
+	leaseRepository := repository.NewLeaseRepository(db)
+	maintenanceRepository := repository.NewMaintenanceRepository(db)
+	maintenanceService := service.NewMaintenanceService(configConfig, maintenanceRepository, idempotencyRepository, coverService, clockClock)
+	scheduler, err := newScheduler(configConfig, leaseRepository, clockClock, maintenanceService, recommendationService)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	tailer := feed.NewTailer(outboxRepository, hub, clockClock)
+	appApp := newApp(configConfig, server, dispatcher, worker, scheduler, enricher, hub, tailer)
+	return appApp, func() {
+		cleanup2()
+		cleanup()
+	}, nil
 }
+
+func initLocalApp() (*app.App, func(), error) {
+	configConfig, err := config.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	clockClock := clock.NewReal()
+	store := memory.NewStore(clockClock)
+	bookRepository := store.Books
+	historyRepository := store.History
+	pricingRuleRepository := store.PricingRules
+	categoryRepository := store.Categories
+	fake := metadata.NewSampleFake()
+	local, err := blob.NewLocal(configConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	coverService := service.NewCoverService(bookRepository, store, local, clockClock)
+	enricher := service.NewEnricher(configConfig, fake, store, coverService)
+	bookService := service.NewBookService(configConfig, bookRepository, historyRepository, pricingRuleRepository, categoryRepository, store, enricher, clockClock)
+	categoryService := service.NewCategoryService(categoryRepository, bookRepository)
+	translationRepository := store.Translations
+	translationService := service.NewTranslationService(configConfig, bookRepository, translationRepository)
+	bookEncoders := v1.NewBookEncoders()
+	bookAPI := v1.NewBookAPI(bookService, categoryService, translationService, bookEncoders)
+	subscriptionRepository := store.Subscriptions
+	deliveryRepository := store.Deliveries
+	webhookService := service.NewWebhookService(subscriptionRepository, deliveryRepository, clockClock)
+	webhookAPI := v1.NewWebhookAPI(webhookService)
+	tenantRepository := store.Tenants
+	tenantService := service.NewTenantService(tenantRepository)
+	tenantAPI := v1.NewTenantAPI(tenantService)
+	pricingService := service.NewPricingService(pricingRuleRepository, categoryRepository)
+	pricingAPI := v1.NewPricingAPI(pricingService)
+	coverAPI := v1.NewCoverAPI(configConfig, coverService)
+	reviewRepository := store.Reviews
+	reviewService := service.NewReviewService(bookRepository, reviewRepository, store)
+	reviewAPI := v1.NewReviewAPI(reviewService)
+	handler, err := gql.NewHandler(configConfig, bookService)
+	if err != nil {
+		return nil, nil, err
+	}
+	breaker := repository.NewBreaker(configConfig, clockClock)
+	breakerAPI := v1.NewBreakerAPI(breaker)
+	hub := feed.NewHub(configConfig)
+	streamAPI := v1.NewStreamAPI(configConfig, hub)
+	cartRepository := store.Carts
+	cartService := service.NewCartService(bookRepository, cartRepository, bookService)
+	cartAPI := v1.NewCartAPI(cartService)
+	orderRepository := store.Orders
+	gateway, cleanup, err := payment.NewLocalGateway(configConfig, clockClock)
+	if err != nil {
+		return nil, nil, err
+	}
+	paymentRepository := store.Payments
+	paymentService := service.NewPaymentService(configConfig, gateway, paymentRepository, orderRepository, tenantRepository, store)
+	orderService := service.NewOrderService(orderRepository, store, paymentService, bookService)
+	orderAPI := v1.NewOrderAPI(orderService)
+	paymentAPI := v1.NewPaymentAPI(paymentService)
+	recommendationRepository := store.Recommendations
+	recommendationService := service.NewRecommendationService(configConfig, bookRepository, tenantRepository, recommendationRepository, clockClock)
+	recommendationAPI := v1.NewRecommendationAPI(recommendationService)
+	categoryAPI := v1.NewCategoryAPI(categoryService)
+	translationAPI := v1.NewTranslationAPI(translationService)
+	apIs := routers.APIs{
+		Book:           bookAPI,
+		Webhook:        webhookAPI,
+		Tenant:         tenantAPI,
+		Pricing:        pricingAPI,
+		Cover:          coverAPI,
+		Review:         reviewAPI,
+		GraphQL:        handler,
+		Breaker:        breakerAPI,
+		Stream:         streamAPI,
+		Cart:           cartAPI,
+		Order:          orderAPI,
+		Payment:        paymentAPI,
+		Recommendation: recommendationAPI,
+		Category:       categoryAPI,
+		Translation:    translationAPI,
+	}
+	idempotencyRepository := store.Idempotency
+	engine := routers.NewRouter(configConfig, clockClock, apIs, tenantService, idempotencyRepository)
+	server := routers.NewHTTPServer(configConfig, engine)
+	outboxRepository := store.Outbox
+	dispatcher := newDispatcher(outboxRepository, clockClock, webhookService)
+	worker := webhook.NewWorker(configConfig, subscriptionRepository, deliveryRepository, clockClock)
+	leaseRepository := store.Leases
+	maintenanceRepository := store.Maintenance
+	maintenanceService := service.NewMaintenanceService(configConfig, maintenanceRepository, idempotencyRepository, coverService, clockClock)
+	scheduler, err := newScheduler(configConfig, leaseRepository, clockClock, maintenanceService, recommendationService)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	tailer := feed.NewTailer(outboxRepository, hub, clockClock)
+	appApp := newApp(configConfig, server, dispatcher, worker, scheduler, enricher, hub, tailer)
+	return appApp, func() {
+		cleanup()
+	}, nil
+}
+
+// wire.go:
+
+// appSet 是和存储、时钟无关的部分
+var appSet = wire.NewSet(config.ProviderSet, blob.ProviderSet, service.ProviderSet, v1.ProviderSet, gql.ProviderSet, feed.ProviderSet, routers.ProviderSet, backgroundSet, newApp)
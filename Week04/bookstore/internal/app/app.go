@@ -0,0 +1,197 @@
+// Package app 管理进程内各个组件的生命周期。
+// 组件以 Hook 的形式注册，按依赖顺序启动，按相反的顺序停止；
+// 每个组件的停止都有自己的超时时间，启动和停止过程中的所有错误都会被收集并返回。
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const defaultStopTimeout = 5 * time.Second
+
+// Hook 描述一个组件的启动和停止。Start 应该尽快返回，长时间运行的任务放到 goroutine 中，
+// 可以用 App.Background 和 App.Server 注册。Start 和 Stop 都可以为空
+type Hook struct {
+	Name      string
+	DependsOn []string
+	Start     func(ctx context.Context) error
+	Stop      func(ctx context.Context) error
+	// StopTimeout 为 0 时使用 App.StopTimeout
+	StopTimeout time.Duration
+}
+
+// App 是组件的生命周期管理器
+type App struct {
+	StopTimeout time.Duration
+
+	hooks   []Hook
+	started []Hook
+	errc    chan error
+}
+
+func New() *App {
+	return &App{
+		StopTimeout: defaultStopTimeout,
+		errc:        make(chan error, 1),
+	}
+}
+
+// Register 注册一个组件，组件名不能重复
+func (a *App) Register(h Hook) {
+	a.hooks = append(a.hooks, h)
+}
+
+// Fail 报告组件在运行期间的致命错误，Run 收到后开始停止整个应用
+func (a *App) Fail(err error) {
+	select {
+	case a.errc <- err:
+	default:
+	}
+}
+
+// Start 按依赖顺序启动所有组件。某个组件启动失败时，已经启动的组件按相反顺序停止
+func (a *App) Start(ctx context.Context) error {
+	hooks, err := sortHooks(a.hooks)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range hooks {
+		if h.Start != nil {
+			log.Printf("app: starting %s", h.Name)
+			if err := h.Start(ctx); err != nil {
+				errs := MultiError{fmt.Errorf("start %s: %w", h.Name, err)}
+				if err := a.Stop(); err != nil {
+					errs = append(errs, err.(MultiError)...)
+				}
+				return errs
+			}
+		}
+		a.started = append(a.started, h)
+	}
+	return nil
+}
+
+// Stop 按启动的相反顺序停止已经启动的组件，某个组件停止失败或超时不影响其他组件的停止
+func (a *App) Stop() error {
+	var errs MultiError
+	for i := len(a.started) - 1; i >= 0; i-- {
+		h := a.started[i]
+		if h.Stop == nil {
+			continue
+		}
+		timeout := h.StopTimeout
+		if timeout == 0 {
+			timeout = a.StopTimeout
+		}
+
+		log.Printf("app: stopping %s", h.Name)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		if err := h.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", h.Name, err))
+		}
+		cancel()
+	}
+	a.started = nil
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Run 启动所有组件，等到收到 SIGINT/SIGTERM 或者有组件调用了 Fail 后停止所有组件
+func (a *App) Run() error {
+	if err := a.Start(context.Background()); err != nil {
+		return err
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	var errs MultiError
+	select {
+	case sig := <-quit:
+		log.Printf("app: received %s, shutting down...", sig)
+	case err := <-a.errc:
+		log.Printf("app: %v, shutting down...", err)
+		errs = append(errs, err)
+	}
+
+	if err := a.Stop(); err != nil {
+		errs = append(errs, err.(MultiError)...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// sortHooks 按依赖做拓扑排序，没有依赖关系的组件保持注册顺序
+func sortHooks(hooks []Hook) ([]Hook, error) {
+	index := make(map[string]int, len(hooks))
+	for i, h := range hooks {
+		if _, ok := index[h.Name]; ok {
+			return nil, fmt.Errorf("app: duplicate component %q", h.Name)
+		}
+		index[h.Name] = i
+	}
+	for _, h := range hooks {
+		for _, dep := range h.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("app: %s depends on unknown component %q", h.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(hooks))
+	sorted := make([]Hook, 0, len(hooks))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("app: dependency cycle %s", strings.Join(append(path, hooks[i].Name), " -> "))
+		}
+		state[i] = visiting
+		for _, dep := range hooks[i].DependsOn {
+			if err := visit(index[dep], append(path, hooks[i].Name)); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		sorted = append(sorted, hooks[i])
+		return nil
+	}
+	for i := range hooks {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// MultiError 收集了启动和停止过程中的多个错误
+type MultiError []error
+
+func (m MultiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
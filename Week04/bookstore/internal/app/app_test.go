@@ -0,0 +1,250 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/app"
+)
+
+// recorder 记录组件启动和停止的顺序
+type recorder struct {
+	events []string
+}
+
+// hook 返回一个记录启动和停止的组件，startErr 和 stopErr 是 Start 和 Stop 返回的错误
+func (r *recorder) hook(name string, startErr, stopErr error, dependsOn ...string) app.Hook {
+	return app.Hook{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(context.Context) error {
+			r.events = append(r.events, "start "+name)
+			return startErr
+		},
+		Stop: func(context.Context) error {
+			r.events = append(r.events, "stop "+name)
+			return stopErr
+		},
+	}
+}
+
+func TestStartOrder(t *testing.T) {
+	var r recorder
+	a := app.New()
+	a.Register(r.hook("http", nil, nil, "db", "cache"))
+	a.Register(r.hook("db", nil, nil))
+	a.Register(r.hook("cache", nil, nil, "db"))
+	a.Register(r.hook("worker", nil, nil))
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"start db", "start cache", "start http", "start worker",
+		"stop worker", "stop http", "stop cache", "stop db",
+	}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("events = %v, want %v", r.events, want)
+	}
+}
+
+// TestStartFailureStopsStarted 后面的组件启动失败时，已经启动的组件按相反顺序停止，后面的组件不再启动
+func TestStartFailureStopsStarted(t *testing.T) {
+	errStart := errors.New("listen failed")
+	errStop := errors.New("flush failed")
+	var r recorder
+	a := app.New()
+	a.Register(r.hook("db", nil, nil))
+	a.Register(r.hook("cache", nil, errStop, "db"))
+	a.Register(r.hook("http", errStart, nil, "cache"))
+	a.Register(r.hook("worker", nil, nil, "http"))
+
+	err := a.Start(context.Background())
+	want := []string{"start db", "start cache", "start http", "stop cache", "stop db"}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("events = %v, want %v", r.events, want)
+	}
+	errs, ok := err.(app.MultiError)
+	if !ok || len(errs) != 2 || !errors.Is(errs[0], errStart) || !errors.Is(errs[1], errStop) {
+		t.Fatalf("Start() = %v, want start and stop errors", err)
+	}
+
+	// 回滚之后再次 Stop 不会重复停止
+	r.events = nil
+	if err := a.Stop(); err != nil || len(r.events) != 0 {
+		t.Fatalf("Stop() = %v, events %v, want nothing stopped", err, r.events)
+	}
+}
+
+func TestInvalidDependencies(t *testing.T) {
+	tests := []struct {
+		name  string
+		hooks []app.Hook
+		want  string
+	}{
+		{
+			name:  "duplicate",
+			hooks: []app.Hook{{Name: "db"}, {Name: "db"}},
+			want:  `duplicate component "db"`,
+		},
+		{
+			name:  "unknown",
+			hooks: []app.Hook{{Name: "http", DependsOn: []string{"db"}}},
+			want:  `http depends on unknown component "db"`,
+		},
+		{
+			name: "cycle",
+			hooks: []app.Hook{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"c"}},
+				{Name: "c", DependsOn: []string{"a"}},
+			},
+			want: "dependency cycle a -> b -> c -> a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := false
+			a := app.New()
+			for _, h := range tt.hooks {
+				h.Start = func(context.Context) error {
+					started = true
+					return nil
+				}
+				a.Register(h)
+			}
+			err := a.Start(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Start() = %v, want %q", err, tt.want)
+			}
+			if started {
+				t.Fatal("components started despite invalid dependencies")
+			}
+		})
+	}
+}
+
+// TestStopTimeout 组件停止超时后返回 context.DeadlineExceeded，不影响其他组件停止
+func TestStopTimeout(t *testing.T) {
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	var stopped []string
+	a := app.New()
+	a.StopTimeout = 20 * time.Millisecond
+	a.Register(app.Hook{Name: "db", Stop: func(context.Context) error {
+		stopped = append(stopped, "db")
+		return nil
+	}})
+	a.Register(app.Hook{Name: "slow", Stop: block, DependsOn: []string{"db"}})
+	a.Register(app.Hook{Name: "slower", Stop: block, StopTimeout: 50 * time.Millisecond, DependsOn: []string{"db"}})
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	begin := time.Now()
+	err := a.Stop()
+	elapsed := time.Since(begin)
+	errs, ok := err.(app.MultiError)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Stop() = %v, want two timeouts", err)
+	}
+	for _, err := range errs {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Stop() = %v, want context.DeadlineExceeded", err)
+		}
+	}
+	if !strings.HasPrefix(errs[0].Error(), "stop slower") || !strings.HasPrefix(errs[1].Error(), "stop slow:") {
+		t.Fatalf("Stop() = %v, want slower then slow", err)
+	}
+	if !reflect.DeepEqual(stopped, []string{"db"}) {
+		t.Fatalf("stopped = %v, want db stopped after timeouts", stopped)
+	}
+	// 每个组件使用自己的超时时间：50ms + 20ms
+	if elapsed < 70*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("Stop() took %v, want about 70ms", elapsed)
+	}
+}
+
+func TestBackground(t *testing.T) {
+	t.Run("stop cancels run", func(t *testing.T) {
+		a := app.New()
+		a.Background("worker", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if err := a.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Stop(); err != nil {
+			t.Fatalf("Stop() = %v, want nil", err)
+		}
+	})
+
+	t.Run("stop times out", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		a := app.New()
+		a.StopTimeout = 10 * time.Millisecond
+		a.Background("stuck", func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+		if err := a.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		err := a.Stop()
+		if errs, ok := err.(app.MultiError); !ok || len(errs) != 1 || !errors.Is(errs[0], context.DeadlineExceeded) {
+			t.Fatalf("Stop() = %v, want context.DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("failure stops app", func(t *testing.T) {
+		errRun := errors.New("connection lost")
+		var r recorder
+		a := app.New()
+		a.Register(r.hook("db", nil, nil))
+		a.Background("worker", func(ctx context.Context) error {
+			return errRun
+		}, "db")
+		err := a.Run()
+		errs, ok := err.(app.MultiError)
+		if !ok || len(errs) != 1 || !errors.Is(errs[0], errRun) {
+			t.Fatalf("Run() = %v, want %v", err, errRun)
+		}
+		if want := []string{"start db", "stop db"}; !reflect.DeepEqual(r.events, want) {
+			t.Fatalf("events = %v, want %v", r.events, want)
+		}
+	})
+}
+
+// TestServerListenFailure 端口被占用时启动失败，已经启动的组件被停止
+func TestServerListenFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var r recorder
+	a := app.New()
+	a.Register(r.hook("db", nil, nil))
+	a.Server("http", &http.Server{Addr: ln.Addr().String()}, "db")
+	err = a.Start(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "start http") {
+		t.Fatalf("Start() = %v, want listen error", err)
+	}
+	if want := []string{"start db", "stop db"}; !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("events = %v, want %v", r.events, want)
+	}
+}
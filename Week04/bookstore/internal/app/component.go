@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Background 注册一个后台任务：启动时在新的 goroutine 中运行 run，
+// 停止时取消 run 的 ctx 并等待它返回。run 在停止之前返回错误会让整个应用退出
+func (a *App) Background(name string, run func(ctx context.Context) error, dependsOn ...string) {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+
+	a.Register(Hook{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				if err := run(ctx); err != nil && ctx.Err() == nil {
+					a.Fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+// Server 注册一个 http server：启动时同步监听端口，这样端口被占用之类的错误会让启动失败；
+// 停止时调用 Shutdown 等待在途请求处理完成
+func (a *App) Server(name string, s *http.Server, dependsOn ...string) {
+	a.Register(Hook{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(context.Context) error {
+			ln, err := net.Listen("tcp", s.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := s.Serve(ln); err != nil && err != http.ErrServerClosed {
+					a.Fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		Stop: s.Shutdown,
+	})
+}
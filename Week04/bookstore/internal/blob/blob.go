@@ -0,0 +1,31 @@
+// Package blob 定义二进制对象的存储接口，目前只有本地文件系统的实现
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/google/wire"
+)
+
+// ProviderSet 提供本地文件系统实现，并绑定到 Store 接口
+var ProviderSet = wire.NewSet(NewLocal, wire.Bind(new(Store), new(*Local)))
+
+// ErrNotFound 表示对象不存在
+var ErrNotFound = errors.New("blob not found")
+
+// Info 是对象的元信息
+type Info struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Store 按 key 存取对象，key 使用 / 分隔的相对路径
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,269 @@
+// Package breaker 实现熔断器：滚动窗口内的错误率超过阈值时打开，打开期间直接失败，
+// 冷却时间过后进入半开状态放行一个探测请求，探测成功则关闭，失败则重新打开
+package breaker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type Settings struct {
+	// Window 是统计错误率的滚动窗口
+	Window time.Duration
+	// ErrorPercent 是打开熔断器的错误率阈值，取值 1~100
+	ErrorPercent int
+	// MinRequests 是窗口内最少的请求数，请求太少时不打开熔断器
+	MinRequests int64
+	// SleepWindow 是打开后进入半开状态前的冷却时间
+	SleepWindow time.Duration
+	// Timeout 是单次调用的超时时间，超时的调用计一次失败，0 表示不限制
+	Timeout time.Duration
+	// IsFailure 判断错误是否计入错误率，为空时所有错误都计入
+	IsFailure func(error) bool
+}
+
+// OpenError 表示熔断器没有放行请求，RetryAfter 是建议的重试间隔
+type OpenError struct {
+	Name       string
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	key, args := e.Message()
+	msg, _ := i18n.Translate(nil, key, args...)
+	return msg
+}
+
+// Message 返回翻译用的 message key 和参数
+func (e *OpenError) Message() (string, []interface{}) {
+	return "breaker.open", []interface{}{e.Name}
+}
+
+// TimeoutError 表示调用超过了 Timeout，调用方已经不再等待它的结果
+type TimeoutError struct {
+	Name    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	key, args := e.Message()
+	msg, _ := i18n.Translate(nil, key, args...)
+	return msg
+}
+
+// Message 返回翻译用的 message key 和参数
+func (e *TimeoutError) Message() (string, []interface{}) {
+	return "breaker.timeout", []interface{}{e.Name}
+}
+
+// Unwrap 让 errors.Is(err, context.DeadlineExceeded) 成立
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// Stats 是熔断器当前的状态，用于监控
+type Stats struct {
+	Name     string
+	State    State
+	Requests int64
+	Failures int64
+	// OpenedAt 是最近一次打开的时间，从未打开过时为零值
+	OpenedAt time.Time
+}
+
+type Breaker struct {
+	name     string
+	settings Settings
+	clock    clock.Clock
+
+	mu       sync.Mutex
+	state    State
+	openedAt time.Time
+	probing  bool
+	counts   *rollingNumber
+	// generation 在每次状态切换时加一，调用结束时只有放行它的那一代状态还在才计数
+	generation uint64
+}
+
+func New(name string, s Settings, clk clock.Clock) *Breaker {
+	return &Breaker{
+		name:     name,
+		settings: s,
+		clock:    clk,
+		counts:   newRollingNumber(s.Window),
+	}
+}
+
+// Do 在熔断器放行时执行 fn，没有放行时返回 *OpenError。fn panic 也算一次失败。
+// 设置了 Timeout 时 fn 在单独的 goroutine 中执行，超时后 Do 立即返回 *TimeoutError，
+// 这时 fn 收到的 ctx 已经到期，fn 不能再提交任何修改
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	generation, err := b.allow()
+	if err != nil {
+		return err
+	}
+	if b.settings.Timeout <= 0 {
+		failed := true
+		defer func() { b.done(generation, failed) }()
+
+		err := fn(ctx)
+		failed = b.isFailure(err)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.settings.Timeout)
+	defer cancel()
+
+	type result struct {
+		err      error
+		panicked bool
+		value    interface{}
+	}
+	ch := make(chan result, 1)
+	go func() {
+		r := result{panicked: true}
+		defer func() {
+			if r.panicked {
+				r.value = recover()
+			}
+			ch <- r
+		}()
+		r.err = fn(ctx)
+		r.panicked = false
+	}()
+
+	select {
+	case r := <-ch:
+		if r.panicked {
+			b.done(generation, true)
+			panic(r.value)
+		}
+		b.done(generation, b.isFailure(r.err))
+		return r.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.Canceled) {
+			// 调用方自己放弃了，不说明被保护的资源有问题
+			b.release(generation)
+			return ctx.Err()
+		}
+		b.done(generation, true)
+		return &TimeoutError{Name: b.name, Timeout: b.settings.Timeout}
+	}
+}
+
+func (b *Breaker) isFailure(err error) bool {
+	return err != nil && (b.settings.IsFailure == nil || b.settings.IsFailure(err))
+}
+
+// allow 决定是否放行一次调用，返回放行时的状态代数
+func (b *Breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	switch b.state {
+	case Open:
+		wait := b.openedAt.Add(b.settings.SleepWindow).Sub(now)
+		if wait > 0 {
+			return 0, &OpenError{Name: b.name, RetryAfter: wait}
+		}
+		b.setState(HalfOpen)
+		b.probing = true
+	case HalfOpen:
+		// 半开状态只放行一个探测请求
+		if b.probing {
+			return 0, &OpenError{Name: b.name, RetryAfter: time.Second}
+		}
+		b.probing = true
+	}
+	return b.generation, nil
+}
+
+// done 结束一次调用。放行之后状态已经切换过的调用不再计数，
+// 比如打开之前放行、半开之后才结束的请求不会被当成探测请求
+func (b *Breaker) done(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+	now := b.clock.Now()
+	switch b.state {
+	case Closed:
+		b.counts.record(now, failed)
+		requests, failures := b.counts.sum(now)
+		if requests >= b.settings.MinRequests && failures*100 >= int64(b.settings.ErrorPercent)*requests {
+			b.trip(now)
+		}
+	case HalfOpen:
+		b.probing = false
+		if failed {
+			b.trip(now)
+			return
+		}
+		b.counts.reset()
+		b.setState(Closed)
+	}
+}
+
+// release 结束一次既不算成功也不算失败的调用，半开状态下允许放行下一个探测请求
+func (b *Breaker) release(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation == b.generation && b.state == HalfOpen {
+		b.probing = false
+	}
+}
+
+func (b *Breaker) trip(now time.Time) {
+	b.openedAt = now
+	b.setState(Open)
+}
+
+func (b *Breaker) setState(s State) {
+	log.Printf("circuit breaker %s: %s -> %s", b.name, b.state, s)
+	b.state = s
+	b.generation++
+}
+
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	requests, failures := b.counts.sum(b.clock.Now())
+	return Stats{
+		Name:     b.name,
+		State:    b.state,
+		Requests: requests,
+		Failures: failures,
+		OpenedAt: b.openedAt,
+	}
+}
@@ -0,0 +1,298 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/breaker"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+)
+
+var errBackend = errors.New("backend error")
+
+func newBreaker(clk clock.Clock, timeout time.Duration) *breaker.Breaker {
+	return breaker.New("test", breaker.Settings{
+		Window:       10 * time.Second,
+		ErrorPercent: 50,
+		MinRequests:  4,
+		SleepWindow:  5 * time.Second,
+		Timeout:      timeout,
+	}, clk)
+}
+
+func succeed(ctx context.Context) error { return nil }
+
+func fail(ctx context.Context) error { return errBackend }
+
+// run 依次执行调用，true 表示失败
+func run(b *breaker.Breaker, results ...bool) {
+	for _, failed := range results {
+		if failed {
+			_ = b.Do(context.Background(), fail)
+		} else {
+			_ = b.Do(context.Background(), succeed)
+		}
+	}
+}
+
+// trip 让熔断器打开
+func trip(t *testing.T, b *breaker.Breaker) {
+	t.Helper()
+	run(b, true, true, true, true)
+	if s := b.Stats().State; s != breaker.Open {
+		t.Fatalf("State = %v, want open", s)
+	}
+}
+
+// start 在 goroutine 中执行一次调用，fn 开始执行后才返回，
+// 向返回的 channel 发送错误让调用结束，Do 的结果从 result 读取
+func start(b *breaker.Breaker) (finish chan<- error, result <-chan error) {
+	started := make(chan struct{})
+	f := make(chan error)
+	r := make(chan error, 1)
+	go func() {
+		r <- b.Do(context.Background(), func(ctx context.Context) error {
+			close(started)
+			return <-f
+		})
+	}()
+	<-started
+	return f, r
+}
+
+func TestTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []bool
+		want    breaker.State
+	}{
+		{name: "no requests", want: breaker.Closed},
+		{name: "below min requests", results: []bool{true, true, true}, want: breaker.Closed},
+		{name: "below error percent", results: []bool{false, false, false, true, true}, want: breaker.Closed},
+		{name: "at error percent", results: []bool{false, false, true, true}, want: breaker.Open},
+		{name: "all failed", results: []bool{true, true, true, true}, want: breaker.Open},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(clock.NewFakeNow(), 0)
+			run(b, tt.results...)
+			if s := b.Stats().State; s != tt.want {
+				t.Fatalf("State = %v, want %v", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowExpires(t *testing.T) {
+	clk := clock.NewFakeNow()
+	b := newBreaker(clk, 0)
+	run(b, true, true, true)
+	clk.Add(10 * time.Second)
+	run(b, true)
+	if s := b.Stats(); s.State != breaker.Closed || s.Requests != 1 {
+		t.Fatalf("Stats = %+v, want closed with 1 request", s)
+	}
+}
+
+func TestIsFailure(t *testing.T) {
+	b := breaker.New("test", breaker.Settings{
+		Window: 10 * time.Second, ErrorPercent: 50, MinRequests: 1, SleepWindow: 5 * time.Second,
+		IsFailure: func(err error) bool { return err != errBackend },
+	}, clock.NewFakeNow())
+	if err := b.Do(context.Background(), fail); err != errBackend {
+		t.Fatalf("Do = %v, want %v", err, errBackend)
+	}
+	if s := b.Stats(); s.State != breaker.Closed || s.Failures != 0 {
+		t.Fatalf("Stats = %+v, want closed without failures", s)
+	}
+}
+
+func TestOpenAndRecover(t *testing.T) {
+	tests := []struct {
+		name  string
+		probe func(ctx context.Context) error
+		want  breaker.State
+	}{
+		{name: "probe succeeds", probe: succeed, want: breaker.Closed},
+		{name: "probe fails", probe: fail, want: breaker.Open},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFakeNow()
+			b := newBreaker(clk, 0)
+			trip(t, b)
+
+			clk.Add(2 * time.Second)
+			called := false
+			err := b.Do(context.Background(), func(ctx context.Context) error {
+				called = true
+				return nil
+			})
+			var open *breaker.OpenError
+			if !errors.As(err, &open) || called {
+				t.Fatalf("Do = %v, called = %v, want *OpenError without calling", err, called)
+			}
+			if open.RetryAfter != 3*time.Second {
+				t.Fatalf("RetryAfter = %v, want 3s", open.RetryAfter)
+			}
+
+			clk.Add(3 * time.Second)
+			_ = b.Do(context.Background(), tt.probe)
+			s := b.Stats()
+			if s.State != tt.want {
+				t.Fatalf("State = %v, want %v", s.State, tt.want)
+			}
+			// 关闭时重新开始统计，之前的失败不会让熔断器马上再打开
+			if s.State == breaker.Closed && s.Requests != 0 {
+				t.Fatalf("Requests = %v, want 0", s.Requests)
+			}
+		})
+	}
+}
+
+func TestHalfOpenAllowsOneProbe(t *testing.T) {
+	clk := clock.NewFakeNow()
+	b := newBreaker(clk, 0)
+	trip(t, b)
+	clk.Add(5 * time.Second)
+
+	finish, result := start(b)
+	if s := b.Stats().State; s != breaker.HalfOpen {
+		t.Fatalf("State = %v, want half-open", s)
+	}
+	var open *breaker.OpenError
+	if err := b.Do(context.Background(), succeed); !errors.As(err, &open) {
+		t.Fatalf("second probe: Do = %v, want *OpenError", err)
+	}
+	finish <- nil
+	if err := <-result; err != nil {
+		t.Fatalf("probe: Do = %v", err)
+	}
+	if s := b.Stats().State; s != breaker.Closed {
+		t.Fatalf("State = %v, want closed", s)
+	}
+}
+
+func TestStaleCompletionIgnored(t *testing.T) {
+	tests := []struct {
+		name  string
+		stale error
+		probe error
+		want  breaker.State
+	}{
+		// 打开前放行的请求在半开期间失败，不能让熔断器重新打开，也不能占掉探测请求的结果
+		{name: "stale failure during probe", stale: errBackend, probe: nil, want: breaker.Closed},
+		// 打开前放行的请求在半开期间成功，不能让熔断器关闭
+		{name: "stale success during probe", stale: nil, probe: errBackend, want: breaker.Open},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFakeNow()
+			b := newBreaker(clk, 0)
+			finishStale, staleResult := start(b)
+			trip(t, b)
+			clk.Add(5 * time.Second)
+			finishProbe, probeResult := start(b)
+
+			finishStale <- tt.stale
+			<-staleResult
+			if s := b.Stats().State; s != breaker.HalfOpen {
+				t.Fatalf("after stale call: State = %v, want half-open", s)
+			}
+			finishProbe <- tt.probe
+			<-probeResult
+			if s := b.Stats().State; s != tt.want {
+				t.Fatalf("after probe: State = %v, want %v", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	b := newBreaker(clock.NewFakeNow(), 10*time.Millisecond)
+	released := make(chan struct{})
+	defer close(released)
+
+	err := b.Do(context.Background(), func(ctx context.Context) error {
+		// 不理会 ctx 的调用也不会让 Do 一直等下去
+		<-released
+		return nil
+	})
+	var timeout *breaker.TimeoutError
+	if !errors.As(err, &timeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Do = %v, want *TimeoutError wrapping context.DeadlineExceeded", err)
+	}
+	if s := b.Stats(); s.Requests != 1 || s.Failures != 1 {
+		t.Fatalf("Stats = %+v, want the timeout counted as a failure", s)
+	}
+}
+
+func TestTimeoutPassesDeadline(t *testing.T) {
+	b := newBreaker(clock.NewFakeNow(), time.Minute)
+	err := b.Do(context.Background(), func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("no deadline")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do = %v", err)
+	}
+}
+
+func TestCanceledProbeReleased(t *testing.T) {
+	clk := clock.NewFakeNow()
+	b := newBreaker(clk, time.Minute)
+	trip(t, b)
+	clk.Add(5 * time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	released := make(chan struct{})
+	defer close(released)
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- b.Do(ctx, func(ctx context.Context) error {
+			close(started)
+			<-released
+			return nil
+		})
+	}()
+	<-started
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Fatalf("Do = %v, want context.Canceled", err)
+	}
+
+	// 调用方放弃的探测请求不算失败，下一个请求可以继续探测
+	if s := b.Stats().State; s != breaker.HalfOpen {
+		t.Fatalf("State = %v, want half-open", s)
+	}
+	if err := b.Do(context.Background(), succeed); err != nil {
+		t.Fatalf("next probe: Do = %v", err)
+	}
+	if s := b.Stats().State; s != breaker.Closed {
+		t.Fatalf("State = %v, want closed", s)
+	}
+}
+
+func TestPanicCountsAsFailure(t *testing.T) {
+	for _, timeout := range []time.Duration{0, time.Minute} {
+		b := breaker.New("test", breaker.Settings{
+			Window: 10 * time.Second, ErrorPercent: 50, MinRequests: 1, SleepWindow: 5 * time.Second, Timeout: timeout,
+		}, clock.NewFakeNow())
+		func() {
+			defer func() {
+				if v := recover(); v != "boom" {
+					t.Fatalf("timeout %v: recovered %v, want boom", timeout, v)
+				}
+			}()
+			_ = b.Do(context.Background(), func(ctx context.Context) error { panic("boom") })
+		}()
+		if s := b.Stats().State; s != breaker.Open {
+			t.Fatalf("timeout %v: State = %v, want open", timeout, s)
+		}
+	}
+}
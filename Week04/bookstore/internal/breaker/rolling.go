@@ -0,0 +1,61 @@
+package breaker
+
+import "time"
+
+// rollingNumber 是 Week06 RollingNumber 的简化版：以秒级时间戳为键的计数桶，
+// 只统计最近 window 秒内的桶。它不加锁，由 Breaker 在自己的锁里调用
+type rollingNumber struct {
+	window  int64
+	buckets map[int64]*bucket
+}
+
+type bucket struct {
+	requests int64
+	failures int64
+}
+
+func newRollingNumber(window time.Duration) *rollingNumber {
+	seconds := int64(window / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &rollingNumber{window: seconds, buckets: make(map[int64]*bucket)}
+}
+
+func (r *rollingNumber) record(now time.Time, failed bool) {
+	ts := now.Unix()
+	b, ok := r.buckets[ts]
+	if !ok {
+		b = &bucket{}
+		r.buckets[ts] = b
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	r.removeOldBuckets(now)
+}
+
+func (r *rollingNumber) removeOldBuckets(now time.Time) {
+	expired := now.Unix() - r.window
+	for ts := range r.buckets {
+		if ts <= expired {
+			delete(r.buckets, ts)
+		}
+	}
+}
+
+// sum 返回最近 window 秒内的请求数和失败数
+func (r *rollingNumber) sum(now time.Time) (requests, failures int64) {
+	for ts, b := range r.buckets {
+		if ts > now.Unix()-r.window {
+			requests += b.requests
+			failures += b.failures
+		}
+	}
+	return requests, failures
+}
+
+func (r *rollingNumber) reset() {
+	r.buckets = make(map[int64]*bucket)
+}
@@ -0,0 +1,61 @@
+// Package clock 抽象当前时间，测试和本地运行时可以换成可手动拨动的假时钟
+package clock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/wire"
+)
+
+// RealSet 提供真实时钟
+var RealSet = wire.NewSet(NewReal)
+
+// FakeSet 提供从当前时间开始的假时钟，同时绑定 Clock 接口和 *Fake，方便测试拨动时间
+var FakeSet = wire.NewSet(NewFakeNow, wire.Bind(new(Clock), new(*Fake)))
+
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func NewReal() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Fake 是只有调用 Set 或 Add 时才会走动的时钟
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func NewFakeNow() *Fake {
+	return NewFake(time.Now())
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	f.now = now
+	f.mu.Unlock()
+}
+
+func (f *Fake) Add(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
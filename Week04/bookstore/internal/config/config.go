@@ -0,0 +1,274 @@
+// Package config 从环境变量加载配置，没有设置的项使用默认值
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/wire"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+)
+
+var ProviderSet = wire.NewSet(Load)
+
+type Config struct {
+	HTTPAddr string
+	DSN      string
+	// TokenSecret 用于校验携带店铺信息的 HS256 token
+	TokenSecret string
+	// AdminToken 是调用管理接口的 token，为空时管理接口不可用
+	AdminToken string
+	// IdempotencyWindow 是 Idempotency-Key 的保留时长
+	IdempotencyWindow time.Duration
+	// StopTimeout 是每个组件停止时的超时时间
+	StopTimeout time.Duration
+	// BlobDir 是本地对象存储的根目录，图书封面保存在这里
+	BlobDir string
+	// MaxCoverSize 是上传封面的字节数上限
+	MaxCoverSize int64
+	// CacheControl 是各个路由成功响应的 Cache-Control，key 是路由名，
+	// 可以用 BOOKSTORE_CACHE_CONTROL_<路由名> 覆盖，例如 BOOKSTORE_CACHE_CONTROL_BOOKS_GET
+	CacheControl map[string]string
+	// GraphQLMaxDepth 和 GraphQLMaxComplexity 限制 /graphql 查询的深度和复杂度
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+	// ReplicaDSNs 是只读副本的 DSN，为空时所有读写都走主库
+	ReplicaDSNs []string
+	// ReplicaCheckInterval 是检查只读副本健康状况的间隔
+	ReplicaCheckInterval time.Duration
+	// ReadYourWrites 为 true 时，请求写过主库后的读也走主库
+	ReadYourWrites bool
+	// BreakerWindow 内的请求数不少于 BreakerMinRequests 且错误率达到 BreakerErrorPercent 时，
+	// 数据库熔断器打开，BreakerSleepWindow 之后放行一个探测请求。
+	// 超过 BreakerTimeout 的数据库调用直接按失败返回
+	BreakerWindow       time.Duration
+	BreakerErrorPercent int
+	BreakerMinRequests  int64
+	BreakerSleepWindow  time.Duration
+	BreakerTimeout      time.Duration
+	// Jobs 是各个定时任务的 cron 表达式，key 是任务名，
+	// 可以用 BOOKSTORE_JOB_<任务名> 覆盖，设置为空字符串时不运行该任务
+	Jobs map[string]string
+	// PurgeDeletedAfter 是软删除的图书保留多久后物理删除
+	PurgeDeletedAfter time.Duration
+	// EnrichBooks 为 true 时，新建的图书会在后台按 ISBN 补全书名、作者、出版社和封面
+	EnrichBooks bool
+	// MetadataURL 和 MetadataCoversURL 是 Open Library 风格的图书信息和封面接口地址
+	MetadataURL       string
+	MetadataCoversURL string
+	// MetadataCacheTTL 是图书信息查询结果的缓存时间
+	MetadataCacheTTL time.Duration
+	// WebhookAllowPrivate 为 true 时允许向回环、内网和链路本地地址推送 webhook，只用于本地调试
+	WebhookAllowPrivate bool
+	// StreamReplaySize 是变更流为断线重连保留的最近事件数
+	StreamReplaySize int
+	// StreamHeartbeat 是变更流发送心跳注释的间隔，避免空闲连接被代理断开
+	StreamHeartbeat time.Duration
+	// PaymentURL、PaymentAPIKey 是支付网关的接口地址和 API key，PaymentWebhookSecret 用于校验网关的回调
+	PaymentURL           string
+	PaymentAPIKey        string
+	PaymentWebhookSecret string
+	// PaymentCallbackURL 只给本地网关使用，线上的回调地址在网关后台配置
+	PaymentCallbackURL string
+	PaymentCurrency    string
+	PaymentTimeout     time.Duration
+	// RecommendationMinSupport 是两本书至少被多少个顾客一起买过才会互相推荐
+	RecommendationMinSupport int
+	// DefaultLocale 是图书本身的书名和简介使用的语言，也是 Accept-Language 回退链的最后一环
+	DefaultLocale string
+	// MaxBatchOperations 是 POST /books/batch 一次最多包含的操作数
+	MaxBatchOperations int
+}
+
+// Load 读取 BOOKSTORE_ 前缀的环境变量
+func Load() (Config, error) {
+	cfg := Config{
+		HTTPAddr:             getenv("BOOKSTORE_HTTP_ADDR", ":8080"),
+		DSN:                  getenv("BOOKSTORE_DSN", "root:111111@tcp(192.168.220.102:3306)/hello?charset=utf8mb4&parseTime=True&loc=Local"),
+		TokenSecret:          os.Getenv("BOOKSTORE_TOKEN_SECRET"),
+		AdminToken:           os.Getenv("BOOKSTORE_ADMIN_TOKEN"),
+		IdempotencyWindow:    24 * time.Hour,
+		StopTimeout:          5 * time.Second,
+		BlobDir:              getenv("BOOKSTORE_BLOB_DIR", "data/blobs"),
+		MaxCoverSize:         5 << 20,
+		ReplicaCheckInterval: 5 * time.Second,
+		ReadYourWrites:       true,
+		BreakerWindow:        10 * time.Second,
+		BreakerErrorPercent:  50,
+		BreakerMinRequests:   20,
+		BreakerSleepWindow:   5 * time.Second,
+		BreakerTimeout:       5 * time.Second,
+		GraphQLMaxDepth:      10,
+		GraphQLMaxComplexity: 5000,
+		PurgeDeletedAfter:    30 * 24 * time.Hour,
+		MetadataURL:          getenv("BOOKSTORE_METADATA_URL", "https://openlibrary.org"),
+		MetadataCoversURL:    getenv("BOOKSTORE_METADATA_COVERS_URL", "https://covers.openlibrary.org"),
+		MetadataCacheTTL:     24 * time.Hour,
+		StreamReplaySize:     1000,
+		StreamHeartbeat:      15 * time.Second,
+		PaymentURL:           getenv("BOOKSTORE_PAYMENT_URL", "http://127.0.0.1:8090"),
+		PaymentAPIKey:        os.Getenv("BOOKSTORE_PAYMENT_API_KEY"),
+		PaymentWebhookSecret: os.Getenv("BOOKSTORE_PAYMENT_WEBHOOK_SECRET"),
+		PaymentCurrency:      getenv("BOOKSTORE_PAYMENT_CURRENCY", "CNY"),
+		PaymentTimeout:       10 * time.Second,
+
+		RecommendationMinSupport: 2,
+		DefaultLocale:            "en",
+		MaxBatchOperations:       100,
+		CacheControl: map[string]string{
+			"books.list":            "private, no-cache",
+			"books.get":             "private, no-cache",
+			"books.cover":           "private, max-age=86400",
+			"books.recommendations": "private, max-age=300",
+		},
+		Jobs: map[string]string{
+			"books.purge":             "0 3 * * *",
+			"idempotency.expire":      "*/10 * * * *",
+			"ratings.recompute":       "30 3 * * *",
+			"recommendations.compute": "15 * * * *",
+		},
+	}
+	cfg.PaymentCallbackURL = getenv("BOOKSTORE_PAYMENT_CALLBACK_URL", localURL(cfg.HTTPAddr)+"/api/v1/payments/callback")
+	for route := range cfg.CacheControl {
+		key := "BOOKSTORE_CACHE_CONTROL_" + strings.ToUpper(strings.Replace(route, ".", "_", -1))
+		if v, ok := os.LookupEnv(key); ok {
+			cfg.CacheControl[route] = v
+		}
+	}
+	for job := range cfg.Jobs {
+		key := "BOOKSTORE_JOB_" + strings.ToUpper(strings.Replace(job, ".", "_", -1))
+		if v, ok := os.LookupEnv(key); ok {
+			cfg.Jobs[job] = v
+		}
+	}
+
+	var err error
+	if v := os.Getenv("BOOKSTORE_IDEMPOTENCY_WINDOW"); v != "" {
+		if cfg.IdempotencyWindow, err = time.ParseDuration(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_STOP_TIMEOUT"); v != "" {
+		if cfg.StopTimeout, err = time.ParseDuration(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_MAX_COVER_SIZE"); v != "" {
+		if cfg.MaxCoverSize, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_REPLICA_DSNS"); v != "" {
+		cfg.ReplicaDSNs = strings.Split(v, ",")
+	}
+	if v := os.Getenv("BOOKSTORE_REPLICA_CHECK_INTERVAL"); v != "" {
+		if cfg.ReplicaCheckInterval, err = time.ParseDuration(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_READ_YOUR_WRITES"); v != "" {
+		if cfg.ReadYourWrites, err = strconv.ParseBool(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_PURGE_DELETED_AFTER"); v != "" {
+		if cfg.PurgeDeletedAfter, err = time.ParseDuration(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_ENRICH_BOOKS"); v != "" {
+		if cfg.EnrichBooks, err = strconv.ParseBool(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_WEBHOOK_ALLOW_PRIVATE"); v != "" {
+		if cfg.WebhookAllowPrivate, err = strconv.ParseBool(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_METADATA_CACHE_TTL"); v != "" {
+		if cfg.MetadataCacheTTL, err = time.ParseDuration(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_STREAM_REPLAY_SIZE"); v != "" {
+		if cfg.StreamReplaySize, err = strconv.Atoi(v); err != nil {
+			return cfg, err
+		}
+		if cfg.StreamReplaySize < 0 {
+			return cfg, fmt.Errorf("invalid BOOKSTORE_STREAM_REPLAY_SIZE %q", v)
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_PAYMENT_TIMEOUT"); v != "" {
+		if cfg.PaymentTimeout, err = time.ParseDuration(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_RECOMMENDATION_MIN_SUPPORT"); v != "" {
+		if cfg.RecommendationMinSupport, err = strconv.Atoi(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_BREAKER_ERROR_PERCENT"); v != "" {
+		if cfg.BreakerErrorPercent, err = strconv.Atoi(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_BREAKER_MIN_REQUESTS"); v != "" {
+		if cfg.BreakerMinRequests, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_BREAKER_SLEEP_WINDOW"); v != "" {
+		if cfg.BreakerSleepWindow, err = time.ParseDuration(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_BREAKER_TIMEOUT"); v != "" {
+		if cfg.BreakerTimeout, err = time.ParseDuration(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_GRAPHQL_MAX_DEPTH"); v != "" {
+		if cfg.GraphQLMaxDepth, err = strconv.Atoi(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_GRAPHQL_MAX_COMPLEXITY"); v != "" {
+		if cfg.GraphQLMaxComplexity, err = strconv.Atoi(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_MAX_BATCH_OPERATIONS"); v != "" {
+		if cfg.MaxBatchOperations, err = strconv.Atoi(v); err != nil {
+			return cfg, err
+		}
+	}
+	if v := os.Getenv("BOOKSTORE_DEFAULT_LOCALE"); v != "" {
+		locale, ok := i18n.Canonical(v)
+		if !ok {
+			return cfg, fmt.Errorf("invalid BOOKSTORE_DEFAULT_LOCALE %q", v)
+		}
+		cfg.DefaultLocale = locale
+	}
+	return cfg, nil
+}
+
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// localURL 返回从本机访问 addr 的地址，addr 只有端口时使用 127.0.0.1
+func localURL(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	return "http://" + addr
+}
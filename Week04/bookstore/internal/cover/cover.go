@@ -0,0 +1,166 @@
+// Package cover 校验上传的封面图片并生成缩略图，只依赖标准库的 image 包
+package cover
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif" // 注册 gif 解码器
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+)
+
+var (
+	// ErrUnsupportedType 表示图片格式不在支持范围内
+	ErrUnsupportedType = i18n.NewError("cover.unsupported_type")
+	// ErrInvalidImage 表示图片无法解码或者尺寸超出限制
+	ErrInvalidImage = i18n.NewError("cover.invalid_image")
+)
+
+// MaxDimension 是原图宽高的上限，避免解码超大图片耗尽内存
+const MaxDimension = 8000
+
+// Original 是原图的尺寸名
+const Original = "original"
+
+// Size 是一种缩略图规格，高度按原图比例计算
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Sizes 是上传时生成的缩略图规格
+var Sizes = []Size{
+	{Name: "small", Width: 120},
+	{Name: "medium", Width: 300},
+	{Name: "large", Width: 600},
+}
+
+// formats 把检测到的 content type 映射为保存时使用的格式，gif 只取第一帧重新编码为 png 保存
+var formats = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "png",
+}
+
+// ContentType 返回格式对应的 content type
+func ContentType(format string) string {
+	if format == "jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// Ext 返回格式对应的文件扩展名
+func Ext(format string) string {
+	if format == "jpeg" {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// Decode 按文件内容而不是客户端声明的类型识别格式，返回图片和保存时使用的格式
+func Decode(data []byte) (image.Image, string, error) {
+	format, ok := formats[http.DetectContentType(data)]
+	if !ok {
+		return nil, "", ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+		return nil, "", ErrInvalidImage
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+	return img, format, nil
+}
+
+// Encode 按格式编码图片
+func Encode(w io.Writer, img image.Image, format string) error {
+	if format == "jpeg" {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+	return png.Encode(w, img)
+}
+
+// EncodeOriginal 返回要保存的原图：data 已经是 format 格式时原样返回，
+// 否则（gif）把解码出的 img 按 format 重新编码，保存的内容和 ContentType(format) 一致
+func EncodeOriginal(data []byte, img image.Image, format string) ([]byte, error) {
+	if http.DetectContentType(data) == ContentType(format) {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, format); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Thumbnail 把图片等比缩小到指定宽度，原图更窄时不放大
+func Thumbnail(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= width {
+		width = b.Dx()
+	}
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	return resize(toRGBA(img), width, height)
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			rgba.Set(x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return rgba
+}
+
+// resize 用盒式滤波缩小图片，每个目标像素取它覆盖的源像素的平均值
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, (y+1)*sh/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, (x+1)*sw/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					sum[0] += int(p[0])
+					sum[1] += int(p[1])
+					sum[2] += int(p[2])
+					sum[3] += int(p[3])
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			d := dst.Pix[y*dst.Stride+x*4:]
+			for i := 0; i < 4; i++ {
+				d[i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,31 @@
+package dto
+
+const (
+	// BatchAtomic 模式下任何一个操作失败都回滚所有操作
+	BatchAtomic = "atomic"
+	// BatchBestEffort 模式下只撤销失败的操作，其余操作照常提交
+	BatchBestEffort = "best_effort"
+)
+
+// BatchRequest 的 Mode 为空时是 atomic，操作按数组顺序执行
+type BatchRequest struct {
+	Mode       string              `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Operations []BatchOperationDTO `json:"operations"`
+}
+
+// BatchOperationDTO 的 Op 是 create、update 或 delete，update 和 delete 需要 ID，create 和 update 需要 Book
+type BatchOperationDTO struct {
+	Op   string   `json:"op"`
+	ID   uint     `json:"id,string,omitempty"`
+	Book *BookDTO `json:"book,omitempty"`
+}
+
+// BatchResultDTO 的 Status 是这个操作单独执行时对应的 HTTP 状态码，
+// 因为其他操作失败而回滚或者没有执行的操作是 424
+type BatchResultDTO struct {
+	Index  int      `json:"index"`
+	Op     string   `json:"op"`
+	Status int      `json:"status"`
+	Book   *BookDTO `json:"book,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
@@ -1,27 +1,54 @@
 package dto
 
 import (
+	"math"
+	"time"
+
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
 )
 
 type BookDTO struct {
-	ID    uint    `json:"id,string,omitempty"`
-	ISBN  string  `json:"isbn"`
-	Price float32 `json:"price,string"`
+	ID          uint    `json:"id,string,omitempty" xml:"id,attr,omitempty"`
+	ISBN        string  `json:"isbn" xml:"isbn"`
+	Title       string  `json:"title,omitempty" xml:"title,omitempty"`
+	Description string  `json:"description,omitempty" xml:"description,omitempty"`
+	Author      string  `json:"author,omitempty" xml:"author,omitempty"`
+	Publisher   string  `json:"publisher,omitempty" xml:"publisher,omitempty"`
+	Category    string  `json:"category,omitempty" xml:"category,omitempty"`
+	Price       float32 `json:"price,string" xml:"price"`
+	// CoverUpdatedAt 只在有封面时返回，只读
+	CoverUpdatedAt *time.Time `json:"cover_updated_at,omitempty" xml:"cover_updated_at,omitempty"`
+	// AverageRating 和 RatingCount 由审核通过的评论计算，只读
+	AverageRating float64 `json:"average_rating" xml:"average_rating"`
+	RatingCount   int     `json:"rating_count" xml:"rating_count"`
 }
 
 func ToBook(bookDTO BookDTO) model.Book {
 	return model.Book{
-		ISBN:  bookDTO.ISBN,
-		Price: bookDTO.Price,
+		ISBN:        bookDTO.ISBN,
+		Title:       bookDTO.Title,
+		Description: bookDTO.Description,
+		Author:      bookDTO.Author,
+		Publisher:   bookDTO.Publisher,
+		Category:    bookDTO.Category,
+		Price:       bookDTO.Price,
 	}
 }
 
 func ToBookDTO(book model.Book) BookDTO {
 	return BookDTO{
-		ID:    book.ID,
-		ISBN:  book.ISBN,
-		Price: book.Price,
+		ID:          book.ID,
+		ISBN:        book.ISBN,
+		Title:       book.Title,
+		Description: book.Description,
+		Author:      book.Author,
+		Publisher:   book.Publisher,
+		Category:    book.Category,
+		Price:       book.Price,
+
+		CoverUpdatedAt: book.CoverUpdatedAt,
+		AverageRating:  math.Round(book.AverageRating()*100) / 100,
+		RatingCount:    book.RatingCount,
 	}
 }
 
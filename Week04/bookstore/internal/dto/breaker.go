@@ -0,0 +1,28 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/breaker"
+)
+
+type BreakerDTO struct {
+	Name     string     `json:"name"`
+	State    string     `json:"state"`
+	Requests int64      `json:"requests"`
+	Failures int64      `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+}
+
+func ToBreakerDTO(s breaker.Stats) BreakerDTO {
+	breakerDTO := BreakerDTO{
+		Name:     s.Name,
+		State:    s.State.String(),
+		Requests: s.Requests,
+		Failures: s.Failures,
+	}
+	if !s.OpenedAt.IsZero() {
+		breakerDTO.OpenedAt = &s.OpenedAt
+	}
+	return breakerDTO
+}
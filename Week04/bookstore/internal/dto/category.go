@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// CategoryDTO 的 BookCount 是整棵子树中的图书数，只在浏览分类时返回
+type CategoryDTO struct {
+	ID        uint   `json:"id,string"`
+	ParentID  uint   `json:"parent_id,string,omitempty"`
+	Name      string `json:"name"`
+	BookCount *int   `json:"book_count,omitempty"`
+}
+
+// CategoryRequest 的 ParentID 为空或 0 时是根分类，修改时指定新的父分类会移动整棵子树
+type CategoryRequest struct {
+	Name     string `json:"name" binding:"required,max=255"`
+	ParentID uint   `json:"parent_id,string"`
+}
+
+// BookCategoriesRequest 替换图书的分类，空数组表示清空
+type BookCategoriesRequest struct {
+	CategoryIDs []string `json:"category_ids" binding:"max=50"`
+}
+
+func ToCategoryDTO(c model.Category) CategoryDTO {
+	return CategoryDTO{ID: c.ID, ParentID: c.ParentID, Name: c.Name}
+}
+
+func ToCategoryDTOs(cs []model.Category) []CategoryDTO {
+	categorydtos := make([]CategoryDTO, len(cs))
+	for i, v := range cs {
+		categorydtos[i] = ToCategoryDTO(v)
+	}
+	return categorydtos
+}
+
+func ToCategoryCountDTO(c model.CategoryCount) CategoryDTO {
+	categoryDTO := ToCategoryDTO(c.Category)
+	count := c.BookCount
+	categoryDTO.BookCount = &count
+	return categoryDTO
+}
+
+func ToCategoryCountDTOs(cs []model.CategoryCount) []CategoryDTO {
+	categorydtos := make([]CategoryDTO, len(cs))
+	for i, v := range cs {
+		categorydtos[i] = ToCategoryCountDTO(v)
+	}
+	return categorydtos
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type RevisionDTO struct {
+	Version   int                 `json:"version"`
+	Action    string              `json:"action"`
+	Actor     string              `json:"actor"`
+	Changes   []model.FieldChange `json:"changes"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+type RevertRequest struct {
+	Version int `json:"version" binding:"required,min=1"`
+}
+
+func ToRevisionDTO(rev model.BookRevision) RevisionDTO {
+	changes := []model.FieldChange{}
+	if err := json.Unmarshal([]byte(rev.Changes), &changes); err != nil {
+		changes = []model.FieldChange{}
+	}
+	return RevisionDTO{
+		Version:   rev.Version,
+		Action:    rev.Action,
+		Actor:     rev.Actor,
+		Changes:   changes,
+		CreatedAt: rev.CreatedAt,
+	}
+}
+
+func ToRevisionDTOs(revs []model.BookRevision) []RevisionDTO {
+	revdtos := make([]RevisionDTO, len(revs))
+	for i, v := range revs {
+		revdtos[i] = ToRevisionDTO(v)
+	}
+	return revdtos
+}
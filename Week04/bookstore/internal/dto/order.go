@@ -0,0 +1,117 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// CartDTO 的价格按当前的优惠规则计算，结算时以结算时的价格为准
+type CartDTO struct {
+	Items []CartItemDTO `json:"items"`
+	Total float32       `json:"total,string"`
+}
+
+// CartItemDTO 的 Available 为 false 表示图书已经被删除，需要移除后才能结算
+type CartItemDTO struct {
+	BookID      uint            `json:"book_id,string"`
+	ISBN        string          `json:"isbn,omitempty"`
+	Title       string          `json:"title,omitempty"`
+	BasePrice   float32         `json:"base_price,string"`
+	UnitPrice   float32         `json:"unit_price,string"`
+	Adjustments []AdjustmentDTO `json:"adjustments,omitempty"`
+	Quantity    int             `json:"quantity"`
+	Subtotal    float32         `json:"subtotal,string"`
+	Available   bool            `json:"available"`
+}
+
+type CartItemRequest struct {
+	BookID   uint `json:"book_id,string" binding:"required"`
+	Quantity int  `json:"quantity" binding:"required,min=1,max=99"`
+}
+
+// QuantityRequest 的数量为 0 时从购物车移除
+type QuantityRequest struct {
+	Quantity *int `json:"quantity" binding:"required,min=0,max=99"`
+}
+
+type OrderDTO struct {
+	ID        uint           `json:"id,string"`
+	Customer  string         `json:"customer"`
+	Status    string         `json:"status"`
+	Items     []OrderItemDTO `json:"items"`
+	Total     float32        `json:"total,string"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type OrderItemDTO struct {
+	BookID      uint            `json:"book_id,string"`
+	ISBN        string          `json:"isbn"`
+	Title       string          `json:"title"`
+	BasePrice   float32         `json:"base_price,string"`
+	UnitPrice   float32         `json:"unit_price,string"`
+	Adjustments []AdjustmentDTO `json:"adjustments"`
+	Quantity    int             `json:"quantity"`
+}
+
+type OrderStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=paid shipped cancelled"`
+}
+
+func ToCartDTO(cart model.Cart) CartDTO {
+	cartDTO := CartDTO{Items: make([]CartItemDTO, len(cart.Lines)), Total: cart.Total}
+	for i, line := range cart.Lines {
+		cartDTO.Items[i] = CartItemDTO{
+			BookID:    line.BookID,
+			ISBN:      line.Book.ISBN,
+			Title:     line.Book.Title,
+			BasePrice: line.Book.Price,
+			UnitPrice: line.UnitPrice,
+			Quantity:  line.Quantity,
+			Subtotal:  line.Subtotal,
+			Available: line.Available,
+		}
+		if line.Available {
+			cartDTO.Items[i].Adjustments = ToAdjustmentDTOs(line.Adjustments)
+		}
+	}
+	return cartDTO
+}
+
+func ToOrderDTO(order model.Order) OrderDTO {
+	orderDTO := OrderDTO{
+		ID:        order.ID,
+		Customer:  order.Customer,
+		Status:    order.Status,
+		Items:     make([]OrderItemDTO, len(order.Items)),
+		Total:     order.Total,
+		CreatedAt: order.CreatedAt,
+		UpdatedAt: order.UpdatedAt,
+	}
+	for i, item := range order.Items {
+		var adjustments []model.PriceAdjustment
+		if err := json.Unmarshal([]byte(item.Adjustments), &adjustments); err != nil {
+			adjustments = nil
+		}
+		orderDTO.Items[i] = OrderItemDTO{
+			BookID:      item.BookID,
+			ISBN:        item.ISBN,
+			Title:       item.Title,
+			BasePrice:   item.BasePrice,
+			UnitPrice:   item.UnitPrice,
+			Adjustments: ToAdjustmentDTOs(adjustments),
+			Quantity:    item.Quantity,
+		}
+	}
+	return orderDTO
+}
+
+func ToOrderDTOs(orders []model.Order) []OrderDTO {
+	orderdtos := make([]OrderDTO, len(orders))
+	for i, v := range orders {
+		orderdtos[i] = ToOrderDTO(v)
+	}
+	return orderdtos
+}
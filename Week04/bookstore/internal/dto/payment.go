@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type PaymentDTO struct {
+	ID          uint      `json:"id,string"`
+	OrderID     uint      `json:"order_id,string"`
+	ProviderRef string    `json:"provider_ref"`
+	Status      string    `json:"status"`
+	Amount      float32   `json:"amount,string"`
+	Currency    string    `json:"currency"`
+	DeclineCode string    `json:"decline_code,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PayRequest 的 Source 是客户端从支付网关拿到的支付凭证
+type PayRequest struct {
+	Source string `json:"source" binding:"required"`
+}
+
+func ToPaymentDTO(p model.Payment) PaymentDTO {
+	return PaymentDTO{
+		ID:          p.ID,
+		OrderID:     p.OrderID,
+		ProviderRef: p.ProviderRef,
+		Status:      p.Status,
+		Amount:      p.Amount,
+		Currency:    p.Currency,
+		DeclineCode: p.DeclineCode,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
@@ -0,0 +1,100 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/pricing"
+)
+
+type PricingRuleDTO struct {
+	ID         uint       `json:"id,string,omitempty"`
+	Name       string     `json:"name"`
+	Kind       string     `json:"kind"`
+	Amount     float32    `json:"amount"`
+	Scope      string     `json:"scope"`
+	Target     string     `json:"target,omitempty"`
+	CouponCode string     `json:"coupon_code,omitempty"`
+	Priority   int        `json:"priority"`
+	Exclusive  bool       `json:"exclusive"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+}
+
+func ToPricingRule(ruleDTO PricingRuleDTO) model.PricingRule {
+	return model.PricingRule{
+		Name:       ruleDTO.Name,
+		Kind:       ruleDTO.Kind,
+		Amount:     ruleDTO.Amount,
+		Scope:      ruleDTO.Scope,
+		Target:     ruleDTO.Target,
+		CouponCode: ruleDTO.CouponCode,
+		Priority:   ruleDTO.Priority,
+		Exclusive:  ruleDTO.Exclusive,
+		StartsAt:   ruleDTO.StartsAt,
+		EndsAt:     ruleDTO.EndsAt,
+	}
+}
+
+func ToPricingRuleDTO(rule model.PricingRule) PricingRuleDTO {
+	return PricingRuleDTO{
+		ID:         rule.ID,
+		Name:       rule.Name,
+		Kind:       rule.Kind,
+		Amount:     rule.Amount,
+		Scope:      rule.Scope,
+		Target:     rule.Target,
+		CouponCode: rule.CouponCode,
+		Priority:   rule.Priority,
+		Exclusive:  rule.Exclusive,
+		StartsAt:   rule.StartsAt,
+		EndsAt:     rule.EndsAt,
+	}
+}
+
+func ToPricingRuleDTOs(rules []model.PricingRule) []PricingRuleDTO {
+	ruledtos := make([]PricingRuleDTO, len(rules))
+	for i, v := range rules {
+		ruledtos[i] = ToPricingRuleDTO(v)
+	}
+	return ruledtos
+}
+
+type AdjustmentDTO struct {
+	RuleID   uint    `json:"rule_id,string"`
+	Name     string  `json:"name"`
+	Kind     string  `json:"kind"`
+	Amount   float32 `json:"amount"`
+	Discount float32 `json:"discount,string"`
+}
+
+// PriceDTO 的金额和 BookDTO.Price 一样以字符串表示
+type PriceDTO struct {
+	BookID         uint            `json:"book_id,string"`
+	BasePrice      float32         `json:"base_price,string"`
+	EffectivePrice float32         `json:"effective_price,string"`
+	Adjustments    []AdjustmentDTO `json:"adjustments"`
+}
+
+func ToPriceDTO(quote pricing.Quote) PriceDTO {
+	return PriceDTO{
+		BookID:         quote.BookID,
+		BasePrice:      quote.BasePrice,
+		EffectivePrice: quote.EffectivePrice,
+		Adjustments:    ToAdjustmentDTOs(quote.Adjustments),
+	}
+}
+
+func ToAdjustmentDTOs(adjustments []model.PriceAdjustment) []AdjustmentDTO {
+	adjustmentdtos := make([]AdjustmentDTO, len(adjustments))
+	for i, a := range adjustments {
+		adjustmentdtos[i] = AdjustmentDTO{
+			RuleID:   a.RuleID,
+			Name:     a.Name,
+			Kind:     a.Kind,
+			Amount:   a.Amount,
+			Discount: a.Discount,
+		}
+	}
+	return adjustmentdtos
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+
+// RecommendationDTO 的 Reason 是 also_bought 或 best_seller，Score 只对 also_bought 有意义
+type RecommendationDTO struct {
+	Book   BookDTO `json:"book"`
+	Reason string  `json:"reason"`
+	Score  float64 `json:"score,omitempty"`
+}
+
+func ToRecommendationDTOs(recs []model.Recommendation) []RecommendationDTO {
+	recdtos := make([]RecommendationDTO, len(recs))
+	for i, v := range recs {
+		recdtos[i] = RecommendationDTO{Book: ToBookDTO(v.Book), Reason: v.Reason, Score: v.Score}
+	}
+	return recdtos
+}
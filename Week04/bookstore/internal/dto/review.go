@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// ReviewDTO 的 Author 和 Status 是只读的，作者取自 X-Actor，状态由审核接口修改
+type ReviewDTO struct {
+	ID        uint      `json:"id,string"`
+	Rating    int       `json:"rating"`
+	Text      string    `json:"text"`
+	Author    string    `json:"author"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Text   string `json:"text"`
+}
+
+type ModerationRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending approved hidden"`
+}
+
+func ToReviewDTO(review model.Review) ReviewDTO {
+	return ReviewDTO{
+		ID:        review.ID,
+		Rating:    review.Rating,
+		Text:      review.Text,
+		Author:    review.Author,
+		Status:    review.Status,
+		CreatedAt: review.CreatedAt,
+		UpdatedAt: review.UpdatedAt,
+	}
+}
+
+func ToReviewDTOs(reviews []model.Review) []ReviewDTO {
+	reviewdtos := make([]ReviewDTO, len(reviews))
+	for i, v := range reviews {
+		reviewdtos[i] = ToReviewDTO(v)
+	}
+	return reviewdtos
+}
@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type TenantDTO struct {
+	ID                uint   `json:"id,string,omitempty"`
+	Slug              string `json:"slug"`
+	Name              string `json:"name"`
+	MaxBooks          int    `json:"max_books"`
+	RequestsPerSecond int    `json:"requests_per_second"`
+}
+
+func ToTenant(tenantDTO TenantDTO) model.Tenant {
+	return model.Tenant{
+		Slug:              tenantDTO.Slug,
+		Name:              tenantDTO.Name,
+		MaxBooks:          tenantDTO.MaxBooks,
+		RequestsPerSecond: tenantDTO.RequestsPerSecond,
+	}
+}
+
+func ToTenantDTO(t model.Tenant) TenantDTO {
+	return TenantDTO{
+		ID:                t.ID,
+		Slug:              t.Slug,
+		Name:              t.Name,
+		MaxBooks:          t.MaxBooks,
+		RequestsPerSecond: t.RequestsPerSecond,
+	}
+}
+
+func ToTenantDTOs(tenants []model.Tenant) []TenantDTO {
+	tenantdtos := make([]TenantDTO, len(tenants))
+	for i, v := range tenants {
+		tenantdtos[i] = ToTenantDTO(v)
+	}
+	return tenantdtos
+}
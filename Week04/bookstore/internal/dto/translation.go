@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type TranslationDTO struct {
+	Locale      string    `json:"locale"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TranslationRequest 的语言由路径指定，为空的字段在响应中沿用回退语言的内容
+type TranslationRequest struct {
+	Title       string `json:"title" binding:"max=255"`
+	Description string `json:"description" binding:"max=10000"`
+}
+
+func ToTranslationDTO(t model.BookTranslation) TranslationDTO {
+	return TranslationDTO{Locale: t.Locale, Title: t.Title, Description: t.Description, UpdatedAt: t.UpdatedAt}
+}
+
+func ToTranslationDTOs(ts []model.BookTranslation) []TranslationDTO {
+	translationdtos := make([]TranslationDTO, len(ts))
+	for i, v := range ts {
+		translationdtos[i] = ToTranslationDTO(v)
+	}
+	return translationdtos
+}
@@ -0,0 +1,80 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type SubscriptionDTO struct {
+	ID         uint     `json:"id,string,omitempty"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret,omitempty"`
+	EventTypes []string `json:"event_types"`
+}
+
+func ToSubscription(subDTO SubscriptionDTO) model.WebhookSubscription {
+	return model.WebhookSubscription{
+		URL:        subDTO.URL,
+		Secret:     subDTO.Secret,
+		EventTypes: strings.Join(subDTO.EventTypes, ","),
+	}
+}
+
+// ToSubscriptionDTO 不返回 secret，secret 只在创建订阅时返回一次
+func ToSubscriptionDTO(sub model.WebhookSubscription) SubscriptionDTO {
+	eventTypes := []string{}
+	if sub.EventTypes != "" {
+		eventTypes = strings.Split(sub.EventTypes, ",")
+	}
+	return SubscriptionDTO{
+		ID:         sub.ID,
+		URL:        sub.URL,
+		EventTypes: eventTypes,
+	}
+}
+
+func ToSubscriptionDTOs(subs []model.WebhookSubscription) []SubscriptionDTO {
+	subdtos := make([]SubscriptionDTO, len(subs))
+	for i, v := range subs {
+		subdtos[i] = ToSubscriptionDTO(v)
+	}
+	return subdtos
+}
+
+type DeliveryDTO struct {
+	ID             uint      `json:"id,string"`
+	EventID        uint      `json:"event_id,string"`
+	EventType      string    `json:"event_type"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func ToDeliveryDTO(d model.WebhookDelivery) DeliveryDTO {
+	return DeliveryDTO{
+		ID:             d.ID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+}
+
+func ToDeliveryDTOs(deliveries []model.WebhookDelivery) []DeliveryDTO {
+	deliverydtos := make([]DeliveryDTO, len(deliveries))
+	for i, v := range deliveries {
+		deliverydtos[i] = ToDeliveryDTO(v)
+	}
+	return deliverydtos
+}
@@ -0,0 +1,179 @@
+// Package feed 把已经提交的图书事件实时推送给订阅者，用于 SSE 变更流。
+// Tailer 从 outbox 表读取所有实例写入的事件交给 Hub，Hub 按店铺分发给当前进程的订阅者，
+// 并保留最近的事件供断线重连时补发。
+package feed
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/wire"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+var ProviderSet = wire.NewSet(NewHub)
+
+// subscriptionBuffer 是每个订阅者的缓冲区，写满说明订阅者太慢，会被断开，
+// 客户端可以带着 Last-Event-ID 重连并从回放缓冲区补齐
+const subscriptionBuffer = 64
+
+// ErrInvalidEventID 表示 Last-Event-ID 的格式不对
+var ErrInvalidEventID = errors.New("invalid event id")
+
+// Event 是推送给订阅者的事件，ID 在进程内单调递增，Epoch 区分不同的进程，
+// 两者一起组成客户端看到的事件 ID
+type Event struct {
+	Epoch     string
+	ID        uint64
+	OutboxID  uint
+	TenantID  uint
+	Type      string
+	Data      string
+	CreatedAt time.Time
+}
+
+// EventID 返回 SSE 的 id 字段，格式为 "<epoch>-<id>"
+func (e Event) EventID() string {
+	return e.Epoch + "-" + strconv.FormatUint(e.ID, 10)
+}
+
+// ParseEventID 解析 EventID 的结果。没有 epoch 的纯数字 ID 来自旧版本，epoch 为空，
+// 订阅时会被当作无法补齐
+func ParseEventID(s string) (epoch string, id uint64, err error) {
+	if i := strings.LastIndex(s, "-"); i >= 0 {
+		epoch, s = s[:i], s[i+1:]
+	}
+	if id, err = strconv.ParseUint(s, 10, 64); err != nil {
+		return "", 0, ErrInvalidEventID
+	}
+	return epoch, id, nil
+}
+
+type Hub struct {
+	mu     sync.Mutex
+	epoch  string
+	buffer []Event
+	size   int
+	nextID uint64
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewHub(cfg config.Config) *Hub {
+	return &Hub{
+		epoch:  strconv.FormatInt(time.Now().UnixNano(), 36),
+		size:   cfg.StreamReplaySize,
+		nextID: 1,
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish 把 outbox 事件推送给订阅者，同一个 outbox 事件只推送一次
+func (h *Hub) Publish(ctx context.Context, e model.OutboxEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, old := range h.buffer {
+		if old.OutboxID == e.ID {
+			return nil
+		}
+	}
+
+	event := Event{
+		Epoch:     h.epoch,
+		ID:        h.nextID,
+		OutboxID:  e.ID,
+		TenantID:  e.TenantID,
+		Type:      e.EventType,
+		Data:      e.Payload,
+		CreatedAt: e.CreatedAt,
+	}
+	h.nextID++
+	h.buffer = append(h.buffer, event)
+	if len(h.buffer) > h.size {
+		h.buffer = h.buffer[len(h.buffer)-h.size:]
+	}
+
+	for s := range h.subs {
+		if s.tenantID != e.TenantID {
+			continue
+		}
+		select {
+		case s.c <- event:
+		default:
+			h.remove(s)
+		}
+	}
+	return nil
+}
+
+// Subscribe 订阅店铺的事件。lastID 不为 0 时返回回放缓冲区中在它之后的事件；
+// lastID 已经不在缓冲区里，或者 epoch 来自另一个进程（重启之前或者另一个实例）时 resumed 为 false，
+// 调用方应该让客户端重新拉取全量数据
+func (h *Hub) Subscribe(tenantID uint, epoch string, lastID uint64) (s *Subscription, backlog []Event, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s = &Subscription{hub: h, tenantID: tenantID, c: make(chan Event, subscriptionBuffer)}
+	if h.closed {
+		close(s.c)
+		return s, nil, lastID == 0
+	}
+	h.subs[s] = struct{}{}
+
+	if lastID == 0 {
+		return s, nil, true
+	}
+	// 事件 ID 是连续的，缓冲区里是最近的 len(buffer) 个事件。lastID 之后的事件已经被挤出缓冲区，
+	// 或者 lastID 属于别的进程时，无法补齐
+	oldest := h.nextID - uint64(len(h.buffer))
+	if epoch != h.epoch || lastID >= h.nextID || lastID+1 < oldest {
+		return s, nil, false
+	}
+	for _, e := range h.buffer {
+		if e.ID > lastID && e.TenantID == tenantID {
+			backlog = append(backlog, e)
+		}
+	}
+	return s, backlog, true
+}
+
+// Close 断开所有订阅者，之后的订阅会立即结束。用于优雅退出，
+// 否则长连接会让 http.Server.Shutdown 一直等到超时
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subs {
+		h.remove(s)
+	}
+}
+
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.c)
+	}
+}
+
+// Subscription 是一个订阅，C 被关闭表示订阅已经结束
+type Subscription struct {
+	hub      *Hub
+	tenantID uint
+	c        chan Event
+}
+
+func (s *Subscription) C() <-chan Event {
+	return s.c
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
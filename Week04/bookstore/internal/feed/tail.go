@@ -0,0 +1,122 @@
+package feed
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+const (
+	defaultTailInterval  = time.Second
+	defaultTailBatchSize = 500
+	defaultGapTimeout    = time.Minute
+)
+
+// Tailer 按 ID 顺序读取 outbox 表里新提交的事件交给 Hub。每个实例都运行一个 Tailer，
+// 所以不管事件由哪个实例写入和投递，所有实例的订阅者都能收到。
+// 事件的 ID 在插入时分配，事务提交的顺序可能和 ID 顺序不同：先读到较大的 ID 时，
+// 中间缺少的 ID 在 GapTimeout 内每次都会再查询，超时的当作已经回滚
+type Tailer struct {
+	Interval   time.Duration
+	BatchSize  int
+	GapTimeout time.Duration
+
+	repo  repository.OutboxRepository
+	hub   *Hub
+	clock clock.Clock
+	// cursor 是读到的最大 ID，gaps 是比 cursor 小但还没有读到的 ID 和第一次发现它缺少的时间
+	cursor  uint
+	started bool
+	gaps    map[uint]time.Time
+}
+
+func NewTailer(repo repository.OutboxRepository, hub *Hub, clk clock.Clock) *Tailer {
+	return &Tailer{
+		Interval:   defaultTailInterval,
+		BatchSize:  defaultTailBatchSize,
+		GapTimeout: defaultGapTimeout,
+		repo:       repo,
+		hub:        hub,
+		clock:      clk,
+		gaps:       make(map[uint]time.Time),
+	}
+}
+
+// Run 周期性地读取新事件，直到 ctx 被取消
+func (t *Tailer) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.Poll(ctx); err != nil {
+			log.Printf("feed tail err: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll 读取一批新事件。第一次调用只记录当前最大的 ID，启动之前的事件不推送
+func (t *Tailer) Poll(ctx context.Context) error {
+	if !t.started {
+		id, err := t.repo.LastID()
+		if err != nil {
+			return err
+		}
+		t.cursor, t.started = id, true
+		return nil
+	}
+
+	var events []model.OutboxEvent
+	if len(t.gaps) > 0 {
+		ids := make([]uint, 0, len(t.gaps))
+		for id := range t.gaps {
+			ids = append(ids, id)
+		}
+		found, err := t.repo.ListByIDs(ids)
+		if err != nil {
+			return err
+		}
+		for _, e := range found {
+			delete(t.gaps, e.ID)
+		}
+		events = append(events, found...)
+	}
+
+	now := t.clock.Now()
+	next, err := t.repo.ListAfter(t.cursor, t.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, e := range next {
+		// 缺少太多 ID 时不是没提交的事务，不再等待
+		if e.ID-t.cursor <= uint(t.BatchSize) {
+			for id := t.cursor + 1; id < e.ID; id++ {
+				t.gaps[id] = now
+			}
+		}
+		t.cursor = e.ID
+	}
+	events = append(events, next...)
+	for id, since := range t.gaps {
+		if now.Sub(since) >= t.GapTimeout {
+			delete(t.gaps, id)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	for _, e := range events {
+		if err := t.hub.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package feed_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/feed"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+// committed 是只实现 Tailer 用到的方法的 outbox 仓储，events 是已经提交的事件
+type committed struct {
+	repository.OutboxRepository
+	events map[uint]model.OutboxEvent
+	last   uint
+}
+
+func (c *committed) commit(ids ...uint) {
+	for _, id := range ids {
+		c.events[id] = model.OutboxEvent{ID: id, TenantID: 1, EventType: model.EventBookUpdated}
+	}
+}
+
+func (c *committed) LastID() (uint, error) {
+	return c.last, nil
+}
+
+func (c *committed) ListAfter(id uint, limit int) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	for i := id + 1; i <= 100 && len(events) < limit; i++ {
+		if e, ok := c.events[i]; ok {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (c *committed) ListByIDs(ids []uint) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	for _, id := range ids {
+		if e, ok := c.events[id]; ok {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// TestTailerWaitsForGaps 较小的 ID 晚提交时仍然会推送，一直没有提交的 ID 超时后不再查询
+func TestTailerWaitsForGaps(t *testing.T) {
+	clk := clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo := &committed{events: make(map[uint]model.OutboxEvent), last: 2}
+	repo.commit(1, 2)
+	hub := feed.NewHub(config.Config{StreamReplaySize: 10})
+	sub, _, _ := hub.Subscribe(1, "", 0)
+	defer sub.Close()
+
+	tailer := feed.NewTailer(repo, hub, clk)
+	tailer.GapTimeout = time.Minute
+	steps := []struct {
+		name   string
+		commit []uint
+		wait   time.Duration
+		want   []uint
+	}{
+		{name: "start skips existing events", want: nil},
+		{name: "4 not committed yet", commit: []uint{3, 5}, want: []uint{3, 5}},
+		{name: "4 committed late", commit: []uint{4}, want: []uint{4}},
+		{name: "6 not committed yet", commit: []uint{7}, want: []uint{7}},
+		{name: "6 rolled back", wait: time.Minute, want: nil},
+		{name: "6 no longer watched", commit: []uint{6, 8}, want: []uint{8}},
+	}
+	for _, step := range steps {
+		repo.commit(step.commit...)
+		clk.Add(step.wait)
+		if err := tailer.Poll(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		var got []uint
+		for len(sub.C()) > 0 {
+			got = append(got, (<-sub.C()).OutboxID)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("%s: published %v, want %v", step.name, got, step.want)
+		}
+	}
+}
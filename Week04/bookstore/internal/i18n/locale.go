@@ -0,0 +1,125 @@
+// Package i18n 负责语言协商：解析 Accept-Language，生成语言回退链，并翻译 API 的错误信息
+package i18n
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Canonical 规范化 BCP 47 语言标签：语言小写，书写系统首字母大写，地区大写，
+// 例如 zh-hant-tw 规范化为 zh-Hant-TW，不是合法标签时返回 false
+func Canonical(tag string) (string, bool) {
+	parts := strings.Split(strings.Replace(strings.TrimSpace(tag), "_", "-", -1), "-")
+	if len(parts[0]) < 2 || len(parts[0]) > 3 || !alpha(parts[0]) {
+		return "", false
+	}
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		p := parts[i]
+		if len(p) == 0 || len(p) > 8 || !alphanumeric(p) {
+			return "", false
+		}
+		switch {
+		case len(p) == 4 && alpha(p):
+			parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		case len(p) == 2 && alpha(p):
+			parts[i] = strings.ToUpper(p)
+		default:
+			parts[i] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-"), true
+}
+
+// Parse 按 q 值从高到低返回 Accept-Language 中规范化后的语言标签，
+// q 值相同时保持原来的顺序，忽略 *、q=0 和不合法的标签
+func Parse(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ws []weighted
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag, ok := Canonical(params[0])
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			ws = append(ws, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(ws, func(i, j int) bool { return ws[i].q > ws[j].q })
+	tags := make([]string, len(ws))
+	for i, w := range ws {
+		tags[i] = w.tag
+	}
+	return tags
+}
+
+// Chain 生成语言回退链：按优先级依次是每个请求的语言和逐段截短后的上级语言，最后是 def，不重复。
+// 例如 def 为 en 时，Accept-Language: zh-TW, fr;q=0.8 得到 zh-TW、zh、fr、en
+func Chain(header, def string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			chain = append(chain, tag)
+		}
+	}
+	for _, tag := range Parse(header) {
+		for {
+			add(tag)
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	add(def)
+	return chain
+}
+
+type localesKey struct{}
+
+// WithLocales 把协商出的语言回退链放进 context
+func WithLocales(ctx context.Context, locales []string) context.Context {
+	return context.WithValue(ctx, localesKey{}, locales)
+}
+
+// LocalesFromContext 返回 context 中的语言回退链，没有协商过时返回 nil
+func LocalesFromContext(ctx context.Context) []string {
+	locales, _ := ctx.Value(localesKey{}).([]string)
+	return locales
+}
+
+func alpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func alphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
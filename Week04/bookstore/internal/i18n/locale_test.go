@@ -0,0 +1,90 @@
+package i18n_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+		ok   bool
+	}{
+		{tag: "en", want: "en", ok: true},
+		{tag: "ZH", want: "zh", ok: true},
+		{tag: "zh-tw", want: "zh-TW", ok: true},
+		{tag: "zh-hant-tw", want: "zh-Hant-TW", ok: true},
+		{tag: "zh_Hans_CN", want: "zh-Hans-CN", ok: true},
+		{tag: " es-419 ", want: "es-419", ok: true},
+		{tag: "de-CH-1996", want: "de-CH-1996", ok: true},
+		{tag: "", ok: false},
+		{tag: "*", ok: false},
+		{tag: "e", ok: false},
+		{tag: "engl", ok: false},
+		{tag: "en-", ok: false},
+		{tag: "en-toolongsubtag", ok: false},
+		{tag: "en-U$", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, ok := i18n.Canonical(tt.tag)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("Canonical(%q) = %q, %v, want %q, %v", tt.tag, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []string
+	}{
+		{name: "empty", header: "", want: []string{}},
+		{name: "single", header: "zh-tw", want: []string{"zh-TW"}},
+		{name: "sorted by q", header: "fr;q=0.5, en;q=0.8, zh", want: []string{"zh", "en", "fr"}},
+		{name: "equal q keeps order", header: "de;q=0.5, fr;q=0.5, it;q=0.5", want: []string{"de", "fr", "it"}},
+		{name: "spaces around params", header: "en ; q=0.3 ,ja", want: []string{"ja", "en"}},
+		{name: "other params ignored", header: "en;level=1;q=0.4, fr", want: []string{"fr", "en"}},
+		{name: "invalid q treated as 1", header: "en;q=0.5, fr;q=x", want: []string{"fr", "en"}},
+		{name: "q=0 excluded", header: "en, fr;q=0, de;q=0.000", want: []string{"en"}},
+		{name: "wildcard and invalid tags skipped", header: "*, zh-Hant, 1x, en-", want: []string{"zh-Hant"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := i18n.Parse(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Parse(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		def    string
+		want   []string
+	}{
+		{name: "no header", header: "", def: "en", want: []string{"en"}},
+		{name: "no header and no default", header: "", def: "", want: nil},
+		{name: "parents follow each tag", header: "zh-TW, fr;q=0.8", def: "en", want: []string{"zh-TW", "zh", "fr", "en"}},
+		{name: "script and region", header: "zh-Hant-HK", def: "en", want: []string{"zh-Hant-HK", "zh-Hant", "zh", "en"}},
+		{name: "parent not repeated", header: "en-GB, en;q=0.9, en-US;q=0.8", def: "en",
+			want: []string{"en-GB", "en", "en-US"}},
+		{name: "default already requested", header: "de, en;q=0.5", def: "en", want: []string{"de", "en"}},
+		{name: "q order before header order", header: "fr;q=0.5, ja", def: "en", want: []string{"ja", "fr", "en"}},
+		{name: "only unusable tags", header: "*, q;q=1", def: "zh", want: []string{"zh"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := i18n.Chain(tt.header, tt.def); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Chain(%q, %q) = %q, want %q", tt.header, tt.def, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SourceLocale 是错误信息原文的语言
+const SourceLocale = "en"
+
+// catalog 按语言保存每个 message key 的文案，%v 按顺序替换为错误的参数
+var catalog = map[string]map[string]string{
+	"en": {
+		"tenant.missing":               "missing tenant",
+		"tenant.unknown":               "unknown tenant",
+		"tenant.invalid_token":         "invalid tenant token",
+		"tenant.slug_exists":           "tenant slug already exists",
+		"tenant.invalid_slug":          "tenant slug must match [a-z0-9-]{2,63}",
+		"tenant.invalid_limit":         "tenant limits must not be negative",
+		"request.unacceptable":         "acceptable types: %v",
+		"book.not_found":               "book not found",
+		"book.quota_exceeded":          "book quota exceeded",
+		"book.invalid_category_filter": "category must be a comma separated list of category ids",
+		"idempotency.key_reused":       "idempotency key was used with a different request",
+		"idempotency.in_progress":      "a request with the same idempotency key is in progress",
+		"breaker.open":                 "circuit breaker %v is open",
+		"breaker.timeout":              "circuit breaker %v call timed out",
+		"translation.invalid_locale":   "invalid locale",
+		"translation.empty":            "translation must have a title or description",
+		"review.invalid":               "invalid review",
+		"review.author_required":       "reviewer must be identified by X-Actor",
+		"review.forbidden":             "review belongs to another user",
+		"review.duplicate":             "book already reviewed by this user",
+		"cover.missing":                "book has no cover",
+		"cover.unknown_size":           "unknown cover size",
+		"cover.unsupported_type":       "unsupported cover type, want jpeg, png or gif",
+		"cover.invalid_image":          "invalid cover image",
+		"cover.too_large":              "cover must not exceed %v bytes",
+		"webhook.invalid_url":          "webhook url must be an absolute http(s) url",
+		"webhook.unknown_event_type":   "unknown event type",
+		"stream.invalid_types":         "types must be some of %v",
+		"stream.invalid_last_event_id": "invalid Last-Event-ID",
+		"pricing.invalid_rule":         "invalid pricing rule",
+		"cart.customer_required":       "customer must be identified by X-Actor",
+		"cart.invalid_quantity":        "invalid quantity",
+		"order.empty_cart":             "cart is empty",
+		"order.book_unavailable":       "cart contains unavailable books",
+		"order.invalid_transition":     "invalid order status transition",
+		"record.conflict":              "record modified concurrently",
+		"payment.declined":             "payment declined",
+		"payment.in_progress":          "order already has a payment in progress",
+		"payment.invalid_state":        "invalid payment state",
+		"payment.invalid_signature":    "invalid webhook signature",
+		"recommendation.invalid_limit": "limit must be between 1 and %v",
+		"category.invalid_parent":      "invalid parent category",
+		"category.not_empty":           "category has subcategories",
+		"category.invalid":             "invalid category",
+		"category.name_exists":         "category name already used under this parent",
+		"batch.invalid_size":           "batch must contain between 1 and %v operations",
+		"batch.invalid_operation":      "invalid batch operation",
+		"batch.aborted":                "batch aborted by a failed operation",
+	},
+	"zh": {
+		"tenant.missing":               "缺少店铺",
+		"tenant.unknown":               "店铺不存在",
+		"tenant.invalid_token":         "店铺 token 无效",
+		"tenant.slug_exists":           "店铺标识已存在",
+		"tenant.invalid_slug":          "店铺标识必须匹配 [a-z0-9-]{2,63}",
+		"tenant.invalid_limit":         "店铺限额不能为负数",
+		"request.unacceptable":         "支持的类型：%v",
+		"book.not_found":               "图书不存在",
+		"book.quota_exceeded":          "图书数量超出店铺限额",
+		"book.invalid_category_filter": "category 必须是逗号分隔的分类 ID",
+		"idempotency.key_reused":       "幂等键已被其他请求使用",
+		"idempotency.in_progress":      "相同幂等键的请求正在处理中",
+		"breaker.open":                 "熔断器 %v 已打开，请稍后重试",
+		"breaker.timeout":              "熔断器 %v 保护的调用超时",
+		"translation.invalid_locale":   "语言标签无效",
+		"translation.empty":            "翻译至少需要书名或简介",
+		"review.invalid":               "评论无效",
+		"review.author_required":       "需要通过 X-Actor 标明评论人",
+		"review.forbidden":             "评论属于其他用户",
+		"review.duplicate":             "该用户已经评论过这本书",
+		"cover.missing":                "图书没有封面",
+		"cover.unknown_size":           "未知的封面尺寸",
+		"cover.unsupported_type":       "不支持的封面格式，只支持 jpeg、png 和 gif",
+		"cover.invalid_image":          "封面图片无效",
+		"cover.too_large":              "封面不能超过 %v 字节",
+		"webhook.invalid_url":          "webhook 地址必须是完整的 http(s) 地址",
+		"webhook.unknown_event_type":   "未知的事件类型",
+		"stream.invalid_types":         "types 只能是 %v 中的值",
+		"stream.invalid_last_event_id": "Last-Event-ID 无效",
+		"pricing.invalid_rule":         "优惠规则无效",
+		"cart.customer_required":       "需要通过 X-Actor 标明顾客",
+		"cart.invalid_quantity":        "数量无效",
+		"order.empty_cart":             "购物车是空的",
+		"order.book_unavailable":       "购物车中有已下架的图书",
+		"order.invalid_transition":     "订单状态不允许这样变更",
+		"record.conflict":              "记录已被并发修改，请重试",
+		"payment.declined":             "支付被拒绝",
+		"payment.in_progress":          "订单已有进行中的支付",
+		"payment.invalid_state":        "支付状态不允许该操作",
+		"payment.invalid_signature":    "回调签名无效",
+		"recommendation.invalid_limit": "limit 必须在 1 到 %v 之间",
+		"category.invalid_parent":      "父分类无效",
+		"category.not_empty":           "分类下还有子分类",
+		"category.invalid":             "分类无效",
+		"category.name_exists":         "同一父分类下已有同名分类",
+		"batch.invalid_size":           "批量操作必须包含 1 到 %v 个操作",
+		"batch.invalid_operation":      "批量操作无效",
+		"batch.aborted":                "其他操作失败，整批操作已回滚",
+	},
+	"zh-TW": {
+		"tenant.missing":               "缺少商店",
+		"tenant.unknown":               "商店不存在",
+		"tenant.invalid_token":         "商店 token 無效",
+		"tenant.slug_exists":           "商店代號已存在",
+		"tenant.invalid_slug":          "商店代號必須符合 [a-z0-9-]{2,63}",
+		"tenant.invalid_limit":         "商店限額不能為負數",
+		"request.unacceptable":         "支援的類型：%v",
+		"book.not_found":               "書籍不存在",
+		"book.quota_exceeded":          "書籍數量超出商店限額",
+		"book.invalid_category_filter": "category 必須是以逗號分隔的分類 ID",
+		"idempotency.key_reused":       "冪等鍵已被其他請求使用",
+		"idempotency.in_progress":      "相同冪等鍵的請求正在處理中",
+		"breaker.open":                 "斷路器 %v 已開啟，請稍後重試",
+		"breaker.timeout":              "斷路器 %v 保護的呼叫逾時",
+		"translation.invalid_locale":   "語言標籤無效",
+		"translation.empty":            "翻譯至少需要書名或簡介",
+		"review.invalid":               "評論無效",
+		"review.author_required":       "需要透過 X-Actor 標明評論者",
+		"review.forbidden":             "評論屬於其他使用者",
+		"review.duplicate":             "該使用者已經評論過這本書",
+		"cover.missing":                "書籍沒有封面",
+		"cover.unknown_size":           "未知的封面尺寸",
+		"cover.unsupported_type":       "不支援的封面格式，只支援 jpeg、png 和 gif",
+		"cover.invalid_image":          "封面圖片無效",
+		"cover.too_large":              "封面不能超過 %v 位元組",
+		"webhook.invalid_url":          "webhook 網址必須是完整的 http(s) 網址",
+		"webhook.unknown_event_type":   "未知的事件類型",
+		"stream.invalid_types":         "types 只能是 %v 中的值",
+		"stream.invalid_last_event_id": "Last-Event-ID 無效",
+		"pricing.invalid_rule":         "優惠規則無效",
+		"cart.customer_required":       "需要透過 X-Actor 標明顧客",
+		"cart.invalid_quantity":        "數量無效",
+		"order.empty_cart":             "購物車是空的",
+		"order.book_unavailable":       "購物車中有已下架的書籍",
+		"order.invalid_transition":     "訂單狀態不允許這樣變更",
+		"record.conflict":              "記錄已被同時修改，請重試",
+		"payment.declined":             "付款遭拒",
+		"payment.in_progress":          "訂單已有進行中的付款",
+		"payment.invalid_state":        "付款狀態不允許該操作",
+		"payment.invalid_signature":    "回呼簽章無效",
+		"recommendation.invalid_limit": "limit 必須在 1 到 %v 之間",
+		"category.invalid_parent":      "上層分類無效",
+		"category.not_empty":           "分類下還有子分類",
+		"category.invalid":             "分類無效",
+		"category.name_exists":         "同一上層分類下已有同名分類",
+		"batch.invalid_size":           "批次必須包含 1 到 %v 個操作",
+		"batch.invalid_operation":      "批次操作無效",
+		"batch.aborted":                "其他操作失敗，整批操作已復原",
+	},
+}
+
+// Error 是带 message key 的错误，Error 返回英文原文，写出响应时再按请求的语言翻译
+type Error struct {
+	Key  string
+	Args []interface{}
+}
+
+// NewError 返回 key 对应的错误。包级的错误变量用它创建后仍然可以用 == 比较
+func NewError(key string, args ...interface{}) *Error {
+	return &Error{Key: key, Args: args}
+}
+
+func (e *Error) Error() string {
+	msg, _ := Translate(nil, e.Key, e.Args...)
+	return msg
+}
+
+// Message 返回 message key 和参数
+func (e *Error) Message() (string, []interface{}) {
+	return e.Key, e.Args
+}
+
+// Localizer 是可以翻译的错误，需要携带其他字段的错误类型也可以实现它
+type Localizer interface {
+	error
+	Message() (string, []interface{})
+}
+
+// Translate 按回退链查找 key 的文案，返回文案和所用的语言；
+// 链中的语言都没有文案时返回原文和 SourceLocale，没有原文时返回 key
+func Translate(locales []string, key string, args ...interface{}) (string, string) {
+	for _, locale := range locales {
+		if locale == SourceLocale {
+			break
+		}
+		if tmpl, ok := catalog[locale][key]; ok {
+			return format(tmpl, args), locale
+		}
+	}
+	if tmpl, ok := catalog[SourceLocale][key]; ok {
+		return format(tmpl, args), SourceLocale
+	}
+	return key, SourceLocale
+}
+
+// Localize 翻译 err 链中第一个 Localizer，没有时返回 err 的原文和 SourceLocale
+func Localize(locales []string, err error) (string, string) {
+	var l Localizer
+	if !errors.As(err, &l) {
+		return err.Error(), SourceLocale
+	}
+	key, args := l.Message()
+	return Translate(locales, key, args...)
+}
+
+func format(tmpl string, args []interface{}) string {
+	if len(args) == 0 {
+		return tmpl
+	}
+	return fmt.Sprintf(tmpl, args...)
+}
@@ -0,0 +1,36 @@
+package i18n_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+)
+
+func TestLocalize(t *testing.T) {
+	tooLarge := i18n.NewError("cover.too_large", 1024)
+	tests := []struct {
+		name       string
+		locales    []string
+		err        error
+		want       string
+		wantLocale string
+	}{
+		{name: "source", locales: []string{"en"}, err: tooLarge, want: "cover must not exceed 1024 bytes", wantLocale: "en"},
+		{name: "translated", locales: []string{"zh-TW", "zh"}, err: tooLarge, want: "封面不能超過 1024 位元組", wantLocale: "zh-TW"},
+		{name: "fallback", locales: []string{"zh-CN", "zh"}, err: tooLarge, want: "封面不能超过 1024 字节", wantLocale: "zh"},
+		{name: "source before translation", locales: []string{"fr", "en", "zh"}, err: tooLarge, want: "cover must not exceed 1024 bytes", wantLocale: "en"},
+		{name: "wrapped", locales: []string{"zh"}, err: fmt.Errorf("upload: %w", i18n.NewError("cart.invalid_quantity")), want: "数量无效", wantLocale: "zh"},
+		{name: "no key", locales: []string{"zh"}, err: errors.New("boom"), want: "boom", wantLocale: "en"},
+		{name: "unknown key", locales: []string{"zh"}, err: i18n.NewError("no.such.key"), want: "no.such.key", wantLocale: "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, locale := i18n.Localize(tt.locales, tt.err)
+			if got != tt.want || locale != tt.wantLocale {
+				t.Fatalf("Localize(%v, %v) = %q, %q, want %q, %q", tt.locales, tt.err, got, locale, tt.want, tt.wantLocale)
+			}
+		})
+	}
+}
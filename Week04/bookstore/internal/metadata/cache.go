@@ -0,0 +1,82 @@
+package metadata
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+)
+
+const defaultCacheSize = 10000
+
+// Cache 缓存 Lookup 的结果，查不到的结果也会缓存，其他错误不缓存。
+// 封面只会在补全时下载一次，不缓存
+type Cache struct {
+	next  Provider
+	ttl   time.Duration
+	size  int
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	md        Metadata
+	err       error
+	expiresAt time.Time
+}
+
+func NewCache(cfg config.Config, next *OpenLibrary, clk clock.Clock) *Cache {
+	return &Cache{
+		next:    next,
+		ttl:     cfg.MetadataCacheTTL,
+		size:    defaultCacheSize,
+		clock:   clk,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (c *Cache) Lookup(ctx context.Context, isbn string) (Metadata, error) {
+	isbn = Normalize(isbn)
+	now := c.clock.Now()
+	c.mu.Lock()
+	e, ok := c.entries[isbn]
+	c.mu.Unlock()
+	if ok && now.Before(e.expiresAt) {
+		return e.md, e.err
+	}
+
+	md, err := c.next.Lookup(ctx, isbn)
+	if err != nil && err != ErrNotFound {
+		return md, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.size {
+		c.evict(now)
+	}
+	c.entries[isbn] = cacheEntry{md: md, err: err, expiresAt: now.Add(c.ttl)}
+	return md, err
+}
+
+// evict 删除过期的条目，都没有过期时随便删掉一条
+func (c *Cache) evict(now time.Time) {
+	for isbn, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, isbn)
+		}
+	}
+	for isbn := range c.entries {
+		if len(c.entries) < c.size {
+			return
+		}
+		delete(c.entries, isbn)
+	}
+}
+
+func (c *Cache) Cover(ctx context.Context, isbn string) ([]byte, error) {
+	return c.next.Cover(ctx, isbn)
+}
@@ -0,0 +1,64 @@
+package metadata
+
+import (
+	"context"
+	"sync"
+)
+
+// Fake 是内存中的 Provider，没有添加过的 ISBN 返回 ErrNotFound
+type Fake struct {
+	mu     sync.RWMutex
+	books  map[string]Metadata
+	covers map[string][]byte
+}
+
+func NewFake() *Fake {
+	return &Fake{books: make(map[string]Metadata), covers: make(map[string][]byte)}
+}
+
+// NewSampleFake 返回预置了几本书的 Fake，方便本地运行时体验自动补全
+func NewSampleFake() *Fake {
+	f := NewFake()
+	f.Add("9780134190440", Metadata{
+		Title:     "The Go Programming Language",
+		Authors:   []string{"Alan A. A. Donovan", "Brian W. Kernighan"},
+		Publisher: "Addison-Wesley",
+	}, nil)
+	f.Add("9780262033848", Metadata{
+		Title:     "Introduction to Algorithms",
+		Authors:   []string{"Thomas H. Cormen", "Charles E. Leiserson", "Ronald L. Rivest", "Clifford Stein"},
+		Publisher: "MIT Press",
+	}, nil)
+	return f
+}
+
+// Add 添加一本书，cover 为空表示没有封面
+func (f *Fake) Add(isbn string, md Metadata, cover []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	isbn = Normalize(isbn)
+	f.books[isbn] = md
+	if cover != nil {
+		f.covers[isbn] = cover
+	}
+}
+
+func (f *Fake) Lookup(ctx context.Context, isbn string) (Metadata, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	md, ok := f.books[Normalize(isbn)]
+	if !ok {
+		return md, ErrNotFound
+	}
+	return md, nil
+}
+
+func (f *Fake) Cover(ctx context.Context, isbn string) ([]byte, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	cover, ok := f.covers[Normalize(isbn)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cover, nil
+}
@@ -0,0 +1,38 @@
+// Package metadata 按 ISBN 查询图书的书名、作者、出版社和封面。
+// Provider 可以替换：线上使用 Open Library 风格的 HTTP 接口，本地和测试使用 Fake，
+// Cache 缓存查询结果，包括查不到的结果。
+package metadata
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/wire"
+)
+
+// ProviderSet 提供带缓存的 Open Library 实现
+var ProviderSet = wire.NewSet(NewOpenLibrary, NewCache, wire.Bind(new(Provider), new(*Cache)))
+
+// FakeSet 提供预置了几本书的 Fake，用于本地运行
+var FakeSet = wire.NewSet(NewSampleFake, wire.Bind(new(Provider), new(*Fake)))
+
+// ErrNotFound 表示查不到这个 ISBN，或者这本书没有封面
+var ErrNotFound = errors.New("metadata not found")
+
+type Metadata struct {
+	Title     string
+	Authors   []string
+	Publisher string
+}
+
+type Provider interface {
+	Lookup(ctx context.Context, isbn string) (Metadata, error)
+	// Cover 返回封面原图
+	Cover(ctx context.Context, isbn string) ([]byte, error)
+}
+
+// Normalize 去掉 ISBN 中的连字符和空格，X 统一为大写
+func Normalize(isbn string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+}
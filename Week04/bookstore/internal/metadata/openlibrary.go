@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+)
+
+const maxCoverSize = 10 << 20
+
+// OpenLibrary 调用 Open Library 风格的 Books API 和 Covers API
+type OpenLibrary struct {
+	BaseURL   string
+	CoversURL string
+	Client    *http.Client
+}
+
+func NewOpenLibrary(cfg config.Config) *OpenLibrary {
+	return &OpenLibrary{
+		BaseURL:   cfg.MetadataURL,
+		CoversURL: cfg.MetadataCoversURL,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type openLibraryBook struct {
+	Title   string `json:"title"`
+	Authors []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Publishers []struct {
+		Name string `json:"name"`
+	} `json:"publishers"`
+}
+
+func (o *OpenLibrary) Lookup(ctx context.Context, isbn string) (Metadata, error) {
+	key := "ISBN:" + Normalize(isbn)
+	q := url.Values{"bibkeys": {key}, "format": {"json"}, "jscmd": {"data"}}
+	resp, err := o.get(ctx, o.BaseURL+"/api/books?"+q.Encode())
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	// 查不到时返回空对象
+	var books map[string]openLibraryBook
+	if err := json.NewDecoder(resp.Body).Decode(&books); err != nil {
+		return Metadata{}, err
+	}
+	book, ok := books[key]
+	if !ok {
+		return Metadata{}, ErrNotFound
+	}
+	md := Metadata{Title: book.Title}
+	for _, a := range book.Authors {
+		md.Authors = append(md.Authors, a.Name)
+	}
+	if len(book.Publishers) > 0 {
+		md.Publisher = book.Publishers[0].Name
+	}
+	return md, nil
+}
+
+func (o *OpenLibrary) Cover(ctx context.Context, isbn string) ([]byte, error) {
+	// default=false 让没有封面的书返回 404，而不是一张占位图
+	resp, err := o.get(ctx, fmt.Sprintf("%s/b/isbn/%s-L.jpg?default=false", o.CoversURL, url.PathEscape(Normalize(isbn))))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxCoverSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxCoverSize {
+		return nil, fmt.Errorf("metadata: cover of %s is larger than %d bytes", isbn, maxCoverSize)
+	}
+	return data, nil
+}
+
+// get 发送 GET 请求，404 返回 ErrNotFound，其他非 200 的状态码返回错误
+func (o *OpenLibrary) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("metadata: GET %s: %s", u, resp.Status)
+}
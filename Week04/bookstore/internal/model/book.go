@@ -1,9 +1,35 @@
 package model
 
-import "github.com/jinzhu/gorm"
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
 
 type Book struct {
 	gorm.Model
-	ISBN  string
-	Price float32
+	TenantID uint `gorm:"index"`
+	ISBN     string
+	Title    string
+	// Description 和 Title 一样使用系统默认语言，其他语言的版本在 BookTranslation 里
+	Description string `gorm:"type:text"`
+	Author      string `gorm:"index"`
+	Publisher   string
+	// Category 是自由填写的分类标签，只用于展示和导入导出，优惠规则按 BookCategory 的分类树匹配
+	Category string `gorm:"index"`
+	Price    float32
+	// CoverType 是封面的保存格式，为空表示没有封面
+	CoverType      string
+	CoverUpdatedAt *time.Time
+	// RatingSum 和 RatingCount 是已审核通过的评论的评分总和与条数，随评论状态增量维护
+	RatingSum   int
+	RatingCount int
+}
+
+// AverageRating 返回平均评分，没有评分时返回 0
+func (b Book) AverageRating() float64 {
+	if b.RatingCount == 0 {
+		return 0
+	}
+	return float64(b.RatingSum) / float64(b.RatingCount)
 }
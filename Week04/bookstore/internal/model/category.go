@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Category 是店铺内的图书分类树。Path 是从根到自身的 ID 路径，形如 /1/4/9/，
+// 查询子树时按前缀匹配，移动分类时整棵子树的 Path 一起更新
+type Category struct {
+	ID        uint   `gorm:"primary_key"`
+	TenantID  uint   `gorm:"unique_index:idx_categories_sibling_name"`
+	ParentID  uint   `gorm:"unique_index:idx_categories_sibling_name"`
+	Name      string `gorm:"unique_index:idx_categories_sibling_name"`
+	Path      string `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// BookCategory 是图书和分类的多对多关系
+type BookCategory struct {
+	BookID     uint `gorm:"primary_key;auto_increment:false"`
+	CategoryID uint `gorm:"primary_key;auto_increment:false"`
+	TenantID   uint `gorm:"index"`
+}
+
+// CategoryCount 是分类和它整棵子树中的图书数，一本书属于子树中多个分类时只算一次
+type CategoryCount struct {
+	Category
+	BookCount int
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// 图书变更动作
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionRevert = "revert"
+	// ActionEnrich 是根据 ISBN 自动补全图书信息
+	ActionEnrich = "enrich"
+)
+
+// BookRevision 是图书的一条变更记录，Version 在同一本书内从 1 开始递增。
+// Changes 是 []FieldChange 的 JSON，Snapshot 是变更后图书字段的 JSON，回滚时使用
+type BookRevision struct {
+	ID        uint `gorm:"primary_key"`
+	BookID    uint `gorm:"unique_index:idx_book_revisions_book_version"`
+	Version   int  `gorm:"unique_index:idx_book_revisions_book_version"`
+	Action    string
+	Actor     string
+	Changes   string `gorm:"type:text"`
+	Snapshot  string `gorm:"type:text"`
+	CreatedAt time.Time
+}
+
+// FieldChange 记录一个字段变更前后的值
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// IdempotencyRecord 记录一个 Idempotency-Key 对应的请求指纹和首次响应，
+// CompletedAt 为空表示首个请求还在处理中
+type IdempotencyRecord struct {
+	Key         string `gorm:"column:idempotency_key;primary_key"`
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        string `gorm:"type:mediumtext"`
+	CompletedAt *time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
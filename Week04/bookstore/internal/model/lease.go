@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// JobLease 记录定时任务当前由哪个实例持有，过期之后其他实例才能接手
+type JobLease struct {
+	Name      string `gorm:"primary_key"`
+	Holder    string
+	ExpiresAt time.Time
+	UpdatedAt time.Time
+}
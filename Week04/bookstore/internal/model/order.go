@@ -0,0 +1,97 @@
+package model
+
+import "time"
+
+// 订单状态，pending 可以支付或取消，paid 可以发货或取消，shipped 和 cancelled 是终态。
+// shipping 和 cancelling 是已支付订单请款、退款期间的状态，网关调用失败时回到 paid，
+// 进程在调用中途退出时可以再次发货或取消，从这两个状态继续
+const (
+	OrderPending    = "pending"
+	OrderPaid       = "paid"
+	OrderShipping   = "shipping"
+	OrderShipped    = "shipped"
+	OrderCancelling = "cancelling"
+	OrderCancelled  = "cancelled"
+)
+
+var orderTransitions = map[string][]string{
+	OrderPending:    {OrderPaid, OrderCancelled},
+	OrderPaid:       {OrderShipped, OrderCancelled},
+	OrderShipping:   {OrderShipped},
+	OrderCancelling: {OrderCancelled},
+}
+
+// CanTransition 判断订单能否从 from 状态变为 to 状态
+func CanTransition(from, to string) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CartItem 是购物车里的一种图书，每个顾客在每个店铺有一个购物车。
+// 顾客取自 X-Actor，服务本身不认证这个头，需要由前面的网关认证后设置
+type CartItem struct {
+	ID        uint   `gorm:"primary_key"`
+	TenantID  uint   `gorm:"unique_index:idx_cart_items_owner_book"`
+	Customer  string `gorm:"unique_index:idx_cart_items_owner_book"`
+	BookID    uint   `gorm:"unique_index:idx_cart_items_owner_book"`
+	Quantity  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Cart 是按当前优惠规则计价的购物车，Total 只包含还能购买的图书
+type Cart struct {
+	Lines []CartLine
+	Total float32
+}
+
+// CartLine 的 Book 是图书的当前状态，图书被删除后 Available 为 false，结算前需要移除。
+// UnitPrice 是优惠后的单价，Adjustments 是计算它用到的优惠
+type CartLine struct {
+	CartItem
+	Book        Book
+	Available   bool
+	UnitPrice   float32
+	Adjustments []PriceAdjustment
+	Subtotal    float32
+}
+
+// Order 是结算时生成的订单。订单行保存了结算时的书名和价格，之后图书的修改不影响订单，
+// 订单创建后只有状态会变化
+type Order struct {
+	ID        uint   `gorm:"primary_key"`
+	TenantID  uint   `gorm:"index"`
+	Customer  string `gorm:"index"`
+	Status    string `gorm:"index"`
+	Total     float32
+	Items     []OrderItem
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// OrderItem 的 BasePrice 是图书的标价，UnitPrice 是结算时优惠后的单价，
+// Adjustments 是结算时用到的 []PriceAdjustment 的 JSON
+type OrderItem struct {
+	ID          uint `gorm:"primary_key"`
+	OrderID     uint `gorm:"index"`
+	BookID      uint
+	ISBN        string
+	Title       string
+	BasePrice   float32
+	UnitPrice   float32
+	Adjustments string `gorm:"type:text"`
+	Quantity    int
+}
+
+// PriceAdjustment 是一条优惠规则带来的优惠
+type PriceAdjustment struct {
+	RuleID   uint    `json:"rule_id"`
+	Name     string  `json:"name"`
+	Kind     string  `json:"kind"`
+	Amount   float32 `json:"amount"`
+	Discount float32 `json:"discount"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// 图书领域事件类型
+const (
+	EventBookCreated  = "book.created"
+	EventBookUpdated  = "book.updated"
+	EventBookRepriced = "book.repriced"
+	EventBookDeleted  = "book.deleted"
+)
+
+// BookEventTypes 列出所有图书事件类型
+var BookEventTypes = []string{EventBookCreated, EventBookUpdated, EventBookRepriced, EventBookDeleted}
+
+func IsBookEventType(t string) bool {
+	for _, v := range BookEventTypes {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// OutboxEvent 是写入 outbox 表的领域事件，和业务数据在同一个事务中提交，
+// 由 outbox.Dispatcher 异步投递，PublishedAt 为空表示尚未投递成功。
+// 投递失败后到 NextAttemptAt 才重试，失败次数太多时记录 DeadAt 不再投递。
+// ClaimToken 和 ClaimedUntil 是正在投递这个事件的实例和它的租期，租期内其他实例不会投递
+type OutboxEvent struct {
+	ID            uint `gorm:"primary_key"`
+	AggregateID   uint `gorm:"index"`
+	TenantID      uint
+	EventType     string
+	Payload       string `gorm:"type:text"`
+	Attempts      int
+	LastError     string `gorm:"type:text"`
+	NextAttemptAt *time.Time
+	ClaimToken    string `gorm:"index"`
+	ClaimedUntil  *time.Time
+	CreatedAt     time.Time
+	PublishedAt   *time.Time `gorm:"index"`
+	DeadAt        *time.Time
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Payment 是订单的一次支付，ProviderRef 是网关上的支付 ID，Status 的取值见 payment 包。
+// Reference 是发给网关的幂等键，由订单和之前的支付决定，同一次支付的并发请求得到同一个 Reference。
+// 一个订单可能有多次支付，例如被拒绝后换一张卡，最新的一次决定订单的支付状态
+type Payment struct {
+	ID          uint   `gorm:"primary_key"`
+	TenantID    uint   `gorm:"index"`
+	OrderID     uint   `gorm:"index"`
+	ProviderRef string `gorm:"unique_index"`
+	Reference   string `gorm:"unique_index"`
+	Status      string
+	Amount      float32
+	Currency    string
+	DeclineCode string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 优惠方式
+const (
+	DiscountPercentage = "percentage"
+	DiscountFixed      = "fixed"
+)
+
+// 优惠规则的适用范围
+const (
+	ScopeAll      = "all"
+	ScopeBook     = "book"
+	ScopeAuthor   = "author"
+	ScopeCategory = "category"
+)
+
+// PricingRule 是店铺的一条优惠规则。
+// Kind 为 percentage 时 Amount 是折扣百分比（0-100），为 fixed 时是直接减掉的金额；
+// Scope 为 book、author、category 时 Target 分别是图书 ID、作者和分类 ID，
+// 分类规则对分类的整棵子树生效；
+// CouponCode 不为空时只有使用对应优惠码才生效；StartsAt、EndsAt 为空表示不限制。
+// 多条规则按 Priority 从小到大依次叠加，Exclusive 的规则不和其他规则叠加
+type PricingRule struct {
+	gorm.Model
+	TenantID   uint `gorm:"index"`
+	Name       string
+	Kind       string
+	Amount     float32
+	Scope      string
+	Target     string
+	CouponCode string
+	Priority   int
+	Exclusive  bool
+	StartsAt   *time.Time
+	EndsAt     *time.Time
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// 推荐的来源
+const (
+	RecommendAlsoBought = "also_bought"
+	RecommendBestSeller = "best_seller"
+)
+
+// BookRecommendation 是定时任务算出的"买了这本书的顾客也买了"，Score 在 0 到 1 之间，越大越相关
+type BookRecommendation struct {
+	ID            uint `gorm:"primary_key"`
+	TenantID      uint `gorm:"index"`
+	BookID        uint `gorm:"index"`
+	RelatedBookID uint
+	Score         float64
+	ComputedAt    time.Time
+}
+
+// Recommendation 是返回给顾客的一条推荐，Reason 是 RecommendAlsoBought 或 RecommendBestSeller
+type Recommendation struct {
+	Book   Book
+	Reason string
+	Score  float64
+}
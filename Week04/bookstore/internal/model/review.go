@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// 评论的审核状态，只有 approved 的评论会公开展示并计入图书评分
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewHidden   = "hidden"
+)
+
+// Review 是用户对图书的评论，每个用户对每本书只能有一条评论。
+// 删除是物理删除，删除后用户可以重新评论
+type Review struct {
+	ID        uint   `gorm:"primary_key"`
+	BookID    uint   `gorm:"unique_index:idx_reviews_book_author"`
+	Author    string `gorm:"unique_index:idx_reviews_book_author"`
+	Rating    int
+	Text      string `gorm:"type:text"`
+	Status    string `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,13 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// Tenant 是一个店铺，图书按店铺隔离。
+// MaxBooks 限制店铺的图书数量，RequestsPerSecond 限制店铺的请求速率，0 表示不限制
+type Tenant struct {
+	gorm.Model
+	Slug              string `gorm:"unique_index"`
+	Name              string
+	MaxBooks          int
+	RequestsPerSecond int
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// BookTranslation 是图书在某种语言下的书名和简介，Locale 是规范化的 BCP 47 标签，例如 zh-TW。
+// 为空的字段沿用回退链中下一种语言的翻译或者图书本身的内容
+type BookTranslation struct {
+	ID          uint   `gorm:"primary_key"`
+	TenantID    uint   `gorm:"index"`
+	BookID      uint   `gorm:"unique_index:idx_book_translations_locale"`
+	Locale      string `gorm:"unique_index:idx_book_translations_locale"`
+	Title       string
+	Description string `gorm:"type:text"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// webhook 投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// WebhookSubscription 是合作方为某个店铺注册的回调地址，只会收到这个店铺的事件。
+// EventTypes 为逗号分隔的事件类型，为空表示订阅全部事件
+type WebhookSubscription struct {
+	gorm.Model
+	TenantID   uint `gorm:"index"`
+	URL        string
+	Secret     string
+	EventTypes string
+}
+
+// Accepts 判断订阅是否关心某类事件
+func (s WebhookSubscription) Accepts(eventType string) bool {
+	if s.EventTypes == "" {
+		return true
+	}
+	for _, t := range strings.Split(s.EventTypes, ",") {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery 是一次事件到一个订阅的投递记录，同时也是投递日志。
+// Payload 是最终 POST 出去的请求体，签名基于它计算。
+// ClaimToken 是最近一次认领它的 webhook.Worker，认领时 NextAttemptAt 推迟到租期结束
+type WebhookDelivery struct {
+	ID             uint `gorm:"primary_key"`
+	SubscriptionID uint `gorm:"unique_index:idx_webhook_deliveries_subscription_event"`
+	EventID        uint `gorm:"unique_index:idx_webhook_deliveries_subscription_event"`
+	EventType      string
+	Payload        string `gorm:"type:text"`
+	Status         string `gorm:"index"`
+	Attempts       int
+	NextAttemptAt  time.Time `gorm:"index"`
+	ClaimToken     string    `gorm:"index"`
+	LastStatusCode int
+	LastError      string `gorm:"type:text"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
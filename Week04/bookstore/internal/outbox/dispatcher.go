@@ -0,0 +1,185 @@
+// Package outbox 实现事务性 outbox 的投递端。
+// BookService 在写业务数据的事务里同时写入 outbox 表，Dispatcher 在后台轮询
+// 未投递的事件并交给 Publisher，成功后才标记为已投递，因此是至少一次投递。
+// 同一本书（AggregateID）的事件严格按写入顺序投递，前一条失败时后面的都要等待，
+// 不同书之间的事件并发投递。失败的事件按指数退避重试，超过最大次数后进入死信（DeadAt），
+// 不再阻塞同一本书后面的事件。多个实例同时运行时，每个实例先认领事件再投递，
+// 同一本书的事件同一时间只由一个实例投递。
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+const (
+	defaultInterval    = time.Second
+	defaultBatchSize   = 100
+	defaultMaxAttempts = 10
+	defaultBaseBackoff = time.Second
+	defaultMaxBackoff  = 10 * time.Minute
+	defaultClaimTTL    = time.Minute
+)
+
+// Dispatcher 轮询 outbox 表并投递事件
+type Dispatcher struct {
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// ClaimTTL 是认领的租期，实例在投递中途退出时，租期过后其他实例重新认领
+	ClaimTTL time.Duration
+
+	repo       repository.OutboxRepository
+	publishers []Publisher
+	clock      clock.Clock
+	holder     string
+}
+
+// claims 为每次认领生成不同的 token，同一个进程里的多个 Dispatcher 也不会重复
+var claims uint64
+
+// NewDispatcher 构造 Dispatcher，每个事件需要所有 publisher 都成功才算投递完成
+func NewDispatcher(repo repository.OutboxRepository, clk clock.Clock, publishers ...Publisher) *Dispatcher {
+	host, _ := os.Hostname()
+	return &Dispatcher{
+		Interval:    defaultInterval,
+		BatchSize:   defaultBatchSize,
+		MaxAttempts: defaultMaxAttempts,
+		BaseBackoff: defaultBaseBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+		ClaimTTL:    defaultClaimTTL,
+		repo:        repo,
+		publishers:  publishers,
+		clock:       clk,
+		holder:      fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Run 周期性地投递事件，直到 ctx 被取消
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Dispatch(ctx); err != nil {
+			log.Printf("outbox dispatch err: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch 认领并投递一批到期的事件
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	token := fmt.Sprintf("%s-%d", d.holder, atomic.AddUint64(&claims, 1))
+	now := d.clock.Now()
+	events, err := d.repo.Claim(token, now, now.Add(d.ClaimTTL), d.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	// 按书分组，组内保持 Claim 返回的顺序
+	var order []uint
+	groups := make(map[uint][]model.OutboxEvent)
+	for _, e := range events {
+		if _, ok := groups[e.AggregateID]; !ok {
+			order = append(order, e.AggregateID)
+		}
+		groups[e.AggregateID] = append(groups[e.AggregateID], e)
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range order {
+		wg.Add(1)
+		go func(events []model.OutboxEvent) {
+			defer wg.Done()
+			d.dispatchAggregate(ctx, events)
+		}(groups[id])
+	}
+	wg.Wait()
+	return nil
+}
+
+func (d *Dispatcher) dispatchAggregate(ctx context.Context, events []model.OutboxEvent) {
+	for i, e := range events {
+		if ctx.Err() != nil {
+			d.release(events[i:])
+			return
+		}
+		err := d.publish(ctx, e)
+		if err == nil {
+			if err := d.repo.MarkPublished(e.ID); err != nil {
+				log.Printf("mark outbox event %d published err: %v", e.ID, err)
+				d.release(events[i:])
+				return
+			}
+			continue
+		}
+
+		log.Printf("publish outbox event %d err: %v", e.ID, err)
+		if e.Attempts+1 >= d.MaxAttempts {
+			log.Printf("outbox event %d failed %d times, giving up", e.ID, e.Attempts+1)
+			if err := d.repo.MarkDead(e.ID, err); err != nil {
+				log.Printf("mark outbox event %d dead err: %v", e.ID, err)
+				d.release(events[i:])
+				return
+			}
+			continue
+		}
+		if err := d.repo.MarkFailed(e.ID, err, d.clock.Now().Add(d.backoff(e.Attempts+1))); err != nil {
+			log.Printf("mark outbox event %d failed err: %v", e.ID, err)
+		}
+		// 同一本书的后续事件要等这条投递成功后再投递
+		d.release(events[i+1:])
+		return
+	}
+}
+
+// release 放弃认领没有投递的事件，让它们不用等到租期结束
+func (d *Dispatcher) release(events []model.OutboxEvent) {
+	if len(events) == 0 {
+		return
+	}
+	ids := make([]uint, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	if err := d.repo.Release(ids); err != nil {
+		log.Printf("release outbox events err: %v", err)
+	}
+}
+
+// backoff 计算第 attempts 次失败后的等待时间：BaseBackoff * 2^(attempts-1)，不超过 MaxBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	b := d.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		b *= 2
+		if b >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+	return b
+}
+
+func (d *Dispatcher) publish(ctx context.Context, e model.OutboxEvent) error {
+	for _, p := range d.publishers {
+		if err := p.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/outbox"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository/memory"
+)
+
+var errDown = errors.New("publisher down")
+
+// recorder 记录投递成功的事件，failing 里的事件总是投递失败
+type recorder struct {
+	mu        sync.Mutex
+	published []uint
+	failing   map[uint]bool
+}
+
+func (r *recorder) Publish(ctx context.Context, e model.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failing[e.ID] {
+		return errDown
+	}
+	r.published = append(r.published, e.ID)
+	return nil
+}
+
+func (r *recorder) take() []uint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.published
+	r.published = nil
+	return p
+}
+
+// add 依次为每本书写入一个事件，事件 ID 从 1 开始
+func add(t *testing.T, store *memory.Store, books ...uint) {
+	t.Helper()
+	for _, id := range books {
+		if err := store.Outbox.Add(model.OutboxEvent{AggregateID: id, EventType: model.EventBookUpdated}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestFailingEventBacksOffAndDies 一本书的事件一直失败时，其他书的事件照常投递，
+// 这本书后面的事件等到它进入死信后再投递
+func TestFailingEventBacksOffAndDies(t *testing.T) {
+	clk := clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := memory.NewStore(clk)
+	r := &recorder{failing: map[uint]bool{1: true}}
+	d := outbox.NewDispatcher(store.Outbox, clk, r)
+	d.MaxAttempts, d.BaseBackoff, d.MaxBackoff = 3, time.Second, time.Minute
+	add(t, store, 1, 2, 1)
+
+	steps := []struct {
+		wait time.Duration
+		want []uint
+	}{
+		{wait: 0, want: []uint{2}},
+		// 第一次失败后等 1 秒再重试
+		{wait: 500 * time.Millisecond, want: nil},
+		{wait: 500 * time.Millisecond, want: nil},
+		// 第二次失败后等 2 秒，第三次失败后进入死信，后面的事件接着投递
+		{wait: time.Second, want: nil},
+		{wait: 2 * time.Second, want: []uint{3}},
+		{wait: time.Hour, want: nil},
+	}
+	for i, step := range steps {
+		clk.Add(step.wait)
+		if err := d.Dispatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.take(); !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("step %d: published %v, want %v", i, got, step.want)
+		}
+	}
+}
+
+// TestDispatchersClaimEvents 多个实例同时投递时每个事件只投递一次，同一本书的事件保持顺序
+func TestDispatchersClaimEvents(t *testing.T) {
+	clk := clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := memory.NewStore(clk)
+	for i := 0; i < 50; i++ {
+		add(t, store, uint(i%5+1))
+	}
+
+	r := &recorder{}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		d := outbox.NewDispatcher(store.Outbox, clk, r)
+		d.BatchSize = 7
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := d.Dispatch(context.Background()); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	published := r.take()
+	seen := make(map[uint]bool)
+	last := make(map[uint]uint)
+	for _, id := range published {
+		if seen[id] {
+			t.Fatalf("event %d published twice", id)
+		}
+		seen[id] = true
+		book := (id-1)%5 + 1
+		if id < last[book] {
+			t.Fatalf("event %d of book %d published after event %d", id, book, last[book])
+		}
+		last[book] = id
+	}
+	if len(seen) != 50 {
+		t.Fatalf("published %d events, want 50", len(seen))
+	}
+}
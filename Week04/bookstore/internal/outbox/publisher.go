@@ -0,0 +1,29 @@
+package outbox
+
+import (
+	"context"
+	"log"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// Publisher 把 outbox 事件投递给下游。
+// 投递语义是至少一次，同一个事件可能被重复投递，下游需要用 event.ID 去重
+type Publisher interface {
+	Publish(ctx context.Context, event model.OutboxEvent) error
+}
+
+// PublisherFunc 让普通函数实现 Publisher
+type PublisherFunc func(ctx context.Context, event model.OutboxEvent) error
+
+func (f PublisherFunc) Publish(ctx context.Context, event model.OutboxEvent) error {
+	return f(ctx, event)
+}
+
+// LogPublisher 只把事件打印到日志，用于本地调试
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(ctx context.Context, event model.OutboxEvent) error {
+	log.Printf("outbox event %d: %s book=%d %s", event.ID, event.EventType, event.AggregateID, event.Payload)
+	return nil
+}
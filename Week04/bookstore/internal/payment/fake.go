@@ -0,0 +1,265 @@
+package payment
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 让 FakeGateway 给出特定结果的 source，其他 source 直接授权成功
+const (
+	SourceDecline           = "tok_decline"
+	SourceInsufficientFunds = "tok_insufficient_funds"
+	// SourceDelay 和 SourceDelayDecline 先返回 pending，Delay 之后通过回调给出结果
+	SourceDelay        = "tok_delay"
+	SourceDelayDecline = "tok_delay_decline"
+)
+
+// callbackAttempts 是每个回调最多尝试的次数，两次尝试之间的间隔从 500ms 开始翻倍
+const callbackAttempts = 5
+
+// FakeGateway 是完全在本地运行的网关，实现了 Gateway 调用的接口，供开发和测试使用。
+// 支付只保存在内存里，每次状态变化都会签名后回调 CallbackURL
+type FakeGateway struct {
+	APIKey        string
+	WebhookSecret string
+	CallbackURL   string
+	// Delay 是 SourceDelay 和 SourceDelayDecline 给出结果的时间
+	Delay time.Duration
+	// Latency 让每个接口都先等待一段时间，模拟慢网关
+	Latency time.Duration
+	Client  *http.Client
+
+	mu       sync.Mutex
+	payments map[string]Payment
+	// keys 把授权请求的 Idempotency-Key 映射到创建的支付 ID
+	keys map[string]string
+	wg   sync.WaitGroup
+	stop chan struct{}
+	once sync.Once
+}
+
+func NewFakeGateway(apiKey, secret, callbackURL string) *FakeGateway {
+	return &FakeGateway{
+		APIKey:        apiKey,
+		WebhookSecret: secret,
+		CallbackURL:   callbackURL,
+		Delay:         3 * time.Second,
+		Client:        &http.Client{Timeout: 10 * time.Second},
+		payments:      make(map[string]Payment),
+		keys:          make(map[string]string),
+		stop:          make(chan struct{}),
+	}
+}
+
+// Close 放弃还没给出的延迟结果和还在重试的回调
+func (f *FakeGateway) Close() {
+	f.once.Do(func() { close(f.stop) })
+	f.wg.Wait()
+}
+
+func (f *FakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.APIKey != "" && r.Header.Get("Authorization") != "Bearer "+f.APIKey {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid api key"})
+		return
+	}
+	if f.Latency > 0 {
+		select {
+		case <-time.After(f.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	// /v1/payments、/v1/payments/:id、/v1/payments/:id/capture、/v1/payments/:id/refund
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/payments"), "/"), "/")
+	switch {
+	case r.Method == http.MethodPost && parts[0] == "":
+		f.authorize(w, r)
+	case r.Method == http.MethodGet && len(parts) == 1:
+		f.mu.Lock()
+		p, ok := f.payments[parts[0]]
+		f.mu.Unlock()
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": ErrNotFound.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, p)
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "capture":
+		f.change(w, r, parts[0], StatusCaptured, StatusAuthorized)
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "refund":
+		f.change(w, r, parts[0], StatusRefunded, StatusAuthorized, StatusCaptured)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *FakeGateway) authorize(w http.ResponseWriter, r *http.Request) {
+	var req authorizeBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Amount <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payment request"})
+		return
+	}
+	p := Payment{
+		ID:        newID("pay_"),
+		Status:    StatusAuthorized,
+		Amount:    req.Amount,
+		Currency:  req.Currency,
+		Reference: req.Reference,
+	}
+	switch req.Source {
+	case SourceDecline:
+		p.Status, p.DeclineCode = StatusDeclined, "card_declined"
+	case SourceInsufficientFunds:
+		p.Status, p.DeclineCode = StatusDeclined, "insufficient_funds"
+	case SourceDelay, SourceDelayDecline:
+		p.Status = StatusPending
+	}
+
+	key := r.Header.Get(HeaderIdempotencyKey)
+	f.mu.Lock()
+	if id, ok := f.keys[key]; ok && key != "" {
+		// 重复的授权请求返回第一次创建的支付，不再冻结金额
+		existing := f.payments[id]
+		f.mu.Unlock()
+		writeJSON(w, http.StatusOK, existing)
+		return
+	}
+	if key != "" {
+		f.keys[key] = p.ID
+	}
+	f.payments[p.ID] = p
+	f.mu.Unlock()
+	if p.Status == StatusPending {
+		f.settleLater(p.ID, req.Source == SourceDelayDecline)
+	} else {
+		f.notify(p)
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+// settleLater 在 Delay 之后给出 pending 支付的结果
+func (f *FakeGateway) settleLater(id string, decline bool) {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		select {
+		case <-time.After(f.Delay):
+		case <-f.stop:
+			return
+		}
+		f.mu.Lock()
+		p := f.payments[id]
+		if decline {
+			p.Status, p.DeclineCode = StatusDeclined, "card_declined"
+		} else {
+			p.Status = StatusAuthorized
+		}
+		f.payments[id] = p
+		f.mu.Unlock()
+		f.notify(p)
+	}()
+}
+
+// change 把支付改为 to 状态，当前状态必须是 from 之一，请求中的金额不能超过支付金额
+func (f *FakeGateway) change(w http.ResponseWriter, r *http.Request, id, to string, from ...string) {
+	var req amountBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return
+	}
+
+	f.mu.Lock()
+	p, ok := f.payments[id]
+	if !ok {
+		f.mu.Unlock()
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": ErrNotFound.Error()})
+		return
+	}
+	allowed := false
+	for _, s := range from {
+		allowed = allowed || p.Status == s
+	}
+	if !allowed || req.Amount > p.Amount {
+		f.mu.Unlock()
+		writeJSON(w, http.StatusConflict, map[string]string{"error": ErrInvalidState.Error()})
+		return
+	}
+	p.Status = to
+	f.payments[id] = p
+	f.mu.Unlock()
+
+	f.notify(p)
+	writeJSON(w, http.StatusOK, p)
+}
+
+// notify 在后台把支付的新状态回调给 CallbackURL，失败时重试
+func (f *FakeGateway) notify(p Payment) {
+	if f.CallbackURL == "" {
+		return
+	}
+	body, err := json.Marshal(Event{ID: newID("evt_"), Type: "payment." + p.Status, Payment: p})
+	if err != nil {
+		log.Printf("fake payment gateway: marshal event err: %v", err)
+		return
+	}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		backoff := 500 * time.Millisecond
+		for i := 0; i < callbackAttempts; i++ {
+			if i > 0 {
+				select {
+				case <-time.After(backoff):
+					backoff *= 2
+				case <-f.stop:
+					return
+				}
+			}
+			if err := f.callback(body); err != nil {
+				log.Printf("fake payment gateway: callback %s attempt %d err: %v", p.ID, i+1, err)
+				continue
+			}
+			return
+		}
+	}()
+}
+
+func (f *FakeGateway) callback(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, f.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(f.WebhookSecret, body, time.Now()))
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("callback returned %s", resp.Status)
+	}
+	return nil
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return prefix + hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
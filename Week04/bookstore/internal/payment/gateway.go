@@ -0,0 +1,111 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+)
+
+// Gateway 调用网关的 HTTP 接口：
+//
+//	POST /v1/payments              授权
+//	POST /v1/payments/:id/capture  请款
+//	POST /v1/payments/:id/refund   退款
+//
+// 请求用 Authorization: Bearer <APIKey> 认证，授权请求用 Idempotency-Key 带上 Reference，
+// 回调用 WebhookSecret 校验签名
+type Gateway struct {
+	BaseURL       string
+	APIKey        string
+	WebhookSecret string
+	Client        *http.Client
+	clock         clock.Clock
+}
+
+func NewGateway(cfg config.Config, clk clock.Clock) *Gateway {
+	return &Gateway{
+		BaseURL:       cfg.PaymentURL,
+		APIKey:        cfg.PaymentAPIKey,
+		WebhookSecret: cfg.PaymentWebhookSecret,
+		Client:        &http.Client{Timeout: cfg.PaymentTimeout},
+		clock:         clk,
+	}
+}
+
+type authorizeBody struct {
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Reference string `json:"reference"`
+	Source    string `json:"source"`
+}
+
+type amountBody struct {
+	Amount int64 `json:"amount"`
+}
+
+func (g *Gateway) Authorize(ctx context.Context, req AuthorizeRequest) (Payment, error) {
+	return g.post(ctx, "/v1/payments", req.Reference, authorizeBody{
+		Amount:    req.Amount,
+		Currency:  req.Currency,
+		Reference: req.Reference,
+		Source:    req.Source,
+	})
+}
+
+func (g *Gateway) Capture(ctx context.Context, id string, amount int64) (Payment, error) {
+	return g.post(ctx, "/v1/payments/"+url.PathEscape(id)+"/capture", "", amountBody{Amount: amount})
+}
+
+func (g *Gateway) Refund(ctx context.Context, id string, amount int64) (Payment, error) {
+	return g.post(ctx, "/v1/payments/"+url.PathEscape(id)+"/refund", "", amountBody{Amount: amount})
+}
+
+func (g *Gateway) VerifyWebhook(header http.Header, body []byte) (Event, error) {
+	var event Event
+	if g.WebhookSecret == "" || !verify(g.WebhookSecret, body, header.Get(HeaderSignature), g.clock.Now()) {
+		return event, ErrInvalidSignature
+	}
+	err := json.Unmarshal(body, &event)
+	return event, err
+}
+
+// post 发送请求并解析返回的支付，idempotencyKey 不为空时放进 Idempotency-Key 头。
+// 404 返回 ErrNotFound，409 返回 ErrInvalidState
+func (g *Gateway) post(ctx context.Context, path, idempotencyKey string, v interface{}) (Payment, error) {
+	var p Payment
+	body, err := json.Marshal(v)
+	if err != nil {
+		return p, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return p, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+g.APIKey)
+	if idempotencyKey != "" {
+		req.Header.Set(HeaderIdempotencyKey, idempotencyKey)
+	}
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return p, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		err = json.NewDecoder(resp.Body).Decode(&p)
+		return p, err
+	case http.StatusNotFound:
+		return p, ErrNotFound
+	case http.StatusConflict:
+		return p, ErrInvalidState
+	}
+	return p, fmt.Errorf("payment: POST %s: %s", path, resp.Status)
+}
@@ -0,0 +1,46 @@
+package payment
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+)
+
+// localDelay 是本地网关给出延迟结果的时间
+const localDelay = 3 * time.Second
+
+// NewLocalGateway 在 127.0.0.1 的随机端口上启动 FakeGateway，返回连接它的 Gateway，
+// 回调发往 cfg.PaymentCallbackURL。没有配置 webhook secret 时使用固定值
+func NewLocalGateway(cfg config.Config, clk clock.Clock) (*Gateway, func(), error) {
+	secret := cfg.PaymentWebhookSecret
+	if secret == "" {
+		secret = "local"
+	}
+	fake := NewFakeGateway(cfg.PaymentAPIKey, secret, cfg.PaymentCallbackURL)
+	fake.Delay = localDelay
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	srv := &http.Server{Handler: fake}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("local payment gateway err: %v", err)
+		}
+	}()
+	log.Printf("local payment gateway listening on %s", ln.Addr())
+
+	g := NewGateway(cfg, clk)
+	g.BaseURL = "http://" + ln.Addr().String()
+	g.WebhookSecret = secret
+	cleanup := func() {
+		fake.Close()
+		srv.Close()
+	}
+	return g, cleanup, nil
+}
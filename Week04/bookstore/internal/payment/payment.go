@@ -0,0 +1,76 @@
+// Package payment 是支付网关的抽象。金额都以分为单位。
+// 线上通过 Gateway 调用网关的 HTTP 接口，本地运行时在进程内启动 FakeGateway，
+// 网关异步得出结果后会把事件回调给书店，书店按回调修正支付和订单的状态。
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/wire"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+)
+
+// ProviderSet 提供调用远程网关的 Gateway
+var ProviderSet = wire.NewSet(NewGateway, wire.Bind(new(Provider), new(*Gateway)))
+
+// FakeSet 在进程内启动 FakeGateway，用于本地运行
+var FakeSet = wire.NewSet(NewLocalGateway, wire.Bind(new(Provider), new(*Gateway)))
+
+// 支付状态。pending 表示网关还没有给出结果，结果通过回调送达
+const (
+	StatusPending    = "pending"
+	StatusAuthorized = "authorized"
+	StatusCaptured   = "captured"
+	StatusRefunded   = "refunded"
+	StatusDeclined   = "declined"
+)
+
+var (
+	// ErrNotFound 表示网关上没有这笔支付
+	ErrNotFound = errors.New("payment not found")
+	// ErrInvalidState 表示支付当前的状态不允许这个操作，例如对没有授权的支付请款
+	ErrInvalidState = i18n.NewError("payment.invalid_state")
+	// ErrInvalidSignature 表示回调的签名不正确或者已经过期
+	ErrInvalidSignature = i18n.NewError("payment.invalid_signature")
+)
+
+type AuthorizeRequest struct {
+	Amount   int64
+	Currency string
+	// Reference 是书店这边这次支付的唯一标识，网关原样带回。它同时是授权的幂等键：
+	// 同一个 Reference 重复授权时网关返回第一次创建的支付，不会重复冻结金额
+	Reference string
+	// Source 是客户端从网关拿到的支付凭证，例如卡的 token
+	Source string
+}
+
+type Payment struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Reference string `json:"reference"`
+	// DeclineCode 是拒绝的原因，只在 declined 时有值
+	DeclineCode string `json:"decline_code,omitempty"`
+}
+
+// Event 是网关回调的事件，Payment 是事件发生后支付的状态
+type Event struct {
+	ID      string  `json:"id"`
+	Type    string  `json:"type"`
+	Payment Payment `json:"payment"`
+}
+
+type Provider interface {
+	// Authorize 冻结金额，结果可能是 authorized、declined，或者 pending 等待回调
+	Authorize(ctx context.Context, req AuthorizeRequest) (Payment, error)
+	// Capture 对已授权的支付请款
+	Capture(ctx context.Context, id string, amount int64) (Payment, error)
+	// Refund 退还已授权或已请款的支付
+	Refund(ctx context.Context, id string, amount int64) (Payment, error)
+	// VerifyWebhook 校验回调的签名，返回回调中的事件
+	VerifyWebhook(header http.Header, body []byte) (Event, error)
+}
@@ -0,0 +1,56 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderSignature 是回调携带签名的头部，形如 t=<unix 秒>,v1=<hex>
+const HeaderSignature = "X-Payment-Signature"
+
+// HeaderIdempotencyKey 是授权请求携带幂等键的头部
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// signatureTolerance 是回调时间戳允许的偏差，超过的回调当作重放拒绝
+const signatureTolerance = 5 * time.Minute
+
+// Sign 对 "<时间戳>.<请求体>" 计算 HMAC-SHA256，时间戳一起签名，防止回调被重放
+func Sign(secret string, body []byte, now time.Time) string {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	return "t=" + ts + ",v1=" + mac(secret, ts, body)
+}
+
+func verify(secret string, body []byte, header string, now time.Time) bool {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sig = kv[1]
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return false
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > signatureTolerance || d < -signatureTolerance {
+		return false
+	}
+	return hmac.Equal([]byte(mac(secret, ts, body)), []byte(sig))
+}
+
+func mac(secret, ts string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(ts + "."))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,109 @@
+// Package pricing 根据优惠规则计算图书的实际售价。
+//
+// 规则先按适用范围、有效期和优惠码筛选，再按 Priority 从小到大依次作用在
+// 上一步的价格上。Exclusive 的规则不和其他规则叠加：如果它是第一条生效的规则，
+// 则只应用它并结束计算；如果前面已经有规则生效，则跳过它。价格最低为 0
+package pricing
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// Adjustment 是一条规则带来的优惠，购物车和订单里保存的是同一个类型
+type Adjustment = model.PriceAdjustment
+
+// Quote 是图书的报价明细
+type Quote struct {
+	BookID         uint
+	BasePrice      float32
+	EffectivePrice float32
+	Adjustments    []Adjustment
+}
+
+// Evaluate 计算 book 在 now 时刻使用优惠码 coupon 的报价，coupon 可以为空。
+// categoryPaths 是图书直接所属分类的 Path，分类规则对目标分类的整棵子树生效
+func Evaluate(book model.Book, categoryPaths []string, rules []model.PricingRule, now time.Time, coupon string) Quote {
+	applicable := make([]model.PricingRule, 0, len(rules))
+	for _, r := range rules {
+		if applies(r, book, categoryPaths, now, coupon) {
+			applicable = append(applicable, r)
+		}
+	}
+	sort.SliceStable(applicable, func(i, j int) bool {
+		return applicable[i].Priority < applicable[j].Priority
+	})
+
+	quote := Quote{BookID: book.ID, BasePrice: book.Price, Adjustments: []Adjustment{}}
+	price := float64(book.Price)
+	for _, r := range applicable {
+		if r.Exclusive && len(quote.Adjustments) > 0 {
+			continue
+		}
+		// 先把优惠金额舍入到分再扣减，各条优惠加起来正好等于原价和实际售价的差
+		discount := round(discountOf(r, price))
+		price -= discount
+		quote.Adjustments = append(quote.Adjustments, Adjustment{
+			RuleID:   r.ID,
+			Name:     r.Name,
+			Kind:     r.Kind,
+			Amount:   r.Amount,
+			Discount: float32(discount),
+		})
+		if r.Exclusive {
+			break
+		}
+	}
+	quote.EffectivePrice = float32(round(math.Max(price, 0)))
+	return quote
+}
+
+func applies(r model.PricingRule, book model.Book, categoryPaths []string, now time.Time, coupon string) bool {
+	if r.StartsAt != nil && now.Before(*r.StartsAt) {
+		return false
+	}
+	if r.EndsAt != nil && !now.Before(*r.EndsAt) {
+		return false
+	}
+	if r.CouponCode != "" && !strings.EqualFold(r.CouponCode, coupon) {
+		return false
+	}
+
+	switch r.Scope {
+	case model.ScopeAll:
+		return true
+	case model.ScopeBook:
+		return r.Target == strconv.FormatUint(uint64(book.ID), 10)
+	case model.ScopeAuthor:
+		return book.Author != "" && strings.EqualFold(r.Target, book.Author)
+	case model.ScopeCategory:
+		for _, path := range categoryPaths {
+			if strings.Contains(path, "/"+r.Target+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// discountOf 返回规则在当前价格上的优惠金额，不会让价格低于 0
+func discountOf(r model.PricingRule, price float64) float64 {
+	var discount float64
+	switch r.Kind {
+	case model.DiscountPercentage:
+		discount = price * float64(r.Amount) / 100
+	case model.DiscountFixed:
+		discount = float64(r.Amount)
+	}
+	return math.Max(0, math.Min(discount, price))
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,133 @@
+package pricing_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/pricing"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	book := model.Book{Model: gorm.Model{ID: 7}, Author: "Rob Pike", Price: 100}
+
+	// rule 生成一条全场规则，按需要修改其他字段
+	rule := func(id uint, kind string, amount float32, priority int) model.PricingRule {
+		return model.PricingRule{Model: gorm.Model{ID: id}, Kind: kind, Amount: amount, Scope: model.ScopeAll, Priority: priority}
+	}
+	percent := func(id uint, amount float32, priority int) model.PricingRule {
+		return rule(id, model.DiscountPercentage, amount, priority)
+	}
+	fixed := func(id uint, amount float32, priority int) model.PricingRule {
+		return rule(id, model.DiscountFixed, amount, priority)
+	}
+	exclusive := func(r model.PricingRule) model.PricingRule {
+		r.Exclusive = true
+		return r
+	}
+	scoped := func(r model.PricingRule, scope, target string) model.PricingRule {
+		r.Scope, r.Target = scope, target
+		return r
+	}
+	withCoupon := func(r model.PricingRule, coupon string) model.PricingRule {
+		r.CouponCode = coupon
+		return r
+	}
+	during := func(r model.PricingRule, startsAt, endsAt *time.Time) model.PricingRule {
+		r.StartsAt, r.EndsAt = startsAt, endsAt
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		rules   []model.PricingRule
+		coupon  string
+		price   float32
+		applied []uint
+	}{
+		{name: "no rules", price: 100, applied: nil},
+		{name: "stacking follows priority", rules: []model.PricingRule{fixed(2, 5, 2), percent(1, 10, 1)},
+			price: 85, applied: []uint{1, 2}},
+		{name: "stacking order changes the result", rules: []model.PricingRule{fixed(2, 5, 1), percent(1, 10, 2)},
+			price: 85.5, applied: []uint{2, 1}},
+		{name: "equal priority keeps rule order", rules: []model.PricingRule{fixed(2, 5, 1), percent(1, 10, 1)},
+			price: 85.5, applied: []uint{2, 1}},
+		{name: "exclusive first stops evaluation", rules: []model.PricingRule{exclusive(percent(1, 20, 1)), fixed(2, 5, 2)},
+			price: 80, applied: []uint{1}},
+		{name: "exclusive after another rule is skipped", rules: []model.PricingRule{fixed(1, 5, 1), exclusive(percent(2, 50, 2)), fixed(3, 5, 3)},
+			price: 90, applied: []uint{1, 3}},
+		{name: "price does not go below zero", rules: []model.PricingRule{fixed(1, 150, 1), fixed(2, 10, 2)},
+			price: 0, applied: []uint{1, 2}},
+		{name: "coupon rule needs the coupon", rules: []model.PricingRule{withCoupon(fixed(1, 5, 1), "SPRING5")},
+			price: 100, applied: nil},
+		{name: "coupon matches case insensitively", rules: []model.PricingRule{withCoupon(fixed(1, 5, 1), "SPRING5")},
+			coupon: "spring5", price: 95, applied: []uint{1}},
+		{name: "wrong coupon", rules: []model.PricingRule{withCoupon(fixed(1, 5, 1), "SPRING5")},
+			coupon: "SUMMER", price: 100, applied: nil},
+		{name: "coupon does not disable other rules", rules: []model.PricingRule{percent(1, 10, 1), withCoupon(fixed(2, 5, 2), "SPRING5")},
+			coupon: "SPRING5", price: 85, applied: []uint{1, 2}},
+		{name: "not started", rules: []model.PricingRule{during(fixed(1, 5, 1), &after, nil)},
+			price: 100, applied: nil},
+		{name: "ended", rules: []model.PricingRule{during(fixed(1, 5, 1), nil, &now)},
+			price: 100, applied: nil},
+		{name: "active", rules: []model.PricingRule{during(fixed(1, 5, 1), &before, &after)},
+			price: 95, applied: []uint{1}},
+		{name: "book scope", rules: []model.PricingRule{scoped(fixed(1, 5, 1), model.ScopeBook, "7"), scoped(fixed(2, 5, 1), model.ScopeBook, "8")},
+			price: 95, applied: []uint{1}},
+		{name: "author scope ignores case", rules: []model.PricingRule{scoped(fixed(1, 5, 1), model.ScopeAuthor, "rob pike")},
+			price: 95, applied: []uint{1}},
+		{name: "category scope matches the subtree", paths: []string{"/1/4/"},
+			rules: []model.PricingRule{scoped(fixed(1, 5, 1), model.ScopeCategory, "1"), scoped(fixed(2, 5, 2), model.ScopeCategory, "4")},
+			price: 90, applied: []uint{1, 2}},
+		{name: "category scope matches whole ids", paths: []string{"/14/", "/2/41/"},
+			rules: []model.PricingRule{scoped(fixed(1, 5, 1), model.ScopeCategory, "1"), scoped(fixed(2, 5, 1), model.ScopeCategory, "4")},
+			price: 100, applied: nil},
+		{name: "category scope without categories", rules: []model.PricingRule{scoped(fixed(1, 5, 1), model.ScopeCategory, "1")},
+			price: 100, applied: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := pricing.Evaluate(book, tt.paths, tt.rules, now, tt.coupon)
+			if quote.BookID != book.ID || quote.BasePrice != book.Price {
+				t.Fatalf("Evaluate returned %+v", quote)
+			}
+			if quote.EffectivePrice != tt.price {
+				t.Fatalf("EffectivePrice = %v, want %v", quote.EffectivePrice, tt.price)
+			}
+			var applied []uint
+			for _, a := range quote.Adjustments {
+				applied = append(applied, a.RuleID)
+			}
+			if !reflect.DeepEqual(applied, tt.applied) {
+				t.Fatalf("applied rules = %v, want %v", applied, tt.applied)
+			}
+		})
+	}
+}
+
+func TestEvaluateAdjustments(t *testing.T) {
+	book := model.Book{Model: gorm.Model{ID: 1}, Price: 59.9}
+	rules := []model.PricingRule{
+		{Model: gorm.Model{ID: 1}, Name: "percent", Kind: model.DiscountPercentage, Amount: 15, Scope: model.ScopeAll, Priority: 1},
+		{Model: gorm.Model{ID: 2}, Name: "fixed", Kind: model.DiscountFixed, Amount: 60, Scope: model.ScopeAll, Priority: 2},
+	}
+	quote := pricing.Evaluate(book, nil, rules, time.Now(), "")
+
+	// 每条优惠记录的是作用在上一步价格上的金额，四舍五入到分，最后一条不会超过剩余价格
+	want := []pricing.Adjustment{
+		{RuleID: 1, Name: "percent", Kind: model.DiscountPercentage, Amount: 15, Discount: 8.99},
+		{RuleID: 2, Name: "fixed", Kind: model.DiscountFixed, Amount: 60, Discount: 50.91},
+	}
+	if !reflect.DeepEqual(quote.Adjustments, want) {
+		t.Fatalf("Adjustments = %+v, want %+v", quote.Adjustments, want)
+	}
+	if quote.EffectivePrice != 0 {
+		t.Fatalf("EffectivePrice = %v, want 0", quote.EffectivePrice)
+	}
+}
@@ -1,8 +1,10 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
@@ -10,40 +12,160 @@ import (
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
 )
 
+// BookRepository 的所有方法都限定在 ctx 中的店铺内，ctx 中没有店铺时返回 tenant.ErrNoTenant
 type BookRepository interface {
-	GetAll() []model.Book
-	GetByID(id uint) model.Book
-	Save(book model.Book) model.Book
-	Delete(book model.Book)
+	GetAll(ctx context.Context) ([]model.Book, error)
+	GetByID(ctx context.Context, id uint) (model.Book, error)
+	// GetForUpdate 在事务中读取图书并锁住这一行，直到事务结束。
+	// 修改图书的事务先锁住图书再计算变更记录的版本号，并发修改同一本书时不会算出同一个版本
+	GetForUpdate(ctx context.Context, id uint) (model.Book, error)
+	// ListAfter 按 ID 升序返回 ID 大于 afterID 的至多 limit 本图书
+	ListAfter(ctx context.Context, afterID uint, limit int) ([]model.Book, error)
+	Count(ctx context.Context) (int, error)
+	// LastModified 返回店铺内图书最后一次新增、修改或删除的时间，没有图书时返回零值
+	LastModified(ctx context.Context) (time.Time, error)
+	// Save 不修改评分和封面，它们分别只通过 AddRating 和 SetCover 修改
+	Save(ctx context.Context, book model.Book) (model.Book, error)
+	Delete(ctx context.Context, book model.Book) error
+	// AddRating 原子地调整图书的评分总和与条数，不修改 UpdatedAt 以外的其他字段
+	AddRating(ctx context.Context, id uint, sum, count int) error
+	// SetCover 只修改封面格式和封面更新时间，返回修改前的封面格式。
+	// 在事务中调用时图书行被锁住，并发上传的封面不会读到同一个旧格式
+	SetCover(ctx context.Context, id uint, coverType string, updatedAt *time.Time) (string, error)
 }
 
 type bookRepository struct {
-	db *gorm.DB
+	db *Cluster
 }
 
-func NewBookRepository(db *gorm.DB) BookRepository {
+func NewBookRepository(db *Cluster) BookRepository {
 	return &bookRepository{db: db}
 }
 
-func (b *bookRepository) GetAll() []model.Book {
+// reader 和 writer 返回限定在当前店铺内的查询，分别走只读副本和主库
+func (b *bookRepository) reader(ctx context.Context) (*gorm.DB, uint, error) {
+	return scoped(ctx, b.db.Reader(ctx))
+}
+
+func (b *bookRepository) writer(ctx context.Context) (*gorm.DB, uint, error) {
+	return scoped(ctx, b.db.Writer(ctx))
+}
+
+func (b *bookRepository) GetAll(ctx context.Context) ([]model.Book, error) {
+	db, _, err := b.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var books []model.Book
-	b.db.Find(&books)
-	return books
+	err = db.Find(&books).Error
+	return books, err
+}
+
+func (b *bookRepository) GetByID(ctx context.Context, id uint) (model.Book, error) {
+	var book model.Book
+	db, _, err := b.reader(ctx)
+	if err != nil {
+		return book, err
+	}
+	err = db.First(&book, id).Error
+	return book, translateError(err)
 }
 
-func (b *bookRepository) GetByID(id uint) model.Book {
+func (b *bookRepository) GetForUpdate(ctx context.Context, id uint) (model.Book, error) {
 	var book model.Book
-	b.db.First(&book, id)
-	return book
+	db, _, err := b.writer(ctx)
+	if err != nil {
+		return book, err
+	}
+	err = db.Set("gorm:query_option", "FOR UPDATE").First(&book, id).Error
+	return book, translateError(err)
+}
+
+func (b *bookRepository) ListAfter(ctx context.Context, afterID uint, limit int) ([]model.Book, error) {
+	db, _, err := b.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var books []model.Book
+	err = db.Where("id > ?", afterID).Order("id").Limit(limit).Find(&books).Error
+	return books, err
 }
 
-func (b *bookRepository) Save(book model.Book) model.Book {
+func (b *bookRepository) Count(ctx context.Context) (int, error) {
+	db, _, err := b.reader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = db.Model(&model.Book{}).Count(&count).Error
+	return count, err
+}
+
+func (b *bookRepository) LastModified(ctx context.Context) (time.Time, error) {
+	db, _, err := b.reader(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// 软删除只更新 deleted_at，需要把已删除的图书也算进来
+	var last *time.Time
+	err = db.Unscoped().Model(&model.Book{}).
+		Select("MAX(GREATEST(updated_at, COALESCE(deleted_at, updated_at)))").Row().Scan(&last)
+	if err != nil || last == nil {
+		return time.Time{}, err
+	}
+	return *last, nil
+}
+
+func (b *bookRepository) Save(ctx context.Context, book model.Book) (model.Book, error) {
 	log.Println(book)
-	b.db.Save(&book)
-	return book
+	db, tenantID, err := b.writer(ctx)
+	if err != nil {
+		return book, err
+	}
+	// 更新时先确认这本书属于当前店铺，gorm 的 Save 在没有更新到行时会退化成 FirstOrCreate
+	if book.ID != 0 {
+		if err := db.First(&model.Book{}, book.ID).Error; err != nil {
+			return book, translateError(err)
+		}
+	}
+	book.TenantID = tenantID
+	// 评分和封面只通过 AddRating、SetCover 修改，避免用读出来的旧值覆盖并发的更新
+	err = db.Omit("rating_sum", "rating_count", "cover_type", "cover_updated_at").Save(&book).Error
+	return book, err
 }
 
-func (b *bookRepository) Delete(book model.Book) {
+func (b *bookRepository) Delete(ctx context.Context, book model.Book) error {
 	fmt.Println(book)
-	b.db.Delete(&book)
+	db, _, err := b.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Delete(&book).Error
+}
+
+func (b *bookRepository) AddRating(ctx context.Context, id uint, sum, count int) error {
+	db, _, err := b.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Model(&model.Book{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"rating_sum":   gorm.Expr("rating_sum + ?", sum),
+		"rating_count": gorm.Expr("rating_count + ?", count),
+	}).Error
+}
+
+func (b *bookRepository) SetCover(ctx context.Context, id uint, coverType string, updatedAt *time.Time) (string, error) {
+	db, _, err := b.writer(ctx)
+	if err != nil {
+		return "", err
+	}
+	var book model.Book
+	if err := db.Set("gorm:query_option", "FOR UPDATE").Select("id, cover_type").First(&book, id).Error; err != nil {
+		return "", translateError(err)
+	}
+	err = db.Model(&model.Book{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"cover_type":       coverType,
+		"cover_updated_at": updatedAt,
+	}).Error
+	return book.CoverType, err
 }
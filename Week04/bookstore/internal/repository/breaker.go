@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/breaker"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// NewBreaker 创建保护数据库访问的熔断器，只有说明数据库不可用的错误才计入错误率
+func NewBreaker(cfg config.Config, clk clock.Clock) *breaker.Breaker {
+	return breaker.New("mysql", breaker.Settings{
+		Window:       cfg.BreakerWindow,
+		ErrorPercent: cfg.BreakerErrorPercent,
+		MinRequests:  cfg.BreakerMinRequests,
+		SleepWindow:  cfg.BreakerSleepWindow,
+		Timeout:      cfg.BreakerTimeout,
+		IsFailure:    isUnavailable,
+	}, clk)
+}
+
+// isUnavailable 判断错误是否说明数据库不可用，查不到记录、违反约束这类业务错误不算
+func isUnavailable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1040, // Too many connections
+			1053, // Server shutdown in progress
+			1205, // Lock wait timeout exceeded
+			3024: // Query execution was interrupted, maximum statement execution time exceeded
+			return true
+		}
+	}
+	return false
+}
+
+// NewBookRepositoryWithBreaker 返回经过熔断器保护的图书仓储，熔断器打开时直接返回 *breaker.OpenError
+func NewBookRepositoryWithBreaker(db *Cluster, b *breaker.Breaker) BookRepository {
+	return &breakerBookRepository{next: NewBookRepository(db), breaker: b}
+}
+
+// breakerBookRepository 只在 Do 返回 nil 时读取 fn 写入的结果，超时返回后 fn 可能还在执行
+type breakerBookRepository struct {
+	next    BookRepository
+	breaker *breaker.Breaker
+}
+
+func (r *breakerBookRepository) GetAll(ctx context.Context) ([]model.Book, error) {
+	var books []model.Book
+	err := r.breaker.Do(ctx, func(ctx context.Context) (err error) {
+		books, err = r.next.GetAll(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+func (r *breakerBookRepository) GetByID(ctx context.Context, id uint) (model.Book, error) {
+	var book model.Book
+	err := r.breaker.Do(ctx, func(ctx context.Context) (err error) {
+		book, err = r.next.GetByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return model.Book{}, err
+	}
+	return book, nil
+}
+
+// GetForUpdate 只在事务中使用，事务本身已经受熔断器保护
+func (r *breakerBookRepository) GetForUpdate(ctx context.Context, id uint) (model.Book, error) {
+	return r.next.GetForUpdate(ctx, id)
+}
+
+func (r *breakerBookRepository) ListAfter(ctx context.Context, afterID uint, limit int) ([]model.Book, error) {
+	var books []model.Book
+	err := r.breaker.Do(ctx, func(ctx context.Context) (err error) {
+		books, err = r.next.ListAfter(ctx, afterID, limit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+func (r *breakerBookRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	err := r.breaker.Do(ctx, func(ctx context.Context) (err error) {
+		count, err = r.next.Count(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *breakerBookRepository) LastModified(ctx context.Context) (time.Time, error) {
+	var last time.Time
+	err := r.breaker.Do(ctx, func(ctx context.Context) (err error) {
+		last, err = r.next.LastModified(ctx)
+		return err
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return last, nil
+}
+
+func (r *breakerBookRepository) Save(ctx context.Context, book model.Book) (model.Book, error) {
+	var saved model.Book
+	err := r.breaker.Do(ctx, func(ctx context.Context) (err error) {
+		saved, err = r.next.Save(ctx, book)
+		return err
+	})
+	if err != nil {
+		return book, err
+	}
+	return saved, nil
+}
+
+func (r *breakerBookRepository) Delete(ctx context.Context, book model.Book) error {
+	return r.breaker.Do(ctx, func(ctx context.Context) error {
+		return r.next.Delete(ctx, book)
+	})
+}
+
+func (r *breakerBookRepository) AddRating(ctx context.Context, id uint, sum, count int) error {
+	return r.breaker.Do(ctx, func(ctx context.Context) error {
+		return r.next.AddRating(ctx, id, sum, count)
+	})
+}
+
+func (r *breakerBookRepository) SetCover(ctx context.Context, id uint, coverType string, updatedAt *time.Time) (string, error) {
+	var old string
+	err := r.breaker.Do(ctx, func(ctx context.Context) (err error) {
+		old, err = r.next.SetCover(ctx, id, coverType, updatedAt)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return old, nil
+}
+
+// NewTenantRepositoryWithBreaker 返回经过熔断器保护的店铺仓储，每个请求解析店铺时都会查询它
+func NewTenantRepositoryWithBreaker(db *gorm.DB, b *breaker.Breaker) TenantRepository {
+	return &breakerTenantRepository{next: NewTenantRepository(db), breaker: b}
+}
+
+type breakerTenantRepository struct {
+	next    TenantRepository
+	breaker *breaker.Breaker
+}
+
+func (r *breakerTenantRepository) GetAll() ([]model.Tenant, error) {
+	var tenants []model.Tenant
+	err := r.breaker.Do(context.Background(), func(ctx context.Context) (err error) {
+		tenants, err = r.next.GetAll()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+func (r *breakerTenantRepository) GetByID(id uint) (model.Tenant, error) {
+	var t model.Tenant
+	err := r.breaker.Do(context.Background(), func(ctx context.Context) (err error) {
+		t, err = r.next.GetByID(id)
+		return err
+	})
+	if err != nil {
+		return model.Tenant{}, err
+	}
+	return t, nil
+}
+
+// GetForUpdate 只在事务中使用，事务本身已经受熔断器保护
+func (r *breakerTenantRepository) GetForUpdate(id uint) (model.Tenant, error) {
+	return r.next.GetForUpdate(id)
+}
+
+func (r *breakerTenantRepository) GetBySlug(slug string) (model.Tenant, error) {
+	var t model.Tenant
+	err := r.breaker.Do(context.Background(), func(ctx context.Context) (err error) {
+		t, err = r.next.GetBySlug(slug)
+		return err
+	})
+	if err != nil {
+		return model.Tenant{}, err
+	}
+	return t, nil
+}
+
+func (r *breakerTenantRepository) Save(t model.Tenant) (model.Tenant, error) {
+	var saved model.Tenant
+	err := r.breaker.Do(context.Background(), func(ctx context.Context) (err error) {
+		saved, err = r.next.Save(t)
+		return err
+	})
+	if err != nil {
+		return t, err
+	}
+	return saved, nil
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// ErrInvalidParent 表示父分类不存在，或者要把分类移动到它自己的子树下
+var ErrInvalidParent = i18n.NewError("category.invalid_parent")
+
+// CategoryRepository 存取分类树和图书的分类，所有方法都限定在 ctx 中的店铺内
+type CategoryRepository interface {
+	GetByID(ctx context.Context, id uint) (model.Category, error)
+	// GetByIDs 按 Path 的长度，也就是从根到叶的顺序返回分类，不存在的 ID 被忽略
+	GetByIDs(ctx context.Context, ids []uint) ([]model.Category, error)
+	// Children 按名字返回直接子分类，parentID 为 0 时返回根分类
+	Children(ctx context.Context, parentID uint) ([]model.Category, error)
+	// Create 在 c.ParentID 下创建分类，父分类在事务中被锁住，不存在时返回 ErrInvalidParent
+	Create(ctx context.Context, c model.Category) (model.Category, error)
+	// Update 在一个事务里锁住分类和新的父分类，重命名分类并把它移动到 parentID 下，
+	// 整棵子树的 Path 跟着更新。新的父分类不存在或者在分类自己的子树下时返回 ErrInvalidParent
+	Update(ctx context.Context, id uint, name string, parentID uint) (model.Category, error)
+	// Delete 删除分类和它与图书的关系
+	Delete(ctx context.Context, id uint) error
+	// BookCounts 返回每个分类整棵子树中未删除的图书数
+	BookCounts(ctx context.Context, ids []uint) (map[uint]int, error)
+	// BookIDs 返回属于任意一个分类子树的图书 ID，可能包括已删除的图书
+	BookIDs(ctx context.Context, categoryIDs []uint) ([]uint, error)
+	// BookPaths 返回每本图书直接所属分类的 Path，没有分类的图书不在结果里
+	BookPaths(ctx context.Context, bookIDs []uint) (map[uint][]string, error)
+	// BookCategories 按 Path 返回图书直接所属的分类
+	BookCategories(ctx context.Context, bookID uint) ([]model.Category, error)
+	// SetBookCategories 把图书的分类替换为 categoryIDs
+	SetBookCategories(ctx context.Context, bookID uint, categoryIDs []uint) error
+}
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+func (r *categoryRepository) GetByID(ctx context.Context, id uint) (model.Category, error) {
+	var c model.Category
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return c, err
+	}
+	err = db.First(&c, id).Error
+	return c, translateError(err)
+}
+
+func (r *categoryRepository) GetByIDs(ctx context.Context, ids []uint) ([]model.Category, error) {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	var cs []model.Category
+	err = db.Where("id IN (?)", ids).Order("LENGTH(path)").Find(&cs).Error
+	return cs, err
+}
+
+func (r *categoryRepository) Children(ctx context.Context, parentID uint) ([]model.Category, error) {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	var cs []model.Category
+	err = db.Where("parent_id = ?", parentID).Order("name").Find(&cs).Error
+	return cs, err
+}
+
+func (r *categoryRepository) Create(ctx context.Context, c model.Category) (model.Category, error) {
+	_, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return c, err
+	}
+	c.TenantID = tenantID
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockCategories(tx, tenantID, c.ParentID)
+		if err != nil {
+			return err
+		}
+		if c.Path, err = parentPath(locked, c.ParentID); err != nil {
+			return err
+		}
+		if err := tx.Create(&c).Error; err != nil {
+			return err
+		}
+		c.Path = c.Path + uintString(c.ID) + "/"
+		return tx.Model(&c).Update("path", c.Path).Error
+	})
+	if isDuplicateEntry(err) {
+		return c, ErrDuplicate
+	}
+	return c, err
+}
+
+func (r *categoryRepository) Update(ctx context.Context, id uint, name string, parentID uint) (model.Category, error) {
+	_, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return model.Category{}, err
+	}
+	var c model.Category
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockCategories(tx, tenantID, id, parentID)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if c, ok = locked[id]; !ok {
+			return ErrNotFound
+		}
+		oldPath := c.Path
+		c.Name = name
+		if parentID != c.ParentID {
+			prefix, err := parentPath(locked, parentID)
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(prefix, oldPath) {
+				return ErrInvalidParent
+			}
+			c.ParentID = parentID
+			c.Path = prefix + uintString(id) + "/"
+		}
+
+		err = tx.Model(&c).Where("tenant_id = ?", tenantID).
+			Updates(map[string]interface{}{"name": c.Name, "parent_id": c.ParentID, "path": c.Path}).Error
+		if err != nil || c.Path == oldPath {
+			return err
+		}
+		// 子树中的 Path 都以 oldPath 开头，把这个前缀换成新的 Path
+		return tx.Exec(`UPDATE categories SET path = CONCAT(?, SUBSTRING(path, ?))
+			WHERE tenant_id = ? AND path LIKE ? AND id <> ?`,
+			c.Path, len(oldPath)+1, tenantID, oldPath+"%", c.ID).Error
+	})
+	if isDuplicateEntry(err) {
+		return c, ErrDuplicate
+	}
+	if err != nil {
+		return c, err
+	}
+	return r.GetByID(ctx, c.ID)
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, id uint) error {
+	_, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND category_id = ?", tenantID, id).Delete(&model.BookCategory{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("tenant_id = ? AND id = ?", tenantID, id).Delete(&model.Category{}).Error
+	})
+}
+
+// subtree 是分类和它所有后代的连接，d 是子树中的分类
+const subtree = `categories c JOIN categories d ON d.tenant_id = c.tenant_id AND d.path LIKE CONCAT(c.path, '%')
+	JOIN book_categories bc ON bc.category_id = d.id`
+
+func (r *categoryRepository) BookCounts(ctx context.Context, ids []uint) (map[uint]int, error) {
+	_, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		ID        uint
+		BookCount int
+	}
+	err = r.db.Raw(`SELECT c.id, COUNT(DISTINCT bc.book_id) AS book_count FROM `+subtree+`
+		JOIN books b ON b.id = bc.book_id AND b.deleted_at IS NULL
+		WHERE c.tenant_id = ? AND c.id IN (?) GROUP BY c.id`, tenantID, ids).Scan(&rows).Error
+	for _, row := range rows {
+		counts[row.ID] = row.BookCount
+	}
+	return counts, err
+}
+
+func (r *categoryRepository) BookIDs(ctx context.Context, categoryIDs []uint) ([]uint, error) {
+	_, tenantID, err := scoped(ctx, r.db)
+	if err != nil || len(categoryIDs) == 0 {
+		return nil, err
+	}
+	var rows []struct{ BookID uint }
+	err = r.db.Raw(`SELECT DISTINCT bc.book_id FROM `+subtree+`
+		WHERE c.tenant_id = ? AND c.id IN (?)`, tenantID, categoryIDs).Scan(&rows).Error
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.BookID
+	}
+	return ids, err
+}
+
+func (r *categoryRepository) BookPaths(ctx context.Context, bookIDs []uint) (map[uint][]string, error) {
+	_, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[uint][]string)
+	if len(bookIDs) == 0 {
+		return paths, nil
+	}
+	var rows []struct {
+		BookID uint
+		Path   string
+	}
+	err = r.db.Raw(`SELECT bc.book_id, c.path FROM book_categories bc JOIN categories c ON c.id = bc.category_id
+		WHERE bc.tenant_id = ? AND bc.book_id IN (?)`, tenantID, bookIDs).Scan(&rows).Error
+	for _, row := range rows {
+		paths[row.BookID] = append(paths[row.BookID], row.Path)
+	}
+	return paths, err
+}
+
+func (r *categoryRepository) BookCategories(ctx context.Context, bookID uint) ([]model.Category, error) {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	var cs []model.Category
+	err = db.Joins("JOIN book_categories bc ON bc.category_id = categories.id").
+		Where("bc.book_id = ?", bookID).Order("categories.path").Find(&cs).Error
+	return cs, err
+}
+
+func (r *categoryRepository) SetBookCategories(ctx context.Context, bookID uint, categoryIDs []uint) error {
+	_, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND book_id = ?", tenantID, bookID).Delete(&model.BookCategory{}).Error; err != nil {
+			return err
+		}
+		for _, id := range categoryIDs {
+			if err := tx.Create(&model.BookCategory{BookID: bookID, CategoryID: id, TenantID: tenantID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// lockCategories 按 ID 的顺序锁住店铺内的分类，并发移动时加锁顺序一致，ID 为 0 的根被忽略
+func lockCategories(tx *gorm.DB, tenantID uint, ids ...uint) (map[uint]model.Category, error) {
+	var cs []model.Category
+	err := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("tenant_id = ? AND id IN (?)", tenantID, ids).Order("id").Find(&cs).Error
+	if err != nil {
+		return nil, err
+	}
+	locked := make(map[uint]model.Category, len(cs))
+	for _, c := range cs {
+		locked[c.ID] = c
+	}
+	return locked, nil
+}
+
+// parentPath 返回父分类下新分类的 Path 前缀，parentID 为 0 时是根
+func parentPath(locked map[uint]model.Category, parentID uint) (string, error) {
+	if parentID == 0 {
+		return "/", nil
+	}
+	parent, ok := locked[parentID]
+	if !ok {
+		return "", ErrInvalidParent
+	}
+	return parent.Path, nil
+}
+
+func uintString(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
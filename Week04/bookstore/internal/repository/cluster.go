@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+// Cluster 把写操作发到主库，读操作轮询发到健康的只读副本，没有健康的副本时退回主库。
+// 目前只有方法带 ctx 的仓储（图书、优惠规则）会读副本，其它仓储的读写都走主库
+type Cluster struct {
+	primary  *gorm.DB
+	replicas []*replica
+	next     uint32
+}
+
+type replica struct {
+	dsn     string
+	db      *gorm.DB
+	healthy int32
+}
+
+// NewCluster 打开配置的只读副本并定期检查它们的健康状况，cleanup 停止检查并关闭副本连接
+func NewCluster(cfg config.Config, primary *gorm.DB, clk clock.Clock) (*Cluster, func(), error) {
+	c := &Cluster{primary: primary}
+	closeReplicas := func() {
+		for _, r := range c.replicas {
+			if err := r.db.Close(); err != nil {
+				log.Printf("close replica err: %v", err)
+			}
+		}
+	}
+	for _, dsn := range cfg.ReplicaDSNs {
+		db, err := gorm.Open("mysql", dsn)
+		if err != nil {
+			closeReplicas()
+			return nil, nil, err
+		}
+		db.SetNowFuncOverride(clk.Now)
+		c.replicas = append(c.replicas, &replica{dsn: dsn, db: db, healthy: 1})
+	}
+	if len(c.replicas) == 0 {
+		return c, func() {}, nil
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.checkHealth(cfg.ReplicaCheckInterval, stop)
+	}()
+	cleanup := func() {
+		close(stop)
+		wg.Wait()
+		closeReplicas()
+	}
+	return c, cleanup, nil
+}
+
+// singleDB 让所有读写都走同一个连接，用于事务
+func singleDB(db *gorm.DB) *Cluster {
+	return &Cluster{primary: db}
+}
+
+// Writer 返回主库，并在开启了 read-your-writes 的请求里把后续的读也固定到主库
+func (c *Cluster) Writer(ctx context.Context) *gorm.DB {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		atomic.StoreInt32(&s.wrote, 1)
+	}
+	return c.primary
+}
+
+// Reader 返回一个健康的只读副本，请求已经写过主库或者没有健康的副本时返回主库
+func (c *Cluster) Reader(ctx context.Context) *gorm.DB {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok && atomic.LoadInt32(&s.wrote) == 1 {
+		return c.primary
+	}
+	n := len(c.replicas)
+	start := int(atomic.AddUint32(&c.next, 1))
+	for i := 0; i < n; i++ {
+		r := c.replicas[(start+i)%n]
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			return r.db
+		}
+	}
+	return c.primary
+}
+
+func (c *Cluster) checkHealth(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		for _, r := range c.replicas {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := r.db.DB().PingContext(ctx)
+			cancel()
+
+			healthy := int32(1)
+			if err != nil {
+				healthy = 0
+			}
+			if atomic.SwapInt32(&r.healthy, healthy) != healthy {
+				log.Printf("replica %d healthy=%v err=%v", c.index(r), healthy == 1, err)
+			}
+		}
+	}
+}
+
+// index 返回副本的序号，日志里不打印 DSN，避免泄露密码
+func (c *Cluster) index(r *replica) int {
+	for i, other := range c.replicas {
+		if other == r {
+			return i
+		}
+	}
+	return -1
+}
+
+type sessionKey struct{}
+
+type session struct {
+	wrote int32
+}
+
+// WithReadYourWrites 开启 read-your-writes：同一个 ctx 写过主库之后，后续的读也走主库
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// scoped 把查询限定在 ctx 中的店铺内
+func scoped(ctx context.Context, db *gorm.DB) (*gorm.DB, uint, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, 0, tenant.ErrNoTenant
+	}
+	return db.Where("tenant_id = ?", t.ID), t.ID, nil
+}
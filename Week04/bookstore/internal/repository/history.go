@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type HistoryRepository interface {
+	Add(rev model.BookRevision) error
+	LatestVersion(bookID uint) (int, error)
+	ListByBook(bookID uint) ([]model.BookRevision, error)
+	// ListByBooks 一次查询多本书的变更记录，按图书和版本排序
+	ListByBooks(bookIDs []uint) ([]model.BookRevision, error)
+	GetVersion(bookID uint, version int) (model.BookRevision, error)
+}
+
+type historyRepository struct {
+	db *gorm.DB
+}
+
+func NewHistoryRepository(db *gorm.DB) HistoryRepository {
+	return &historyRepository{db: db}
+}
+
+func (h *historyRepository) Add(rev model.BookRevision) error {
+	return h.db.Create(&rev).Error
+}
+
+func (h *historyRepository) LatestVersion(bookID uint) (int, error) {
+	var version struct{ Version int }
+	err := h.db.Model(&model.BookRevision{}).Select("COALESCE(MAX(version), 0) AS version").
+		Where("book_id = ?", bookID).Scan(&version).Error
+	return version.Version, err
+}
+
+func (h *historyRepository) ListByBook(bookID uint) ([]model.BookRevision, error) {
+	var revs []model.BookRevision
+	err := h.db.Where("book_id = ?", bookID).Order("version").Find(&revs).Error
+	return revs, err
+}
+
+func (h *historyRepository) ListByBooks(bookIDs []uint) ([]model.BookRevision, error) {
+	var revs []model.BookRevision
+	if len(bookIDs) == 0 {
+		return revs, nil
+	}
+	err := h.db.Where("book_id IN (?)", bookIDs).Order("book_id, version").Find(&revs).Error
+	return revs, err
+}
+
+func (h *historyRepository) GetVersion(bookID uint, version int) (model.BookRevision, error) {
+	var rev model.BookRevision
+	err := h.db.Where("book_id = ? AND version = ?", bookID, version).First(&rev).Error
+	return rev, translateError(err)
+}
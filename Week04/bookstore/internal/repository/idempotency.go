@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type IdempotencyRepository interface {
+	// Reserve 尝试占用 record.Key，成功时返回 true；
+	// key 已被占用时返回 false 和已有的记录。record.CreatedAt 作为当前时间，
+	// 在它之前过期的记录，以及 staleBefore 之前创建但一直没有完成的记录（处理它的进程可能已经退出）视为不存在
+	Reserve(record model.IdempotencyRecord, staleBefore time.Time) (model.IdempotencyRecord, bool, error)
+	Get(key string) (model.IdempotencyRecord, error)
+	// Complete 保存首个请求的响应，now 是完成时间
+	Complete(key string, statusCode int, contentType string, body []byte, now time.Time) error
+	// Release 删除未完成的记录，让后续的重试可以重新执行
+	Release(key string) error
+	DeleteExpired(now time.Time) error
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) Reserve(record model.IdempotencyRecord, staleBefore time.Time) (model.IdempotencyRecord, bool, error) {
+	err := r.db.Where("idempotency_key = ? AND (expires_at < ? OR (completed_at IS NULL AND created_at < ?))",
+		record.Key, record.CreatedAt, staleBefore).Delete(&model.IdempotencyRecord{}).Error
+	if err != nil {
+		return record, false, err
+	}
+
+	err = r.db.Create(&record).Error
+	if err == nil {
+		return record, true, nil
+	}
+	if !isDuplicateEntry(err) {
+		return record, false, err
+	}
+	existing, err := r.Get(record.Key)
+	return existing, false, err
+}
+
+func (r *idempotencyRepository) Get(key string) (model.IdempotencyRecord, error) {
+	var record model.IdempotencyRecord
+	err := r.db.Where("idempotency_key = ?", key).First(&record).Error
+	return record, translateError(err)
+}
+
+func (r *idempotencyRepository) Complete(key string, statusCode int, contentType string, body []byte, now time.Time) error {
+	return r.db.Model(&model.IdempotencyRecord{}).Where("idempotency_key = ?", key).
+		Updates(map[string]interface{}{
+			"status_code":  statusCode,
+			"content_type": contentType,
+			"body":         string(body),
+			"completed_at": now,
+		}).Error
+}
+
+func (r *idempotencyRepository) Release(key string) error {
+	return r.db.Where("idempotency_key = ? AND completed_at IS NULL", key).
+		Delete(&model.IdempotencyRecord{}).Error
+}
+
+func (r *idempotencyRepository) DeleteExpired(now time.Time) error {
+	return r.db.Where("expires_at < ?", now).Delete(&model.IdempotencyRecord{}).Error
+}
+
+// isDuplicateEntry 判断是否违反了唯一约束
+func isDuplicateEntry(err error) bool {
+	e, ok := err.(*mysql.MySQLError)
+	return ok && e.Number == 1062
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// LeaseRepository 保证多个实例中同一时间只有一个持有某个定时任务
+type LeaseRepository interface {
+	// Acquire 在租约不存在、到 now 时已经过期或者本来就属于 holder 时占用租约直到 until，成功时返回 true。
+	// 租约在 until 时刻失效，now 等于 until 的占用可以成功
+	Acquire(name, holder string, now, until time.Time) (bool, error)
+	// Renew 在租约仍然属于 holder 时把它延长到 until，租约已经被别人占用时返回 false
+	Renew(name, holder string, until time.Time) (bool, error)
+}
+
+type leaseRepository struct {
+	db *gorm.DB
+}
+
+func NewLeaseRepository(db *gorm.DB) LeaseRepository {
+	return &leaseRepository{db: db}
+}
+
+func (r *leaseRepository) Acquire(name, holder string, now, until time.Time) (bool, error) {
+	err := r.db.Create(&model.JobLease{Name: name, Holder: holder, ExpiresAt: until}).Error
+	if err == nil {
+		return true, nil
+	}
+	if !isDuplicateEntry(err) {
+		return false, err
+	}
+	db := r.db.Model(&model.JobLease{}).Where("name = ? AND (expires_at <= ? OR holder = ?)", name, now, holder).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": until})
+	return db.RowsAffected == 1, db.Error
+}
+
+func (r *leaseRepository) Renew(name, holder string, until time.Time) (bool, error) {
+	db := r.db.Model(&model.JobLease{}).Where("name = ? AND holder = ?", name, holder).
+		Updates(map[string]interface{}{"expires_at": until})
+	return db.RowsAffected == 1, db.Error
+}
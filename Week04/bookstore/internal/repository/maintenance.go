@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// MaintenanceRepository 是定时任务使用的维护操作，和其他仓储不同，它的方法不限定店铺
+type MaintenanceRepository interface {
+	// ListDeletedBooks 返回 before 之前软删除的至多 limit 本图书
+	ListDeletedBooks(before time.Time, limit int) ([]model.Book, error)
+	// PurgeBooks 物理删除图书、它们的评论、分类关系和翻译，变更历史保留
+	PurgeBooks(ids []uint) error
+	// RecomputeRatings 按已通过的评论重新计算所有图书的评分，返回被修正的图书数
+	RecomputeRatings(now time.Time) (int64, error)
+}
+
+type maintenanceRepository struct {
+	db *gorm.DB
+}
+
+func NewMaintenanceRepository(db *gorm.DB) MaintenanceRepository {
+	return &maintenanceRepository{db: db}
+}
+
+func (r *maintenanceRepository) ListDeletedBooks(before time.Time, limit int) ([]model.Book, error) {
+	var books []model.Book
+	err := r.db.Unscoped().Where("deleted_at < ?", before).Order("id").Limit(limit).Find(&books).Error
+	return books, err
+}
+
+func (r *maintenanceRepository) PurgeBooks(ids []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("book_id IN (?)", ids).Delete(&model.Review{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("book_id IN (?)", ids).Delete(&model.BookCategory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("book_id IN (?)", ids).Delete(&model.BookTranslation{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN (?)", ids).Delete(&model.Book{}).Error
+	})
+}
+
+func (r *maintenanceRepository) RecomputeRatings(now time.Time) (int64, error) {
+	// 只更新和评论统计不一致的图书，和 AddRating 一样更新 updated_at，让缓存失效
+	db := r.db.Exec(`UPDATE books b
+		LEFT JOIN (SELECT book_id, SUM(rating) AS rating_sum, COUNT(*) AS rating_count
+			FROM reviews WHERE status = ? GROUP BY book_id) r ON r.book_id = b.id
+		SET b.rating_sum = COALESCE(r.rating_sum, 0), b.rating_count = COALESCE(r.rating_count, 0), b.updated_at = ?
+		WHERE b.rating_sum <> COALESCE(r.rating_sum, 0) OR b.rating_count <> COALESCE(r.rating_count, 0)`,
+		model.ReviewApproved, now)
+	return db.RowsAffected, db.Error
+}
@@ -0,0 +1,219 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+type bookState struct {
+	books  map[uint]model.Book
+	nextID uint
+}
+
+type bookRepository struct {
+	mu    sync.RWMutex
+	state bookState
+	clock clock.Clock
+}
+
+func newBookRepository(clk clock.Clock) *bookRepository {
+	return &bookRepository{
+		state: bookState{books: make(map[uint]model.Book), nextID: 1},
+		clock: clk,
+	}
+}
+
+func (b *bookRepository) snapshot() bookState {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	books := make(map[uint]model.Book, len(b.state.books))
+	for k, v := range b.state.books {
+		books[k] = v
+	}
+	return bookState{books: books, nextID: b.state.nextID}
+}
+
+func (b *bookRepository) restore(state bookState) {
+	b.mu.Lock()
+	b.state = state
+	b.mu.Unlock()
+}
+
+// visible 返回当前店铺内未删除的图书
+func (b *bookRepository) visible(ctx context.Context, id uint) (model.Book, bool) {
+	t, _ := tenant.FromContext(ctx)
+	book, ok := b.state.books[id]
+	if !ok || book.DeletedAt != nil || book.TenantID != t.ID {
+		return model.Book{}, false
+	}
+	return book, true
+}
+
+func (b *bookRepository) GetAll(ctx context.Context) ([]model.Book, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var books []model.Book
+	for _, book := range b.state.books {
+		if book.DeletedAt == nil && book.TenantID == t.ID {
+			books = append(books, book)
+		}
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	return books, nil
+}
+
+func (b *bookRepository) GetByID(ctx context.Context, id uint) (model.Book, error) {
+	if _, ok := tenant.FromContext(ctx); !ok {
+		return model.Book{}, tenant.ErrNoTenant
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	book, ok := b.visible(ctx, id)
+	if !ok {
+		return book, repository.ErrNotFound
+	}
+	return book, nil
+}
+
+// GetForUpdate 不需要加锁，内存仓储的事务是串行执行的
+func (b *bookRepository) GetForUpdate(ctx context.Context, id uint) (model.Book, error) {
+	return b.GetByID(ctx, id)
+}
+
+func (b *bookRepository) ListAfter(ctx context.Context, afterID uint, limit int) ([]model.Book, error) {
+	books, err := b.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(books), func(i int) bool { return books[i].ID > afterID })
+	books = books[i:]
+	if len(books) > limit {
+		books = books[:limit]
+	}
+	return books, nil
+}
+
+func (b *bookRepository) Count(ctx context.Context) (int, error) {
+	books, err := b.GetAll(ctx)
+	return len(books), err
+}
+
+func (b *bookRepository) LastModified(ctx context.Context) (time.Time, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return time.Time{}, tenant.ErrNoTenant
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var last time.Time
+	for _, book := range b.state.books {
+		if book.TenantID != t.ID {
+			continue
+		}
+		if book.UpdatedAt.After(last) {
+			last = book.UpdatedAt
+		}
+		if book.DeletedAt != nil && book.DeletedAt.After(last) {
+			last = *book.DeletedAt
+		}
+	}
+	return last, nil
+}
+
+func (b *bookRepository) Save(ctx context.Context, book model.Book) (model.Book, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return book, tenant.ErrNoTenant
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	if book.ID == 0 {
+		book.ID = b.state.nextID
+		b.state.nextID++
+		book.CreatedAt = now
+		book.RatingSum, book.RatingCount = 0, 0
+		book.CoverType, book.CoverUpdatedAt = "", nil
+	} else {
+		old, ok := b.visible(ctx, book.ID)
+		if !ok {
+			return book, repository.ErrNotFound
+		}
+		book.CreatedAt = old.CreatedAt
+		// 和 MySQL 实现一致，评分和封面只通过 AddRating、SetCover 修改
+		book.RatingSum, book.RatingCount = old.RatingSum, old.RatingCount
+		book.CoverType, book.CoverUpdatedAt = old.CoverType, old.CoverUpdatedAt
+	}
+	book.TenantID = t.ID
+	book.UpdatedAt = now
+	b.state.books[book.ID] = book
+	return book, nil
+}
+
+func (b *bookRepository) Delete(ctx context.Context, book model.Book) error {
+	if _, ok := tenant.FromContext(ctx); !ok {
+		return tenant.ErrNoTenant
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stored, ok := b.visible(ctx, book.ID)
+	if !ok {
+		return nil
+	}
+	now := b.clock.Now()
+	stored.DeletedAt = &now
+	b.state.books[book.ID] = stored
+	return nil
+}
+
+func (b *bookRepository) AddRating(ctx context.Context, id uint, sum, count int) error {
+	if _, ok := tenant.FromContext(ctx); !ok {
+		return tenant.ErrNoTenant
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	book, ok := b.visible(ctx, id)
+	if !ok {
+		return repository.ErrNotFound
+	}
+	book.RatingSum += sum
+	book.RatingCount += count
+	book.UpdatedAt = b.clock.Now()
+	b.state.books[id] = book
+	return nil
+}
+
+func (b *bookRepository) SetCover(ctx context.Context, id uint, coverType string, updatedAt *time.Time) (string, error) {
+	if _, ok := tenant.FromContext(ctx); !ok {
+		return "", tenant.ErrNoTenant
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	book, ok := b.visible(ctx, id)
+	if !ok {
+		return "", repository.ErrNotFound
+	}
+	old := book.CoverType
+	book.CoverType, book.CoverUpdatedAt = coverType, updatedAt
+	book.UpdatedAt = b.clock.Now()
+	b.state.books[id] = book
+	return old, nil
+}
@@ -0,0 +1,330 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+// categoryRepository 统计图书数时要跳过已删除的图书，所以持有 Store
+type categoryRepository struct {
+	store *Store
+	clock clock.Clock
+
+	mu         sync.RWMutex
+	categories map[uint]model.Category
+	books      map[model.BookCategory]bool
+	nextID     uint
+}
+
+func newCategoryRepository(s *Store, clk clock.Clock) *categoryRepository {
+	return &categoryRepository{
+		store:      s,
+		clock:      clk,
+		categories: make(map[uint]model.Category),
+		books:      make(map[model.BookCategory]bool),
+		nextID:     1,
+	}
+}
+
+func (r *categoryRepository) GetByID(ctx context.Context, id uint) (model.Category, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return model.Category{}, tenant.ErrNoTenant
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.categories[id]
+	if !ok || c.TenantID != t.ID {
+		return model.Category{}, repository.ErrNotFound
+	}
+	return c, nil
+}
+
+func (r *categoryRepository) GetByIDs(ctx context.Context, ids []uint) ([]model.Category, error) {
+	var cs []model.Category
+	for _, id := range ids {
+		c, err := r.GetByID(ctx, id)
+		if err == repository.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+	}
+	sort.SliceStable(cs, func(i, j int) bool { return len(cs[i].Path) < len(cs[j].Path) })
+	return cs, nil
+}
+
+func (r *categoryRepository) Children(ctx context.Context, parentID uint) ([]model.Category, error) {
+	return r.find(ctx, func(c model.Category) bool { return c.ParentID == parentID }, func(a, b model.Category) bool {
+		return a.Name < b.Name
+	})
+}
+
+func (r *categoryRepository) find(ctx context.Context, match func(model.Category) bool,
+	less func(a, b model.Category) bool) ([]model.Category, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var cs []model.Category
+	for _, c := range r.categories {
+		if c.TenantID == t.ID && match(c) {
+			cs = append(cs, c)
+		}
+	}
+	sort.Slice(cs, func(i, j int) bool { return less(cs[i], cs[j]) })
+	return cs, nil
+}
+
+// siblingExists 检查同一个父分类下是否已经有同名的分类，调用方需要持有锁
+func (r *categoryRepository) siblingExists(c model.Category) bool {
+	for _, other := range r.categories {
+		if other.ID != c.ID && other.TenantID == c.TenantID && other.ParentID == c.ParentID && other.Name == c.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *categoryRepository) Create(ctx context.Context, c model.Category) (model.Category, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return c, tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.TenantID = t.ID
+	prefix, err := r.parentPath(t.ID, c.ParentID)
+	if err != nil {
+		return c, err
+	}
+	if r.siblingExists(c) {
+		return c, repository.ErrDuplicate
+	}
+	now := r.clock.Now()
+	c.ID = r.nextID
+	r.nextID++
+	c.Path = prefix + uintString(c.ID) + "/"
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	r.categories[c.ID] = c
+	return c, nil
+}
+
+func (r *categoryRepository) Update(ctx context.Context, id uint, name string, parentID uint) (model.Category, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return model.Category{}, tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.categories[id]
+	if !ok || c.TenantID != t.ID {
+		return model.Category{}, repository.ErrNotFound
+	}
+	oldPath := c.Path
+	c.Name = name
+	if parentID != c.ParentID {
+		prefix, err := r.parentPath(t.ID, parentID)
+		if err != nil {
+			return c, err
+		}
+		if strings.HasPrefix(prefix, oldPath) {
+			return c, repository.ErrInvalidParent
+		}
+		c.ParentID = parentID
+		c.Path = prefix + uintString(id) + "/"
+	}
+	if r.siblingExists(c) {
+		return c, repository.ErrDuplicate
+	}
+	now := r.clock.Now()
+	c.UpdatedAt = now
+	r.categories[id] = c
+	if c.Path != oldPath {
+		for did, d := range r.categories {
+			if d.TenantID == t.ID && did != id && strings.HasPrefix(d.Path, oldPath) {
+				d.Path = c.Path + d.Path[len(oldPath):]
+				d.UpdatedAt = now
+				r.categories[did] = d
+			}
+		}
+	}
+	return c, nil
+}
+
+// parentPath 返回父分类下新分类的 Path 前缀，调用方需要持有锁
+func (r *categoryRepository) parentPath(tenantID, parentID uint) (string, error) {
+	if parentID == 0 {
+		return "/", nil
+	}
+	parent, ok := r.categories[parentID]
+	if !ok || parent.TenantID != tenantID {
+		return "", repository.ErrInvalidParent
+	}
+	return parent.Path, nil
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, id uint) error {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.categories[id]; ok && c.TenantID == t.ID {
+		delete(r.categories, id)
+		for bc := range r.books {
+			if bc.CategoryID == id {
+				delete(r.books, bc)
+			}
+		}
+	}
+	return nil
+}
+
+// subtreeBooks 返回分类子树中的图书，调用方需要持有锁
+func (r *categoryRepository) subtreeBooks(c model.Category) map[uint]bool {
+	books := make(map[uint]bool)
+	for bc := range r.books {
+		d, ok := r.categories[bc.CategoryID]
+		if ok && d.TenantID == c.TenantID && strings.HasPrefix(d.Path, c.Path) {
+			books[bc.BookID] = true
+		}
+	}
+	return books
+}
+
+func (r *categoryRepository) BookCounts(ctx context.Context, ids []uint) (map[uint]int, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	books := r.store.books
+	books.mu.RLock()
+	defer books.mu.RUnlock()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[uint]int, len(ids))
+	for _, id := range ids {
+		c, ok := r.categories[id]
+		if !ok || c.TenantID != t.ID {
+			continue
+		}
+		for bookID := range r.subtreeBooks(c) {
+			if book, ok := books.state.books[bookID]; ok && book.DeletedAt == nil {
+				counts[id]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+func (r *categoryRepository) BookIDs(ctx context.Context, categoryIDs []uint) ([]uint, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := make(map[uint]bool)
+	for _, id := range categoryIDs {
+		if c, ok := r.categories[id]; ok && c.TenantID == t.ID {
+			for bookID := range r.subtreeBooks(c) {
+				seen[bookID] = true
+			}
+		}
+	}
+	ids := make([]uint, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (r *categoryRepository) BookPaths(ctx context.Context, bookIDs []uint) (map[uint][]string, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	want := make(map[uint]bool, len(bookIDs))
+	for _, id := range bookIDs {
+		want[id] = true
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	paths := make(map[uint][]string)
+	for bc := range r.books {
+		if want[bc.BookID] && bc.TenantID == t.ID {
+			if c, ok := r.categories[bc.CategoryID]; ok {
+				paths[bc.BookID] = append(paths[bc.BookID], c.Path)
+			}
+		}
+	}
+	return paths, nil
+}
+
+func (r *categoryRepository) BookCategories(ctx context.Context, bookID uint) ([]model.Category, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	r.mu.RLock()
+	var ids []uint
+	for bc := range r.books {
+		if bc.BookID == bookID && bc.TenantID == t.ID {
+			ids = append(ids, bc.CategoryID)
+		}
+	}
+	r.mu.RUnlock()
+	cs, err := r.GetByIDs(ctx, ids)
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Path < cs[j].Path })
+	return cs, err
+}
+
+func (r *categoryRepository) SetBookCategories(ctx context.Context, bookID uint, categoryIDs []uint) error {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for bc := range r.books {
+		if bc.BookID == bookID && bc.TenantID == t.ID {
+			delete(r.books, bc)
+		}
+	}
+	for _, id := range categoryIDs {
+		r.books[model.BookCategory{BookID: bookID, CategoryID: id, TenantID: t.ID}] = true
+	}
+	return nil
+}
+
+// purgeBooks 删除图书的分类关系，供物理删除图书时使用
+func (r *categoryRepository) purgeBooks(purged map[uint]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for bc := range r.books {
+		if purged[bc.BookID] {
+			delete(r.books, bc)
+		}
+	}
+}
+
+func uintString(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+type historyRepository struct {
+	mu    sync.RWMutex
+	revs  []model.BookRevision
+	clock clock.Clock
+}
+
+func newHistoryRepository(clk clock.Clock) *historyRepository {
+	return &historyRepository{clock: clk}
+}
+
+func (h *historyRepository) snapshot() []model.BookRevision {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]model.BookRevision(nil), h.revs...)
+}
+
+func (h *historyRepository) restore(revs []model.BookRevision) {
+	h.mu.Lock()
+	h.revs = revs
+	h.mu.Unlock()
+}
+
+func (h *historyRepository) Add(rev model.BookRevision) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range h.revs {
+		if r.BookID == rev.BookID && r.Version == rev.Version {
+			return repository.ErrDuplicate
+		}
+	}
+	rev.ID = uint(len(h.revs) + 1)
+	rev.CreatedAt = h.clock.Now()
+	h.revs = append(h.revs, rev)
+	return nil
+}
+
+func (h *historyRepository) LatestVersion(bookID uint) (int, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	version := 0
+	for _, r := range h.revs {
+		if r.BookID == bookID && r.Version > version {
+			version = r.Version
+		}
+	}
+	return version, nil
+}
+
+func (h *historyRepository) ListByBook(bookID uint) ([]model.BookRevision, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var revs []model.BookRevision
+	for _, r := range h.revs {
+		if r.BookID == bookID {
+			revs = append(revs, r)
+		}
+	}
+	return revs, nil
+}
+
+func (h *historyRepository) ListByBooks(bookIDs []uint) ([]model.BookRevision, error) {
+	ids := make(map[uint]bool, len(bookIDs))
+	for _, id := range bookIDs {
+		ids[id] = true
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var revs []model.BookRevision
+	for _, r := range h.revs {
+		if ids[r.BookID] {
+			revs = append(revs, r)
+		}
+	}
+	sort.SliceStable(revs, func(i, j int) bool {
+		if revs[i].BookID != revs[j].BookID {
+			return revs[i].BookID < revs[j].BookID
+		}
+		return revs[i].Version < revs[j].Version
+	})
+	return revs, nil
+}
+
+func (h *historyRepository) GetVersion(bookID uint, version int) (model.BookRevision, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, r := range h.revs {
+		if r.BookID == bookID && r.Version == version {
+			return r, nil
+		}
+	}
+	return model.BookRevision{}, repository.ErrNotFound
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+type idempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]model.IdempotencyRecord
+}
+
+func newIdempotencyRepository() *idempotencyRepository {
+	return &idempotencyRepository{records: make(map[string]model.IdempotencyRecord)}
+}
+
+func (r *idempotencyRepository) Reserve(record model.IdempotencyRecord, staleBefore time.Time) (model.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.records[record.Key]; ok {
+		expired := existing.ExpiresAt.Before(record.CreatedAt)
+		stale := existing.CompletedAt == nil && existing.CreatedAt.Before(staleBefore)
+		if !expired && !stale {
+			return existing, false, nil
+		}
+	}
+	r.records[record.Key] = record
+	return record, true, nil
+}
+
+func (r *idempotencyRepository) Get(key string) (model.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[key]
+	if !ok {
+		return record, repository.ErrNotFound
+	}
+	return record, nil
+}
+
+func (r *idempotencyRepository) Complete(key string, statusCode int, contentType string, body []byte, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[key]
+	if !ok {
+		return nil
+	}
+	record.StatusCode = statusCode
+	record.ContentType = contentType
+	record.Body = string(body)
+	record.CompletedAt = &now
+	r.records[key] = record
+	return nil
+}
+
+func (r *idempotencyRepository) Release(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record, ok := r.records[key]; ok && record.CompletedAt == nil {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteExpired(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, record := range r.records {
+		if record.ExpiresAt.Before(now) {
+			delete(r.records, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type leaseRepository struct {
+	mu     sync.Mutex
+	leases map[string]model.JobLease
+}
+
+func newLeaseRepository() *leaseRepository {
+	return &leaseRepository{leases: make(map[string]model.JobLease)}
+}
+
+func (r *leaseRepository) Acquire(name, holder string, now, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if lease, ok := r.leases[name]; ok && lease.ExpiresAt.After(now) && lease.Holder != holder {
+		return false, nil
+	}
+	r.leases[name] = model.JobLease{Name: name, Holder: holder, ExpiresAt: until, UpdatedAt: now}
+	return true, nil
+}
+
+func (r *leaseRepository) Renew(name, holder string, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lease, ok := r.leases[name]
+	if !ok || lease.Holder != holder {
+		return false, nil
+	}
+	lease.ExpiresAt = until
+	r.leases[name] = lease
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// maintenanceRepository 直接操作 Store 里的图书和评论，持有事务锁避免和进行中的事务交错
+type maintenanceRepository struct {
+	store *Store
+}
+
+func (r *maintenanceRepository) ListDeletedBooks(before time.Time, limit int) ([]model.Book, error) {
+	books := r.store.books
+	books.mu.RLock()
+	defer books.mu.RUnlock()
+
+	var deleted []model.Book
+	for _, book := range books.state.books {
+		if book.DeletedAt != nil && book.DeletedAt.Before(before) {
+			deleted = append(deleted, book)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].ID < deleted[j].ID })
+	if len(deleted) > limit {
+		deleted = deleted[:limit]
+	}
+	return deleted, nil
+}
+
+func (r *maintenanceRepository) PurgeBooks(ids []uint) error {
+	r.store.txMu.Lock()
+	defer r.store.txMu.Unlock()
+	books, reviews := r.store.books, r.store.reviews
+
+	purged := make(map[uint]bool, len(ids))
+	books.mu.Lock()
+	for _, id := range ids {
+		delete(books.state.books, id)
+		purged[id] = true
+	}
+	books.mu.Unlock()
+
+	reviews.mu.Lock()
+	for id, review := range reviews.state.reviews {
+		if purged[review.BookID] {
+			delete(reviews.state.reviews, id)
+		}
+	}
+	reviews.mu.Unlock()
+
+	r.store.categories.purgeBooks(purged)
+	r.store.translations.purgeBooks(purged)
+	return nil
+}
+
+func (r *maintenanceRepository) RecomputeRatings(now time.Time) (int64, error) {
+	r.store.txMu.Lock()
+	defer r.store.txMu.Unlock()
+	books, reviews := r.store.books, r.store.reviews
+
+	type rating struct{ sum, count int }
+	ratings := make(map[uint]rating)
+	reviews.mu.RLock()
+	for _, review := range reviews.state.reviews {
+		if review.Status == model.ReviewApproved {
+			rt := ratings[review.BookID]
+			ratings[review.BookID] = rating{rt.sum + review.Rating, rt.count + 1}
+		}
+	}
+	reviews.mu.RUnlock()
+
+	books.mu.Lock()
+	defer books.mu.Unlock()
+	var fixed int64
+	for id, book := range books.state.books {
+		rt := ratings[id]
+		if book.RatingSum != rt.sum || book.RatingCount != rt.count {
+			book.RatingSum, book.RatingCount = rt.sum, rt.count
+			book.UpdatedAt = now
+			books.state.books[id] = book
+			fixed++
+		}
+	}
+	return fixed, nil
+}
@@ -0,0 +1,228 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+type cartState struct {
+	items  map[uint]model.CartItem
+	nextID uint
+}
+
+type cartRepository struct {
+	mu    sync.RWMutex
+	state cartState
+	clock clock.Clock
+}
+
+func newCartRepository(clk clock.Clock) *cartRepository {
+	return &cartRepository{
+		state: cartState{items: make(map[uint]model.CartItem), nextID: 1},
+		clock: clk,
+	}
+}
+
+func (r *cartRepository) snapshot() cartState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	items := make(map[uint]model.CartItem, len(r.state.items))
+	for k, v := range r.state.items {
+		items[k] = v
+	}
+	return cartState{items: items, nextID: r.state.nextID}
+}
+
+func (r *cartRepository) restore(state cartState) {
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+}
+
+func (r *cartRepository) List(ctx context.Context, customer string) ([]model.CartItem, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var items []model.CartItem
+	for _, item := range r.state.items {
+		if item.TenantID == t.ID && item.Customer == customer {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+func (r *cartRepository) Put(ctx context.Context, item model.CartItem) (model.CartItem, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return item, tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	for id, existing := range r.state.items {
+		if existing.TenantID == t.ID && existing.Customer == item.Customer && existing.BookID == item.BookID {
+			existing.Quantity = item.Quantity
+			existing.UpdatedAt = now
+			r.state.items[id] = existing
+			return existing, nil
+		}
+	}
+	item.ID = r.state.nextID
+	r.state.nextID++
+	item.TenantID = t.ID
+	item.CreatedAt = now
+	item.UpdatedAt = now
+	r.state.items[item.ID] = item
+	return item, nil
+}
+
+func (r *cartRepository) Remove(ctx context.Context, customer string, bookID uint) error {
+	return r.removeWhere(ctx, func(item model.CartItem) bool {
+		return item.Customer == customer && item.BookID == bookID
+	})
+}
+
+func (r *cartRepository) Clear(ctx context.Context, customer string) error {
+	return r.removeWhere(ctx, func(item model.CartItem) bool { return item.Customer == customer })
+}
+
+func (r *cartRepository) removeWhere(ctx context.Context, match func(model.CartItem) bool) error {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, item := range r.state.items {
+		if item.TenantID == t.ID && match(item) {
+			delete(r.state.items, id)
+		}
+	}
+	return nil
+}
+
+type orderState struct {
+	orders     map[uint]model.Order
+	nextID     uint
+	nextItemID uint
+}
+
+type orderRepository struct {
+	mu    sync.RWMutex
+	state orderState
+	clock clock.Clock
+}
+
+func newOrderRepository(clk clock.Clock) *orderRepository {
+	return &orderRepository{
+		state: orderState{orders: make(map[uint]model.Order), nextID: 1, nextItemID: 1},
+		clock: clk,
+	}
+}
+
+// snapshot 只复制 map，订单行创建后不会被修改，可以共享
+func (r *orderRepository) snapshot() orderState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	orders := make(map[uint]model.Order, len(r.state.orders))
+	for k, v := range r.state.orders {
+		orders[k] = v
+	}
+	return orderState{orders: orders, nextID: r.state.nextID, nextItemID: r.state.nextItemID}
+}
+
+func (r *orderRepository) restore(state orderState) {
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+}
+
+func (r *orderRepository) Create(ctx context.Context, order model.Order) (model.Order, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return order, tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	order.ID = r.state.nextID
+	r.state.nextID++
+	order.TenantID = t.ID
+	order.CreatedAt = now
+	order.UpdatedAt = now
+	items := make([]model.OrderItem, len(order.Items))
+	for i, item := range order.Items {
+		item.ID = r.state.nextItemID
+		r.state.nextItemID++
+		item.OrderID = order.ID
+		items[i] = item
+	}
+	order.Items = items
+	r.state.orders[order.ID] = order
+	return order, nil
+}
+
+func (r *orderRepository) GetByID(ctx context.Context, id uint) (model.Order, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return model.Order{}, tenant.ErrNoTenant
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	order, ok := r.state.orders[id]
+	if !ok || order.TenantID != t.ID {
+		return model.Order{}, repository.ErrNotFound
+	}
+	return order, nil
+}
+
+func (r *orderRepository) List(ctx context.Context, customer, status string) ([]model.Order, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var orders []model.Order
+	for _, order := range r.state.orders {
+		if order.TenantID == t.ID && (customer == "" || order.Customer == customer) &&
+			(status == "" || order.Status == status) {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID > orders[j].ID })
+	return orders, nil
+}
+
+func (r *orderRepository) UpdateStatus(ctx context.Context, id uint, from, to string) (model.Order, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return model.Order{}, tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.state.orders[id]
+	if !ok || order.TenantID != t.ID {
+		return model.Order{}, repository.ErrNotFound
+	}
+	if order.Status != from {
+		return order, repository.ErrConflict
+	}
+	order.Status = to
+	order.UpdatedAt = r.clock.Now()
+	r.state.orders[id] = order
+	return order, nil
+}
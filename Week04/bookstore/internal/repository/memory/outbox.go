@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type outboxRepository struct {
+	mu     sync.RWMutex
+	events []model.OutboxEvent
+	clock  clock.Clock
+}
+
+func newOutboxRepository(clk clock.Clock) *outboxRepository {
+	return &outboxRepository{clock: clk}
+}
+
+func (o *outboxRepository) snapshot() []model.OutboxEvent {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return append([]model.OutboxEvent(nil), o.events...)
+}
+
+func (o *outboxRepository) restore(events []model.OutboxEvent) {
+	o.mu.Lock()
+	o.events = events
+	o.mu.Unlock()
+}
+
+func (o *outboxRepository) Add(event model.OutboxEvent) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	event.ID = uint(len(o.events) + 1)
+	event.CreatedAt = o.clock.Now()
+	o.events = append(o.events, event)
+	return nil
+}
+
+// Claim 和 MySQL 实现一样，只认领最早的未投递事件到期且没有被认领的书
+func (o *outboxRepository) Claim(token string, now, until time.Time, limit int) ([]model.OutboxEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// blocked 是有更早的未投递事件的书
+	blocked := make(map[uint]bool)
+	won := make(map[uint]bool)
+	var events []model.OutboxEvent
+	for i, e := range o.events {
+		if len(events) >= limit {
+			break
+		}
+		if e.PublishedAt != nil || e.DeadAt != nil {
+			continue
+		}
+		head := !blocked[e.AggregateID]
+		blocked[e.AggregateID] = true
+		if e.ClaimedUntil != nil && e.ClaimedUntil.After(now) {
+			won[e.AggregateID] = false
+			continue
+		}
+		if head && (e.NextAttemptAt == nil || !e.NextAttemptAt.After(now)) {
+			won[e.AggregateID] = true
+		}
+		if !won[e.AggregateID] {
+			continue
+		}
+		o.events[i].ClaimToken, o.events[i].ClaimedUntil = token, &until
+		events = append(events, o.events[i])
+	}
+	return events, nil
+}
+
+func (o *outboxRepository) Release(ids []uint) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, id := range ids {
+		if i := int(id) - 1; i >= 0 && i < len(o.events) {
+			o.events[i].ClaimToken, o.events[i].ClaimedUntil = "", nil
+		}
+	}
+	return nil
+}
+
+func (o *outboxRepository) MarkPublished(id uint) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if i := int(id) - 1; i >= 0 && i < len(o.events) {
+		now := o.clock.Now()
+		o.events[i].PublishedAt = &now
+	}
+	return nil
+}
+
+func (o *outboxRepository) MarkFailed(id uint, cause error, next time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if i := int(id) - 1; i >= 0 && i < len(o.events) {
+		o.events[i].Attempts++
+		o.events[i].LastError = cause.Error()
+		o.events[i].NextAttemptAt = &next
+		o.events[i].ClaimToken, o.events[i].ClaimedUntil = "", nil
+	}
+	return nil
+}
+
+func (o *outboxRepository) MarkDead(id uint, cause error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if i := int(id) - 1; i >= 0 && i < len(o.events) {
+		now := o.clock.Now()
+		o.events[i].Attempts++
+		o.events[i].LastError = cause.Error()
+		o.events[i].DeadAt = &now
+	}
+	return nil
+}
+
+func (o *outboxRepository) LastID() (uint, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return uint(len(o.events)), nil
+}
+
+func (o *outboxRepository) ListAfter(id uint, limit int) ([]model.OutboxEvent, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var events []model.OutboxEvent
+	for i := int(id); i < len(o.events) && len(events) < limit; i++ {
+		events = append(events, o.events[i])
+	}
+	return events, nil
+}
+
+func (o *outboxRepository) ListByIDs(ids []uint) ([]model.OutboxEvent, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var events []model.OutboxEvent
+	for _, id := range ids {
+		if i := int(id) - 1; i >= 0 && i < len(o.events) {
+			events = append(events, o.events[i])
+		}
+	}
+	return events, nil
+}
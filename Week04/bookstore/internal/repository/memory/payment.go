@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+type paymentState struct {
+	payments map[uint]model.Payment
+	nextID   uint
+}
+
+type paymentRepository struct {
+	mu    sync.RWMutex
+	state paymentState
+	clock clock.Clock
+}
+
+func newPaymentRepository(clk clock.Clock) *paymentRepository {
+	return &paymentRepository{
+		state: paymentState{payments: make(map[uint]model.Payment), nextID: 1},
+		clock: clk,
+	}
+}
+
+func (r *paymentRepository) snapshot() paymentState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	payments := make(map[uint]model.Payment, len(r.state.payments))
+	for k, v := range r.state.payments {
+		payments[k] = v
+	}
+	return paymentState{payments: payments, nextID: r.state.nextID}
+}
+
+func (r *paymentRepository) restore(state paymentState) {
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+}
+
+func (r *paymentRepository) Create(ctx context.Context, p model.Payment) (model.Payment, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return p, tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.state.payments {
+		if existing.ProviderRef == p.ProviderRef || existing.Reference == p.Reference {
+			return p, repository.ErrDuplicate
+		}
+	}
+	now := r.clock.Now()
+	p.ID = r.state.nextID
+	r.state.nextID++
+	p.TenantID = t.ID
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	r.state.payments[p.ID] = p
+	return p, nil
+}
+
+func (r *paymentRepository) Latest(ctx context.Context, orderID uint) (model.Payment, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return model.Payment{}, tenant.ErrNoTenant
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var latest model.Payment
+	for _, p := range r.state.payments {
+		if p.TenantID == t.ID && p.OrderID == orderID && p.ID > latest.ID {
+			latest = p
+		}
+	}
+	if latest.ID == 0 {
+		return latest, repository.ErrNotFound
+	}
+	return latest, nil
+}
+
+func (r *paymentRepository) GetByProviderRef(ref string) (model.Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.state.payments {
+		if p.ProviderRef == ref {
+			return p, nil
+		}
+	}
+	return model.Payment{}, repository.ErrNotFound
+}
+
+func (r *paymentRepository) UpdateStatus(ctx context.Context, p model.Payment, from string) (model.Payment, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return p, tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved, ok := r.state.payments[p.ID]
+	if !ok || saved.TenantID != t.ID {
+		return p, repository.ErrNotFound
+	}
+	if saved.Status != from {
+		return saved, repository.ErrConflict
+	}
+	saved.Status = p.Status
+	saved.DeclineCode = p.DeclineCode
+	saved.UpdatedAt = r.clock.Now()
+	r.state.payments[p.ID] = saved
+	return saved, nil
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+type pricingRuleRepository struct {
+	mu     sync.RWMutex
+	rules  map[uint]model.PricingRule
+	nextID uint
+	clock  clock.Clock
+}
+
+func newPricingRuleRepository(clk clock.Clock) *pricingRuleRepository {
+	return &pricingRuleRepository{rules: make(map[uint]model.PricingRule), nextID: 1, clock: clk}
+}
+
+func (p *pricingRuleRepository) GetAll(ctx context.Context) ([]model.PricingRule, error) {
+	return p.list(ctx, func(model.PricingRule) bool { return true })
+}
+
+func (p *pricingRuleRepository) GetByID(ctx context.Context, id uint) (model.PricingRule, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return model.PricingRule{}, tenant.ErrNoTenant
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	rule, ok := p.rules[id]
+	if !ok || rule.DeletedAt != nil || rule.TenantID != t.ID {
+		return model.PricingRule{}, repository.ErrNotFound
+	}
+	return rule, nil
+}
+
+func (p *pricingRuleRepository) ListEffective(ctx context.Context, now time.Time) ([]model.PricingRule, error) {
+	return p.list(ctx, func(r model.PricingRule) bool {
+		return (r.StartsAt == nil || !r.StartsAt.After(now)) && (r.EndsAt == nil || r.EndsAt.After(now))
+	})
+}
+
+func (p *pricingRuleRepository) list(ctx context.Context, keep func(model.PricingRule) bool) ([]model.PricingRule, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var rules []model.PricingRule
+	for _, r := range p.rules {
+		if r.DeletedAt == nil && r.TenantID == t.ID && keep(r) {
+			rules = append(rules, r)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority < rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	return rules, nil
+}
+
+func (p *pricingRuleRepository) Save(ctx context.Context, rule model.PricingRule) (model.PricingRule, error) {
+	if rule.ID != 0 {
+		if _, err := p.GetByID(ctx, rule.ID); err != nil {
+			return rule, err
+		}
+	}
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return rule, tenant.ErrNoTenant
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.clock.Now()
+	if rule.ID == 0 {
+		rule.ID = p.nextID
+		p.nextID++
+		rule.CreatedAt = now
+	}
+	rule.TenantID = t.ID
+	rule.UpdatedAt = now
+	p.rules[rule.ID] = rule
+	return rule, nil
+}
+
+func (p *pricingRuleRepository) Delete(ctx context.Context, rule model.PricingRule) error {
+	stored, err := p.GetByID(ctx, rule.ID)
+	if err == repository.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.clock.Now()
+	stored.DeletedAt = &now
+	p.rules[rule.ID] = stored
+	return nil
+}
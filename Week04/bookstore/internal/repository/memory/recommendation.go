@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+// recommendationRepository 从 Store 里的订单计算购买记录和销量，推荐按店铺整体替换
+type recommendationRepository struct {
+	store *Store
+
+	mu     sync.RWMutex
+	recs   map[uint][]model.BookRecommendation
+	nextID uint
+}
+
+func newRecommendationRepository(s *Store) *recommendationRepository {
+	return &recommendationRepository{store: s, recs: make(map[uint][]model.BookRecommendation), nextID: 1}
+}
+
+func purchased(order model.Order) bool {
+	return order.Status == model.OrderPaid || order.Status == model.OrderShipping || order.Status == model.OrderShipped
+}
+
+func (r *recommendationRepository) Purchases() ([]repository.Purchase, error) {
+	orders := r.store.orders
+	orders.mu.RLock()
+	defer orders.mu.RUnlock()
+
+	seen := make(map[repository.Purchase]bool)
+	var purchases []repository.Purchase
+	for _, order := range orders.state.orders {
+		if !purchased(order) {
+			continue
+		}
+		for _, item := range order.Items {
+			p := repository.Purchase{TenantID: order.TenantID, Customer: order.Customer, BookID: item.BookID}
+			if !seen[p] {
+				seen[p] = true
+				purchases = append(purchases, p)
+			}
+		}
+	}
+	return purchases, nil
+}
+
+func (r *recommendationRepository) Replace(tenantID uint, recs []model.BookRecommendation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := make([]model.BookRecommendation, len(recs))
+	for i, rec := range recs {
+		rec.ID = r.nextID
+		r.nextID++
+		rec.TenantID = tenantID
+		saved[i] = rec
+	}
+	r.recs[tenantID] = saved
+	return nil
+}
+
+func (r *recommendationRepository) Related(ctx context.Context, bookID uint, limit int) ([]model.BookRecommendation, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var recs []model.BookRecommendation
+	for _, rec := range r.recs[t.ID] {
+		if rec.BookID == bookID {
+			recs = append(recs, rec)
+		}
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].RelatedBookID < recs[j].RelatedBookID
+	})
+	if len(recs) > limit {
+		recs = recs[:limit]
+	}
+	return recs, nil
+}
+
+func (r *recommendationRepository) BestSellers(ctx context.Context, limit int) ([]uint, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	orders := r.store.orders
+	orders.mu.RLock()
+	sold := make(map[uint]int)
+	for _, order := range orders.state.orders {
+		if order.TenantID == t.ID && purchased(order) {
+			for _, item := range order.Items {
+				sold[item.BookID] += item.Quantity
+			}
+		}
+	}
+	orders.mu.RUnlock()
+
+	ids := make([]uint, 0, len(sold))
+	for id := range sold {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if sold[ids[i]] != sold[ids[j]] {
+			return sold[ids[i]] > sold[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
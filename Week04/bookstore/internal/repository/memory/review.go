@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+type reviewState struct {
+	reviews map[uint]model.Review
+	nextID  uint
+}
+
+type reviewRepository struct {
+	mu    sync.RWMutex
+	state reviewState
+	clock clock.Clock
+}
+
+func newReviewRepository(clk clock.Clock) *reviewRepository {
+	return &reviewRepository{
+		state: reviewState{reviews: make(map[uint]model.Review), nextID: 1},
+		clock: clk,
+	}
+}
+
+func (r *reviewRepository) snapshot() reviewState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	reviews := make(map[uint]model.Review, len(r.state.reviews))
+	for k, v := range r.state.reviews {
+		reviews[k] = v
+	}
+	return reviewState{reviews: reviews, nextID: r.state.nextID}
+}
+
+func (r *reviewRepository) restore(state reviewState) {
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+}
+
+func (r *reviewRepository) ListByBook(bookID uint, status string) ([]model.Review, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var reviews []model.Review
+	for _, review := range r.state.reviews {
+		if review.BookID == bookID && (status == "" || review.Status == status) {
+			reviews = append(reviews, review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool { return reviews[i].ID < reviews[j].ID })
+	return reviews, nil
+}
+
+// GetForUpdate 不需要加锁，Store.Transaction 本身就是串行的
+func (r *reviewRepository) GetForUpdate(bookID, id uint) (model.Review, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	review, ok := r.state.reviews[id]
+	if !ok || review.BookID != bookID {
+		return model.Review{}, repository.ErrNotFound
+	}
+	return review, nil
+}
+
+func (r *reviewRepository) Save(review model.Review) (model.Review, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.state.reviews {
+		if other.ID != review.ID && other.BookID == review.BookID && other.Author == review.Author {
+			return review, repository.ErrDuplicate
+		}
+	}
+
+	now := r.clock.Now()
+	if review.ID == 0 {
+		review.ID = r.state.nextID
+		r.state.nextID++
+		review.CreatedAt = now
+	}
+	review.UpdatedAt = now
+	r.state.reviews[review.ID] = review
+	return review, nil
+}
+
+func (r *reviewRepository) Delete(review model.Review) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.state.reviews, review.ID)
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package memory 是仓储的内存实现，用于测试和不依赖 MySQL 的本地运行。
+// 行为尽量和 MySQL 实现保持一致：图书按店铺隔离、软删除、唯一约束等。
+// 事务通过全局锁串行执行，失败时把事务内涉及的仓储恢复到事务开始前的快照
+package memory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/wire"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+// ProviderSet 提供全部仓储的内存实现，可以替换 repository.MySQLSet
+var ProviderSet = wire.NewSet(
+	NewStore,
+	// 内存仓储不经过熔断器，这里只是为了让监控接口可用
+	repository.NewBreaker,
+	wire.FieldsOf(new(*Store),
+		"Books", "Outbox", "History", "Subscriptions", "Deliveries", "Idempotency", "Tenants",
+		"PricingRules", "Reviews", "Leases", "Maintenance",
+		"Carts", "Orders", "Payments", "Recommendations", "Categories", "Translations"),
+	wire.Bind(new(repository.Transactor), new(*Store)),
+)
+
+// Store 持有所有内存仓储
+type Store struct {
+	Books           repository.BookRepository
+	Outbox          repository.OutboxRepository
+	History         repository.HistoryRepository
+	Subscriptions   repository.SubscriptionRepository
+	Deliveries      repository.DeliveryRepository
+	Idempotency     repository.IdempotencyRepository
+	Tenants         repository.TenantRepository
+	PricingRules    repository.PricingRuleRepository
+	Reviews         repository.ReviewRepository
+	Leases          repository.LeaseRepository
+	Maintenance     repository.MaintenanceRepository
+	Carts           repository.CartRepository
+	Orders          repository.OrderRepository
+	Payments        repository.PaymentRepository
+	Recommendations repository.RecommendationRepository
+	Categories      repository.CategoryRepository
+	Translations    repository.TranslationRepository
+
+	txMu         sync.Mutex
+	tenants      *tenantRepository
+	books        *bookRepository
+	outbox       *outboxRepository
+	history      *historyRepository
+	reviews      *reviewRepository
+	carts        *cartRepository
+	orders       *orderRepository
+	payments     *paymentRepository
+	categories   *categoryRepository
+	translations *translationRepository
+}
+
+func NewStore(clk clock.Clock) *Store {
+	s := &Store{
+		books:    newBookRepository(clk),
+		outbox:   newOutboxRepository(clk),
+		history:  newHistoryRepository(clk),
+		reviews:  newReviewRepository(clk),
+		carts:    newCartRepository(clk),
+		orders:   newOrderRepository(clk),
+		payments: newPaymentRepository(clk),
+	}
+	s.Books = s.books
+	s.Outbox = s.outbox
+	s.History = s.history
+	s.Reviews = s.reviews
+	s.Carts = s.carts
+	s.Orders = s.orders
+	s.Payments = s.payments
+	s.Subscriptions = newSubscriptionRepository(clk)
+	s.Deliveries = newDeliveryRepository(clk)
+	s.Idempotency = newIdempotencyRepository()
+	s.tenants = newTenantRepository(clk)
+	s.Tenants = s.tenants
+	s.PricingRules = newPricingRuleRepository(clk)
+	s.Leases = newLeaseRepository()
+	s.Maintenance = &maintenanceRepository{store: s}
+	s.Recommendations = newRecommendationRepository(s)
+	s.categories = newCategoryRepository(s, clk)
+	s.Categories = s.categories
+	s.translations = newTranslationRepository(clk)
+	s.Translations = s.translations
+	return s
+}
+
+func (s *Store) Transaction(fn func(tx repository.Tx) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	snap := s.snapshot()
+	err := fn(repository.Tx{Tenants: s.tenants, Books: s.books, Outbox: s.outbox, History: s.history, Reviews: s.reviews,
+		Carts: s.carts, Orders: s.orders, Payments: s.payments,
+		Savepoints: &savepoints{store: s, points: make(map[string]storeSnapshot)}})
+	if err != nil {
+		s.restore(snap)
+	}
+	return err
+}
+
+// storeSnapshot 是事务涉及的仓储在某一时刻的状态
+type storeSnapshot struct {
+	books    bookState
+	outbox   []model.OutboxEvent
+	history  []model.BookRevision
+	reviews  reviewState
+	carts    cartState
+	orders   orderState
+	payments paymentState
+}
+
+func (s *Store) snapshot() storeSnapshot {
+	return storeSnapshot{
+		books:    s.books.snapshot(),
+		outbox:   s.outbox.snapshot(),
+		history:  s.history.snapshot(),
+		reviews:  s.reviews.snapshot(),
+		carts:    s.carts.snapshot(),
+		orders:   s.orders.snapshot(),
+		payments: s.payments.snapshot(),
+	}
+}
+
+func (s *Store) restore(snap storeSnapshot) {
+	s.books.restore(snap.books)
+	s.outbox.restore(snap.outbox)
+	s.history.restore(snap.history)
+	s.reviews.restore(snap.reviews)
+	s.carts.restore(snap.carts)
+	s.orders.restore(snap.orders)
+	s.payments.restore(snap.payments)
+}
+
+// savepoints 用快照实现事务内的保存点
+type savepoints struct {
+	store  *Store
+	points map[string]storeSnapshot
+}
+
+func (p *savepoints) Savepoint(name string) error {
+	p.points[name] = p.store.snapshot()
+	return nil
+}
+
+func (p *savepoints) RollbackTo(name string) error {
+	snap, ok := p.points[name]
+	if !ok {
+		return fmt.Errorf("savepoint %s does not exist", name)
+	}
+	p.store.restore(snap)
+	return nil
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+type tenantRepository struct {
+	mu      sync.RWMutex
+	tenants map[uint]model.Tenant
+	nextID  uint
+	clock   clock.Clock
+}
+
+func newTenantRepository(clk clock.Clock) *tenantRepository {
+	return &tenantRepository{tenants: make(map[uint]model.Tenant), nextID: 1, clock: clk}
+}
+
+func (r *tenantRepository) GetAll() ([]model.Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var tenants []model.Tenant
+	for _, t := range r.tenants {
+		if t.DeletedAt == nil {
+			tenants = append(tenants, t)
+		}
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants, nil
+}
+
+func (r *tenantRepository) GetByID(id uint) (model.Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tenants[id]
+	if !ok || t.DeletedAt != nil {
+		return model.Tenant{}, repository.ErrNotFound
+	}
+	return t, nil
+}
+
+// GetForUpdate 不需要加锁，内存仓储的事务是串行执行的
+func (r *tenantRepository) GetForUpdate(id uint) (model.Tenant, error) {
+	return r.GetByID(id)
+}
+
+func (r *tenantRepository) GetBySlug(slug string) (model.Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.tenants {
+		if t.Slug == slug && t.DeletedAt == nil {
+			return t, nil
+		}
+	}
+	return model.Tenant{}, repository.ErrNotFound
+}
+
+func (r *tenantRepository) Save(t model.Tenant) (model.Tenant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range r.tenants {
+		if v.Slug == t.Slug && v.ID != t.ID {
+			return t, repository.ErrDuplicate
+		}
+	}
+	now := r.clock.Now()
+	if t.ID == 0 {
+		t.ID = r.nextID
+		r.nextID++
+		t.CreatedAt = now
+	}
+	t.UpdatedAt = now
+	r.tenants[t.ID] = t
+	return t, nil
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+type translationRepository struct {
+	mu           sync.RWMutex
+	translations map[uint]model.BookTranslation
+	nextID       uint
+	clock        clock.Clock
+}
+
+func newTranslationRepository(clk clock.Clock) *translationRepository {
+	return &translationRepository{translations: make(map[uint]model.BookTranslation), nextID: 1, clock: clk}
+}
+
+func (r *translationRepository) List(ctx context.Context, bookID uint) ([]model.BookTranslation, error) {
+	ts, err := r.Find(ctx, []uint{bookID}, nil)
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Locale < ts[j].Locale })
+	return ts, err
+}
+
+// Find 的 locales 为 nil 时不按语言过滤，只在 List 中使用
+func (r *translationRepository) Find(ctx context.Context, bookIDs []uint, locales []string) ([]model.BookTranslation, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	books := make(map[uint]bool, len(bookIDs))
+	for _, id := range bookIDs {
+		books[id] = true
+	}
+	wanted := make(map[string]bool, len(locales))
+	for _, l := range locales {
+		wanted[l] = true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var ts []model.BookTranslation
+	for _, tr := range r.translations {
+		if tr.TenantID == t.ID && books[tr.BookID] && (locales == nil || wanted[tr.Locale]) {
+			ts = append(ts, tr)
+		}
+	}
+	return ts, nil
+}
+
+func (r *translationRepository) Put(ctx context.Context, tr model.BookTranslation) (model.BookTranslation, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tr, tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	for id, existing := range r.translations {
+		if existing.TenantID == t.ID && existing.BookID == tr.BookID && existing.Locale == tr.Locale {
+			existing.Title = tr.Title
+			existing.Description = tr.Description
+			existing.UpdatedAt = now
+			r.translations[id] = existing
+			return existing, nil
+		}
+	}
+	tr.ID = r.nextID
+	r.nextID++
+	tr.TenantID = t.ID
+	tr.CreatedAt = now
+	tr.UpdatedAt = now
+	r.translations[tr.ID] = tr
+	return tr, nil
+}
+
+func (r *translationRepository) Delete(ctx context.Context, bookID uint, locale string) error {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, tr := range r.translations {
+		if tr.TenantID == t.ID && tr.BookID == bookID && tr.Locale == locale {
+			delete(r.translations, id)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *translationRepository) purgeBooks(purged map[uint]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, tr := range r.translations {
+		if purged[tr.BookID] {
+			delete(r.translations, id)
+		}
+	}
+}
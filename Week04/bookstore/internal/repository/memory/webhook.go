@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+type subscriptionRepository struct {
+	mu     sync.RWMutex
+	subs   map[uint]model.WebhookSubscription
+	nextID uint
+	clock  clock.Clock
+}
+
+func newSubscriptionRepository(clk clock.Clock) *subscriptionRepository {
+	return &subscriptionRepository{subs: make(map[uint]model.WebhookSubscription), nextID: 1, clock: clk}
+}
+
+func (s *subscriptionRepository) GetAll(ctx context.Context) ([]model.WebhookSubscription, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrNoTenant
+	}
+	return s.ListByTenant(t.ID)
+}
+
+func (s *subscriptionRepository) GetByID(ctx context.Context, id uint) (model.WebhookSubscription, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return model.WebhookSubscription{}, tenant.ErrNoTenant
+	}
+	sub, err := s.Find(id)
+	if err == nil && sub.TenantID != t.ID {
+		return model.WebhookSubscription{}, repository.ErrNotFound
+	}
+	return sub, err
+}
+
+func (s *subscriptionRepository) ListByTenant(tenantID uint) ([]model.WebhookSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var subs []model.WebhookSubscription
+	for _, sub := range s.subs {
+		if sub.DeletedAt == nil && sub.TenantID == tenantID {
+			subs = append(subs, sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs, nil
+}
+
+func (s *subscriptionRepository) Find(id uint) (model.WebhookSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sub, ok := s.subs[id]
+	if !ok || sub.DeletedAt != nil {
+		return model.WebhookSubscription{}, repository.ErrNotFound
+	}
+	return sub, nil
+}
+
+func (s *subscriptionRepository) Save(ctx context.Context, sub model.WebhookSubscription) (model.WebhookSubscription, error) {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return sub, tenant.ErrNoTenant
+	}
+	sub.TenantID = t.ID
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	if sub.ID == 0 {
+		sub.ID = s.nextID
+		s.nextID++
+		sub.CreatedAt = now
+	}
+	sub.UpdatedAt = now
+	s.subs[sub.ID] = sub
+	return sub, nil
+}
+
+func (s *subscriptionRepository) Delete(ctx context.Context, sub model.WebhookSubscription) error {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.subs[sub.ID]
+	if !ok || stored.TenantID != t.ID {
+		return nil
+	}
+	now := s.clock.Now()
+	stored.DeletedAt = &now
+	s.subs[sub.ID] = stored
+	return nil
+}
+
+type deliveryRepository struct {
+	mu         sync.RWMutex
+	deliveries []model.WebhookDelivery
+	clock      clock.Clock
+}
+
+func newDeliveryRepository(clk clock.Clock) *deliveryRepository {
+	return &deliveryRepository{clock: clk}
+}
+
+func (d *deliveryRepository) AddOnce(delivery model.WebhookDelivery) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, v := range d.deliveries {
+		if v.SubscriptionID == delivery.SubscriptionID && v.EventID == delivery.EventID {
+			return nil
+		}
+	}
+	now := d.clock.Now()
+	delivery.ID = uint(len(d.deliveries) + 1)
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+	d.deliveries = append(d.deliveries, delivery)
+	return nil
+}
+
+func (d *deliveryRepository) Claim(token string, now, until time.Time, limit int) ([]model.WebhookDelivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var due []model.WebhookDelivery
+	for i, v := range d.deliveries {
+		if len(due) >= limit {
+			break
+		}
+		if v.Status == model.DeliveryPending && !v.NextAttemptAt.After(now) {
+			d.deliveries[i].ClaimToken, d.deliveries[i].NextAttemptAt = token, until
+			due = append(due, d.deliveries[i])
+		}
+	}
+	return due, nil
+}
+
+func (d *deliveryRepository) ListBySubscription(subscriptionID uint, status string, limit int) ([]model.WebhookDelivery, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var deliveries []model.WebhookDelivery
+	for i := len(d.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		v := d.deliveries[i]
+		if v.SubscriptionID == subscriptionID && (status == "" || v.Status == status) {
+			deliveries = append(deliveries, v)
+		}
+	}
+	return deliveries, nil
+}
+
+func (d *deliveryRepository) Update(delivery model.WebhookDelivery) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if i := int(delivery.ID) - 1; i >= 0 && i < len(d.deliveries) {
+		delivery.UpdatedAt = d.clock.Now()
+		d.deliveries[i] = delivery
+	}
+	return nil
+}
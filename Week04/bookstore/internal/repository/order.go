@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// ErrConflict 表示记录已经被并发修改，不满足更新的前提条件
+var ErrConflict = i18n.NewError("record.conflict")
+
+// CartRepository 存取顾客的购物车，所有方法都限定在 ctx 中的店铺内
+type CartRepository interface {
+	// List 按加入顺序返回顾客购物车中的图书
+	List(ctx context.Context, customer string) ([]model.CartItem, error)
+	// Put 把顾客购物车中某本书的数量设置为 item.Quantity，没有时新增
+	Put(ctx context.Context, item model.CartItem) (model.CartItem, error)
+	Remove(ctx context.Context, customer string, bookID uint) error
+	Clear(ctx context.Context, customer string) error
+}
+
+// OrderRepository 存取订单，所有方法都限定在 ctx 中的店铺内，返回的订单都带着订单行
+type OrderRepository interface {
+	Create(ctx context.Context, order model.Order) (model.Order, error)
+	GetByID(ctx context.Context, id uint) (model.Order, error)
+	// List 按创建顺序倒序返回订单，customer 或 status 为空时不按它过滤
+	List(ctx context.Context, customer, status string) ([]model.Order, error)
+	// UpdateStatus 只在订单当前是 from 状态时修改为 to，否则返回 ErrConflict
+	UpdateStatus(ctx context.Context, id uint, from, to string) (model.Order, error)
+}
+
+type cartRepository struct {
+	db *gorm.DB
+}
+
+func NewCartRepository(db *gorm.DB) CartRepository {
+	return &cartRepository{db: db}
+}
+
+func (r *cartRepository) List(ctx context.Context, customer string) ([]model.CartItem, error) {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	var items []model.CartItem
+	err = db.Where("customer = ?", customer).Order("id").Find(&items).Error
+	return items, err
+}
+
+func (r *cartRepository) Put(ctx context.Context, item model.CartItem) (model.CartItem, error) {
+	db, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return item, err
+	}
+	item.TenantID = tenantID
+	var existing model.CartItem
+	err = db.Where("customer = ? AND book_id = ?", item.Customer, item.BookID).First(&existing).Error
+	if gorm.IsRecordNotFoundError(err) {
+		err = r.db.Create(&item).Error
+		if isDuplicateEntry(err) {
+			return item, ErrConflict
+		}
+		return item, err
+	}
+	if err != nil {
+		return item, err
+	}
+	existing.Quantity = item.Quantity
+	err = r.db.Save(&existing).Error
+	return existing, err
+}
+
+func (r *cartRepository) Remove(ctx context.Context, customer string, bookID uint) error {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Where("customer = ? AND book_id = ?", customer, bookID).Delete(&model.CartItem{}).Error
+}
+
+func (r *cartRepository) Clear(ctx context.Context, customer string) error {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	return db.Where("customer = ?", customer).Delete(&model.CartItem{}).Error
+}
+
+type orderRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderRepository(db *gorm.DB) OrderRepository {
+	return &orderRepository{db: db}
+}
+
+func (r *orderRepository) Create(ctx context.Context, order model.Order) (model.Order, error) {
+	_, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return order, err
+	}
+	order.TenantID = tenantID
+	// gorm 会在同一个事务里写入订单行
+	err = r.db.Create(&order).Error
+	return order, err
+}
+
+func (r *orderRepository) GetByID(ctx context.Context, id uint) (model.Order, error) {
+	var order model.Order
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return order, err
+	}
+	err = db.Preload("Items", itemsInOrder).First(&order, id).Error
+	return order, translateError(err)
+}
+
+func (r *orderRepository) List(ctx context.Context, customer, status string) ([]model.Order, error) {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	if customer != "" {
+		db = db.Where("customer = ?", customer)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	var orders []model.Order
+	err = db.Preload("Items", itemsInOrder).Order("id DESC").Find(&orders).Error
+	return orders, err
+}
+
+func (r *orderRepository) UpdateStatus(ctx context.Context, id uint, from, to string) (model.Order, error) {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return model.Order{}, err
+	}
+	res := db.Model(&model.Order{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	if res.Error != nil {
+		return model.Order{}, res.Error
+	}
+	order, err := r.GetByID(ctx, id)
+	if err == nil && res.RowsAffected == 0 {
+		err = ErrConflict
+	}
+	return order, err
+}
+
+func itemsInOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
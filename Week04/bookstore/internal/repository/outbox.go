@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type OutboxRepository interface {
+	Add(event model.OutboxEvent) error
+	// Claim 用 token 认领最多 limit 个事件，租期到 until 为止，按写入顺序返回。
+	// 只认领每本书最早的未投递事件已经到期且没有被其他实例认领的书，
+	// 同一本书的事件只会被一个实例按顺序投递
+	Claim(token string, now, until time.Time, limit int) ([]model.OutboxEvent, error)
+	// Release 放弃认领还没有投递的事件，其他实例可以马上认领
+	Release(ids []uint) error
+	MarkPublished(id uint) error
+	// MarkFailed 记录一次失败，到 next 之后再重试
+	MarkFailed(id uint, cause error, next time.Time) error
+	// MarkDead 记录最后一次失败，事件不再投递，同一本书的后续事件继续投递
+	MarkDead(id uint, cause error) error
+	// LastID 返回最大的事件 ID，没有事件时返回 0
+	LastID() (uint, error)
+	// ListAfter 按 ID 顺序返回 ID 大于 id 的事件，不管是否已经投递
+	ListAfter(id uint, limit int) ([]model.OutboxEvent, error)
+	// ListByIDs 返回 ids 中存在的事件
+	ListByIDs(ids []uint) ([]model.OutboxEvent, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (o *outboxRepository) Add(event model.OutboxEvent) error {
+	return o.db.Create(&event).Error
+}
+
+// Claim 先认领每本书最早的事件，抢到的书再认领后面的事件。
+// 同一本书最早的事件只有一个实例能认领成功，没认领到的实例不会去认领后面的事件
+func (o *outboxRepository) Claim(token string, now, until time.Time, limit int) ([]model.OutboxEvent, error) {
+	var heads []model.OutboxEvent
+	err := o.db.Raw(`SELECT e.id, e.aggregate_id FROM outbox_events e
+		WHERE e.published_at IS NULL AND e.dead_at IS NULL
+		AND (e.next_attempt_at IS NULL OR e.next_attempt_at <= ?)
+		AND (e.claimed_until IS NULL OR e.claimed_until <= ?)
+		AND NOT EXISTS (SELECT 1 FROM outbox_events p
+			WHERE p.aggregate_id = e.aggregate_id AND p.id < e.id AND p.published_at IS NULL AND p.dead_at IS NULL)
+		ORDER BY e.id LIMIT ?`, now, now, limit).Scan(&heads).Error
+	if err != nil || len(heads) == 0 {
+		return nil, err
+	}
+	ids := make([]uint, len(heads))
+	for i, h := range heads {
+		ids[i] = h.ID
+	}
+	claim := map[string]interface{}{"claim_token": token, "claimed_until": until}
+	err = o.db.Model(&model.OutboxEvent{}).
+		Where("id IN (?) AND published_at IS NULL AND dead_at IS NULL", ids).
+		Where("claimed_until IS NULL OR claimed_until <= ?", now).
+		Updates(claim).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var won []uint
+	if err := o.db.Model(&model.OutboxEvent{}).Where("claim_token = ?", token).Pluck("aggregate_id", &won).Error; err != nil {
+		return nil, err
+	}
+	if len(won) > 0 {
+		err = o.db.Model(&model.OutboxEvent{}).
+			Where("aggregate_id IN (?) AND published_at IS NULL AND dead_at IS NULL", won).
+			Where("claimed_until IS NULL OR claimed_until <= ?", now).
+			Order("id").Limit(limit - len(won)).Updates(claim).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var events []model.OutboxEvent
+	err = o.db.Where("claim_token = ? AND published_at IS NULL AND dead_at IS NULL", token).
+		Order("id").Find(&events).Error
+	return events, err
+}
+
+func (o *outboxRepository) Release(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return o.db.Model(&model.OutboxEvent{}).Where("id IN (?)", ids).
+		Updates(map[string]interface{}{"claim_token": "", "claimed_until": nil}).Error
+}
+
+func (o *outboxRepository) MarkPublished(id uint) error {
+	return o.db.Model(&model.OutboxEvent{}).Where("id = ?", id).
+		Update("published_at", time.Now()).Error
+}
+
+func (o *outboxRepository) MarkFailed(id uint, cause error, next time.Time) error {
+	return o.db.Model(&model.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      cause.Error(),
+			"next_attempt_at": next,
+			"claim_token":     "",
+			"claimed_until":   nil,
+		}).Error
+}
+
+func (o *outboxRepository) MarkDead(id uint, cause error) error {
+	return o.db.Model(&model.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": cause.Error(),
+			"dead_at":    time.Now(),
+		}).Error
+}
+
+func (o *outboxRepository) LastID() (uint, error) {
+	var ids []uint
+	err := o.db.Model(&model.OutboxEvent{}).Order("id DESC").Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+func (o *outboxRepository) ListAfter(id uint, limit int) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	err := o.db.Where("id > ?", id).Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+func (o *outboxRepository) ListByIDs(ids []uint) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	if len(ids) == 0 {
+		return events, nil
+	}
+	err := o.db.Where("id IN (?)", ids).Order("id").Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// PaymentRepository 存取订单的支付，除了 GetByProviderRef 都限定在 ctx 中的店铺内
+type PaymentRepository interface {
+	Create(ctx context.Context, p model.Payment) (model.Payment, error)
+	// Latest 返回订单最新的一次支付，没有时返回 ErrNotFound
+	Latest(ctx context.Context, orderID uint) (model.Payment, error)
+	// GetByProviderRef 按网关上的支付 ID 查询，不限定店铺，用于处理网关回调
+	GetByProviderRef(ref string) (model.Payment, error)
+	// UpdateStatus 只在支付当前是 from 状态时把状态改为 p.Status 并保存 p.DeclineCode，否则返回 ErrConflict
+	UpdateStatus(ctx context.Context, p model.Payment, from string) (model.Payment, error)
+}
+
+type paymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository(db *gorm.DB) PaymentRepository {
+	return &paymentRepository{db: db}
+}
+
+func (r *paymentRepository) Create(ctx context.Context, p model.Payment) (model.Payment, error) {
+	_, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return p, err
+	}
+	p.TenantID = tenantID
+	err = r.db.Create(&p).Error
+	if isDuplicateEntry(err) {
+		return p, ErrDuplicate
+	}
+	return p, err
+}
+
+func (r *paymentRepository) Latest(ctx context.Context, orderID uint) (model.Payment, error) {
+	var p model.Payment
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return p, err
+	}
+	err = db.Where("order_id = ?", orderID).Order("id DESC").First(&p).Error
+	return p, translateError(err)
+}
+
+func (r *paymentRepository) GetByProviderRef(ref string) (model.Payment, error) {
+	var p model.Payment
+	err := r.db.Where("provider_ref = ?", ref).First(&p).Error
+	return p, translateError(err)
+}
+
+func (r *paymentRepository) UpdateStatus(ctx context.Context, p model.Payment, from string) (model.Payment, error) {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return p, err
+	}
+	res := db.Model(&model.Payment{}).Where("id = ? AND status = ?", p.ID, from).
+		Updates(map[string]interface{}{"status": p.Status, "decline_code": p.DeclineCode})
+	if res.Error != nil {
+		return p, res.Error
+	}
+	var saved model.Payment
+	if err := db.First(&saved, p.ID).Error; err != nil {
+		return p, translateError(err)
+	}
+	if res.RowsAffected == 0 {
+		return saved, ErrConflict
+	}
+	return saved, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// PricingRuleRepository 和 BookRepository 一样限定在 ctx 中的店铺内
+type PricingRuleRepository interface {
+	GetAll(ctx context.Context) ([]model.PricingRule, error)
+	GetByID(ctx context.Context, id uint) (model.PricingRule, error)
+	// ListEffective 返回在 now 时刻处于有效期内的规则
+	ListEffective(ctx context.Context, now time.Time) ([]model.PricingRule, error)
+	Save(ctx context.Context, rule model.PricingRule) (model.PricingRule, error)
+	Delete(ctx context.Context, rule model.PricingRule) error
+}
+
+type pricingRuleRepository struct {
+	db *Cluster
+}
+
+func NewPricingRuleRepository(db *Cluster) PricingRuleRepository {
+	return &pricingRuleRepository{db: db}
+}
+
+func (p *pricingRuleRepository) reader(ctx context.Context) (*gorm.DB, uint, error) {
+	return scoped(ctx, p.db.Reader(ctx))
+}
+
+func (p *pricingRuleRepository) writer(ctx context.Context) (*gorm.DB, uint, error) {
+	return scoped(ctx, p.db.Writer(ctx))
+}
+
+func (p *pricingRuleRepository) GetAll(ctx context.Context) ([]model.PricingRule, error) {
+	db, _, err := p.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rules []model.PricingRule
+	err = db.Order("priority, id").Find(&rules).Error
+	return rules, err
+}
+
+func (p *pricingRuleRepository) GetByID(ctx context.Context, id uint) (model.PricingRule, error) {
+	var rule model.PricingRule
+	db, _, err := p.reader(ctx)
+	if err != nil {
+		return rule, err
+	}
+	err = db.First(&rule, id).Error
+	return rule, translateError(err)
+}
+
+func (p *pricingRuleRepository) ListEffective(ctx context.Context, now time.Time) ([]model.PricingRule, error) {
+	db, _, err := p.reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rules []model.PricingRule
+	err = db.Where("(starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", now, now).
+		Order("priority, id").Find(&rules).Error
+	return rules, err
+}
+
+func (p *pricingRuleRepository) Save(ctx context.Context, rule model.PricingRule) (model.PricingRule, error) {
+	db, tenantID, err := p.writer(ctx)
+	if err != nil {
+		return rule, err
+	}
+	if rule.ID != 0 {
+		if err := db.First(&model.PricingRule{}, rule.ID).Error; err != nil {
+			return rule, translateError(err)
+		}
+	}
+	rule.TenantID = tenantID
+	err = db.Save(&rule).Error
+	return rule, err
+}
+
+func (p *pricingRuleRepository) Delete(ctx context.Context, rule model.PricingRule) error {
+	db, _, err := p.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Delete(&rule).Error
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"log"
+
+	"github.com/google/wire"
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+)
+
+// MySQLSet 提供基于 MySQL 的全部仓储，memory.ProviderSet 是它的内存版替代
+var MySQLSet = wire.NewSet(
+	NewDB,
+	NewCluster,
+	NewBreaker,
+	NewBookRepositoryWithBreaker,
+	NewOutboxRepository,
+	NewHistoryRepository,
+	NewSubscriptionRepository,
+	NewDeliveryRepository,
+	NewIdempotencyRepository,
+	NewTenantRepositoryWithBreaker,
+	NewPricingRuleRepository,
+	NewReviewRepository,
+	NewLeaseRepository,
+	NewMaintenanceRepository,
+	NewCartRepository,
+	NewOrderRepository,
+	NewPaymentRepository,
+	NewRecommendationRepository,
+	NewCategoryRepository,
+	NewTranslationRepository,
+	NewTransactor,
+)
+
+// NewDB 打开数据库连接，返回的 cleanup 负责关闭连接
+func NewDB(cfg config.Config, clk clock.Clock) (*gorm.DB, func(), error) {
+	db, err := gorm.Open("mysql", cfg.DSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	db.SetNowFuncOverride(clk.Now)
+
+	// db.AutoMigrate(&model.Book{}, &model.OutboxEvent{},
+	// 	&model.WebhookSubscription{}, &model.WebhookDelivery{}, &model.BookRevision{},
+	// 	&model.IdempotencyRecord{}, &model.Tenant{}, &model.PricingRule{}, &model.Review{}, &model.JobLease{},
+	// 	&model.CartItem{}, &model.Order{}, &model.OrderItem{}, &model.Payment{},
+	// 	&model.BookRecommendation{}, &model.Category{}, &model.BookCategory{},
+	// 	&model.BookTranslation{})
+
+	cleanup := func() {
+		if err := db.Close(); err != nil {
+			log.Printf("close db err: %v", err)
+		}
+	}
+	return db, cleanup, nil
+}
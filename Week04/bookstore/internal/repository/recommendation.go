@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// purchasedStatuses 是计入推荐和销量的订单状态
+var purchasedStatuses = []string{model.OrderPaid, model.OrderShipping, model.OrderShipped}
+
+// Purchase 表示顾客买过某本书
+type Purchase struct {
+	TenantID uint
+	Customer string
+	BookID   uint
+}
+
+// RecommendationRepository 存取推荐，Purchases 和 Replace 给定时任务使用，不限定店铺
+type RecommendationRepository interface {
+	// Purchases 返回所有店铺已支付或已发货的订单中顾客买过的图书，同一个顾客买过多次的书只返回一次
+	Purchases() ([]Purchase, error)
+	// Replace 用 recs 替换店铺的全部推荐
+	Replace(tenantID uint, recs []model.BookRecommendation) error
+	// Related 按 Score 从高到低返回和图书一起被买的至多 limit 本书
+	Related(ctx context.Context, bookID uint, limit int) ([]model.BookRecommendation, error)
+	// BestSellers 按销量从高到低返回店铺至多 limit 本书的 ID
+	BestSellers(ctx context.Context, limit int) ([]uint, error)
+}
+
+type recommendationRepository struct {
+	db *gorm.DB
+}
+
+func NewRecommendationRepository(db *gorm.DB) RecommendationRepository {
+	return &recommendationRepository{db: db}
+}
+
+func (r *recommendationRepository) Purchases() ([]Purchase, error) {
+	var purchases []Purchase
+	err := r.db.Raw(`SELECT DISTINCT o.tenant_id, o.customer, i.book_id
+		FROM orders o JOIN order_items i ON i.order_id = o.id
+		WHERE o.status IN (?)`, purchasedStatuses).Scan(&purchases).Error
+	return purchases, err
+}
+
+func (r *recommendationRepository) Replace(tenantID uint, recs []model.BookRecommendation) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ?", tenantID).Delete(&model.BookRecommendation{}).Error; err != nil {
+			return err
+		}
+		for _, rec := range recs {
+			rec.TenantID = tenantID
+			if err := tx.Create(&rec).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *recommendationRepository) Related(ctx context.Context, bookID uint, limit int) ([]model.BookRecommendation, error) {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	var recs []model.BookRecommendation
+	err = db.Where("book_id = ?", bookID).Order("score DESC, related_book_id").Limit(limit).Find(&recs).Error
+	return recs, err
+}
+
+func (r *recommendationRepository) BestSellers(ctx context.Context, limit int) ([]uint, error) {
+	_, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct{ BookID uint }
+	err = r.db.Raw(`SELECT i.book_id FROM orders o JOIN order_items i ON i.order_id = o.id
+		WHERE o.tenant_id = ? AND o.status IN (?)
+		GROUP BY i.book_id ORDER BY SUM(i.quantity) DESC, i.book_id LIMIT ?`,
+		tenantID, purchasedStatuses, limit).Scan(&rows).Error
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.BookID
+	}
+	return ids, err
+}
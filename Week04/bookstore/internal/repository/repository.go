@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/breaker"
+)
+
+// ErrNotFound 表示没有查询到记录，上层不需要关心底层用的是 gorm 还是别的存储
+var ErrNotFound = errors.New("record not found")
+
+// ErrDuplicate 表示违反了唯一约束
+var ErrDuplicate = errors.New("duplicate record")
+
+// Tx 聚合了同一个数据库事务内可用的仓储
+type Tx struct {
+	Tenants  TenantRepository
+	Books    BookRepository
+	Outbox   OutboxRepository
+	History  HistoryRepository
+	Reviews  ReviewRepository
+	Carts    CartRepository
+	Orders   OrderRepository
+	Payments PaymentRepository
+	// Savepoints 让事务中的一部分操作失败时只撤销这一部分
+	Savepoints Savepoints
+}
+
+// Savepoints 在事务内设置保存点，RollbackTo 撤销保存点之后的所有修改，事务本身继续有效
+type Savepoints interface {
+	Savepoint(name string) error
+	RollbackTo(name string) error
+}
+
+// Transactor 在一个数据库事务中执行 fn，fn 返回错误时整个事务回滚。
+// 事务超时返回时 fn 可能还在执行，调用方只在返回 nil 时读取 fn 写入的变量
+type Transactor interface {
+	Transaction(fn func(tx Tx) error) error
+}
+
+type transactor struct {
+	db      *gorm.DB
+	breaker *breaker.Breaker
+}
+
+// NewTransactor 返回的事务整体受熔断器保护，熔断器打开时不会开启事务，
+// 超时的事务会被回滚
+func NewTransactor(db *gorm.DB, b *breaker.Breaker) Transactor {
+	return &transactor{db: db, breaker: b}
+}
+
+func (t *transactor) Transaction(fn func(tx Tx) error) error {
+	return t.breaker.Do(context.Background(), func(ctx context.Context) error {
+		return t.db.Transaction(func(db *gorm.DB) error {
+			err := fn(Tx{
+				Tenants:  NewTenantRepository(db),
+				Books:    NewBookRepository(singleDB(db)),
+				Outbox:   NewOutboxRepository(db),
+				History:  NewHistoryRepository(db),
+				Reviews:  NewReviewRepository(db),
+				Carts:    NewCartRepository(db),
+				Orders:   NewOrderRepository(db),
+				Payments: NewPaymentRepository(db),
+
+				Savepoints: savepoints{db: db},
+			})
+			if err != nil {
+				return err
+			}
+			// 超时后调用方已经按失败处理，不能再提交
+			return ctx.Err()
+		})
+	})
+}
+
+type savepoints struct {
+	db *gorm.DB
+}
+
+// Savepoint 的 name 由调用方生成，不能来自用户输入
+func (s savepoints) Savepoint(name string) error {
+	return s.db.Exec("SAVEPOINT " + name).Error
+}
+
+func (s savepoints) RollbackTo(name string) error {
+	return s.db.Exec("ROLLBACK TO SAVEPOINT " + name).Error
+}
+
+func translateError(err error) error {
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrNotFound
+	}
+	return err
+}
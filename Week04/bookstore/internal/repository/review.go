@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// ReviewRepository 按图书存取评论，调用方负责确认图书属于当前店铺
+type ReviewRepository interface {
+	// ListByBook 按创建顺序返回评论，status 为空时返回所有状态
+	ListByBook(bookID uint, status string) ([]model.Review, error)
+	// GetForUpdate 在事务中读取评论并锁住这一行，直到事务结束，
+	// 并发修改同一条评论时后来的事务读到的是前一个事务提交后的状态
+	GetForUpdate(bookID, id uint) (model.Review, error)
+	// Save 在同一用户重复评论同一本书时返回 ErrDuplicate
+	Save(review model.Review) (model.Review, error)
+	Delete(review model.Review) error
+}
+
+type reviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) ReviewRepository {
+	return &reviewRepository{db: db}
+}
+
+func (r *reviewRepository) ListByBook(bookID uint, status string) ([]model.Review, error) {
+	db := r.db.Where("book_id = ?", bookID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	var reviews []model.Review
+	err := db.Order("id").Find(&reviews).Error
+	return reviews, err
+}
+
+func (r *reviewRepository) GetForUpdate(bookID, id uint) (model.Review, error) {
+	var review model.Review
+	err := r.db.Set("gorm:query_option", "FOR UPDATE").Where("book_id = ?", bookID).First(&review, id).Error
+	return review, translateError(err)
+}
+
+func (r *reviewRepository) Save(review model.Review) (model.Review, error) {
+	err := r.db.Save(&review).Error
+	if isDuplicateEntry(err) {
+		return review, ErrDuplicate
+	}
+	return review, err
+}
+
+func (r *reviewRepository) Delete(review model.Review) error {
+	return r.db.Delete(&review).Error
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+type TenantRepository interface {
+	GetAll() ([]model.Tenant, error)
+	GetByID(id uint) (model.Tenant, error)
+	// GetForUpdate 在事务中读取店铺并锁住这一行，直到事务结束，
+	// 用来串行化同一店铺内依赖配额的写操作
+	GetForUpdate(id uint) (model.Tenant, error)
+	GetBySlug(slug string) (model.Tenant, error)
+	Save(t model.Tenant) (model.Tenant, error)
+}
+
+type tenantRepository struct {
+	db *gorm.DB
+}
+
+func NewTenantRepository(db *gorm.DB) TenantRepository {
+	return &tenantRepository{db: db}
+}
+
+func (r *tenantRepository) GetAll() ([]model.Tenant, error) {
+	var tenants []model.Tenant
+	err := r.db.Find(&tenants).Error
+	return tenants, err
+}
+
+func (r *tenantRepository) GetByID(id uint) (model.Tenant, error) {
+	var t model.Tenant
+	err := r.db.First(&t, id).Error
+	return t, translateError(err)
+}
+
+func (r *tenantRepository) GetForUpdate(id uint) (model.Tenant, error) {
+	var t model.Tenant
+	err := r.db.Set("gorm:query_option", "FOR UPDATE").First(&t, id).Error
+	return t, translateError(err)
+}
+
+func (r *tenantRepository) GetBySlug(slug string) (model.Tenant, error) {
+	var t model.Tenant
+	err := r.db.Where("slug = ?", slug).First(&t).Error
+	return t, translateError(err)
+}
+
+func (r *tenantRepository) Save(t model.Tenant) (model.Tenant, error) {
+	err := r.db.Save(&t).Error
+	if isDuplicateEntry(err) {
+		return t, ErrDuplicate
+	}
+	return t, err
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// TranslationRepository 存取图书的多语言内容，所有方法都限定在 ctx 中的店铺内
+type TranslationRepository interface {
+	// List 按 Locale 返回图书的所有翻译
+	List(ctx context.Context, bookID uint) ([]model.BookTranslation, error)
+	// Find 返回这些图书在 locales 中任意一种语言下的翻译
+	Find(ctx context.Context, bookIDs []uint, locales []string) ([]model.BookTranslation, error)
+	// Put 新建或者覆盖图书在 t.Locale 下的翻译
+	Put(ctx context.Context, t model.BookTranslation) (model.BookTranslation, error)
+	Delete(ctx context.Context, bookID uint, locale string) error
+}
+
+type translationRepository struct {
+	db *gorm.DB
+}
+
+func NewTranslationRepository(db *gorm.DB) TranslationRepository {
+	return &translationRepository{db: db}
+}
+
+func (r *translationRepository) List(ctx context.Context, bookID uint) ([]model.BookTranslation, error) {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	var ts []model.BookTranslation
+	err = db.Where("book_id = ?", bookID).Order("locale").Find(&ts).Error
+	return ts, err
+}
+
+func (r *translationRepository) Find(ctx context.Context, bookIDs []uint, locales []string) ([]model.BookTranslation, error) {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil || len(bookIDs) == 0 || len(locales) == 0 {
+		return nil, err
+	}
+	var ts []model.BookTranslation
+	err = db.Where("book_id IN (?) AND locale IN (?)", bookIDs, locales).Find(&ts).Error
+	return ts, err
+}
+
+func (r *translationRepository) Put(ctx context.Context, t model.BookTranslation) (model.BookTranslation, error) {
+	db, tenantID, err := scoped(ctx, r.db)
+	if err != nil {
+		return t, err
+	}
+	t.TenantID = tenantID
+	var existing model.BookTranslation
+	err = db.Where("book_id = ? AND locale = ?", t.BookID, t.Locale).First(&existing).Error
+	if gorm.IsRecordNotFoundError(err) {
+		err = r.db.Create(&t).Error
+		if isDuplicateEntry(err) {
+			return t, ErrConflict
+		}
+		return t, err
+	}
+	if err != nil {
+		return t, err
+	}
+	existing.Title = t.Title
+	existing.Description = t.Description
+	err = r.db.Save(&existing).Error
+	return existing, err
+}
+
+func (r *translationRepository) Delete(ctx context.Context, bookID uint, locale string) error {
+	db, _, err := scoped(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	result := db.Where("book_id = ? AND locale = ?", bookID, locale).Delete(&model.BookTranslation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// SubscriptionRepository 存取 webhook 订阅，除了 ListByTenant 和 Find 都限定在 ctx 中的店铺内
+type SubscriptionRepository interface {
+	GetAll(ctx context.Context) ([]model.WebhookSubscription, error)
+	GetByID(ctx context.Context, id uint) (model.WebhookSubscription, error)
+	Save(ctx context.Context, sub model.WebhookSubscription) (model.WebhookSubscription, error)
+	Delete(ctx context.Context, sub model.WebhookSubscription) error
+	// ListByTenant 返回店铺的全部订阅，用于把 outbox 事件分发给所属店铺的订阅
+	ListByTenant(tenantID uint) ([]model.WebhookSubscription, error)
+	// Find 按 ID 查询订阅，不限定店铺，用于后台推送
+	Find(id uint) (model.WebhookSubscription, error)
+}
+
+type subscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &subscriptionRepository{db: db}
+}
+
+func (s *subscriptionRepository) GetAll(ctx context.Context) ([]model.WebhookSubscription, error) {
+	db, _, err := scoped(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	var subs []model.WebhookSubscription
+	err = db.Find(&subs).Error
+	return subs, err
+}
+
+func (s *subscriptionRepository) GetByID(ctx context.Context, id uint) (model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+	db, _, err := scoped(ctx, s.db)
+	if err != nil {
+		return sub, err
+	}
+	err = db.First(&sub, id).Error
+	return sub, translateError(err)
+}
+
+func (s *subscriptionRepository) Save(ctx context.Context, sub model.WebhookSubscription) (model.WebhookSubscription, error) {
+	_, tenantID, err := scoped(ctx, s.db)
+	if err != nil {
+		return sub, err
+	}
+	sub.TenantID = tenantID
+	err = s.db.Save(&sub).Error
+	return sub, err
+}
+
+func (s *subscriptionRepository) Delete(ctx context.Context, sub model.WebhookSubscription) error {
+	db, _, err := scoped(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	return db.Delete(&sub).Error
+}
+
+func (s *subscriptionRepository) ListByTenant(tenantID uint) ([]model.WebhookSubscription, error) {
+	var subs []model.WebhookSubscription
+	err := s.db.Where("tenant_id = ?", tenantID).Find(&subs).Error
+	return subs, err
+}
+
+func (s *subscriptionRepository) Find(id uint) (model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+	err := s.db.First(&sub, id).Error
+	return sub, translateError(err)
+}
+
+type DeliveryRepository interface {
+	// AddOnce 按 (SubscriptionID, EventID) 去重写入，outbox 重复投递同一事件时不会重复推送
+	AddOnce(delivery model.WebhookDelivery) error
+	// Claim 用 token 认领最多 limit 个到期需要推送的投递，并把 NextAttemptAt 推迟到 until，
+	// 租期内其他实例不会再认领，认领的实例没有更新投递就退出时，租期过后重新推送
+	Claim(token string, now, until time.Time, limit int) ([]model.WebhookDelivery, error)
+	ListBySubscription(subscriptionID uint, status string, limit int) ([]model.WebhookDelivery, error)
+	Update(delivery model.WebhookDelivery) error
+}
+
+type deliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewDeliveryRepository(db *gorm.DB) DeliveryRepository {
+	return &deliveryRepository{db: db}
+}
+
+func (d *deliveryRepository) AddOnce(delivery model.WebhookDelivery) error {
+	return d.db.Where(model.WebhookDelivery{
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+	}).Attrs(delivery).FirstOrCreate(&delivery).Error
+}
+
+func (d *deliveryRepository) Claim(token string, now, until time.Time, limit int) ([]model.WebhookDelivery, error) {
+	err := d.db.Model(&model.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+		Order("id").Limit(limit).
+		Updates(map[string]interface{}{"claim_token": token, "next_attempt_at": until}).Error
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []model.WebhookDelivery
+	err = d.db.Where("claim_token = ? AND status = ?", token, model.DeliveryPending).
+		Order("id").Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (d *deliveryRepository) ListBySubscription(subscriptionID uint, status string, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	db := d.db.Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	err := db.Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (d *deliveryRepository) Update(delivery model.WebhookDelivery) error {
+	return d.db.Save(&delivery).Error
+}
@@ -1,30 +1,167 @@
 package routers
 
 import (
-	"github.com/yngwiewang/Go-000/Week04/bookstore/api/v1"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/api/gql"
+	v1 "github.com/yngwiewang/Go-000/Week04/bookstore/api/v1"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
 )
 
-func NewRouter() *gin.Engine {
+var ProviderSet = wire.NewSet(wire.Struct(new(APIs), "*"), NewRouter, NewHTTPServer)
+
+// APIs 聚合了路由需要的所有 handler
+type APIs struct {
+	Book           v1.BookAPI
+	Webhook        v1.WebhookAPI
+	Tenant         v1.TenantAPI
+	Pricing        v1.PricingAPI
+	Cover          v1.CoverAPI
+	Review         v1.ReviewAPI
+	GraphQL        *gql.Handler
+	Breaker        v1.BreakerAPI
+	Stream         v1.StreamAPI
+	Cart           v1.CartAPI
+	Order          v1.OrderAPI
+	Payment        v1.PaymentAPI
+	Recommendation v1.RecommendationAPI
+	Category       v1.CategoryAPI
+	Translation    v1.TranslationAPI
+}
+
+func NewRouter(cfg config.Config, clk clock.Clock, apis APIs,
+	tenantService service.TenantService, idempotency repository.IdempotencyRepository) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
+	if cfg.ReadYourWrites {
+		r.Use(v1.ReadYourWrites())
+	}
 
-	bookAPI := InitBookAPI(db)
-	tag := v1.NewTag()
+	bookAPI, webhookAPI, tenantAPI, pricingAPI, coverAPI := apis.Book, apis.Webhook, apis.Tenant, apis.Pricing, apis.Cover
+	reviewAPI := apis.Review
+	tenantScoped := []gin.HandlerFunc{v1.Tenant(tenantService, []byte(cfg.TokenSecret)), v1.TenantRateLimit()}
 	apiv1 := r.Group("/api/v1")
+	apiv1.Use(v1.Actor(), v1.Locale(cfg.DefaultLocale))
 	{
-		apiv1.POST("/tags", tag.Create)
-		apiv1.DELETE("/tags/:id", tag.Delete)
-		apiv1.PUT("/tags/:id", tag.Update)
-		apiv1.PATCH("/tags/:id/state", tag.Update)
-		apiv1.GET("/tags", tag.List)
-
-		apiv1.POST("/articles", article.Create)
-		apiv1.DELETE("/articles/:id", article.Delete)
-		apiv1.PUT("/articles/:id", article.Update)
-		apiv1.PATCH("/articles/:id/state", article.Update)
-		apiv1.GET("/articles/:id", article.Get)
-		apiv1.GET("/articles", article.List)
+		books := apiv1.Group("/books")
+		books.Use(tenantScoped...)
+		books.POST("", v1.Idempotency(idempotency, cfg.IdempotencyWindow, clk), bookAPI.Create)
+		books.DELETE("/:id", bookAPI.Delete)
+		books.PUT("/:id", bookAPI.Update)
+		books.GET("", v1.CacheControl(cfg.CacheControl["books.list"]), bookAPI.GetAll)
+		// gin 1.6 不允许 /books/stream 和 /books/:id 注册在同一层，在 :id 的处理函数里分发
+		books.GET("/:id", v1.CacheControl(cfg.CacheControl["books.get"]), func(c *gin.Context) {
+			if c.Param("id") == "stream" {
+				apis.Stream.Books(c)
+				return
+			}
+			bookAPI.GetByID(c)
+		})
+		// 同样的原因 /books/batch 也在 :id 的处理函数里分发，其他 id 没有 POST 接口
+		books.POST("/:id", func(c *gin.Context) {
+			if c.Param("id") != "batch" {
+				c.AbortWithStatus(http.StatusNotFound)
+			}
+		}, v1.Idempotency(idempotency, cfg.IdempotencyWindow, clk), bookAPI.Batch)
+		books.GET("/:id/history", bookAPI.History)
+		books.POST("/:id/revert", bookAPI.Revert)
+		books.GET("/:id/price", bookAPI.Price)
+		books.PUT("/:id/cover", coverAPI.Upload)
+		books.GET("/:id/cover", v1.CacheControl(cfg.CacheControl["books.cover"]), coverAPI.Get)
+		books.DELETE("/:id/cover", coverAPI.Delete)
+		books.GET("/:id/reviews", reviewAPI.List)
+		books.POST("/:id/reviews", reviewAPI.Create)
+		books.PUT("/:id/reviews/:reviewID", reviewAPI.Update)
+		books.DELETE("/:id/reviews/:reviewID", reviewAPI.Delete)
+		books.GET("/:id/recommendations", v1.CacheControl(cfg.CacheControl["books.recommendations"]),
+			apis.Recommendation.List)
+		books.GET("/:id/categories", apis.Category.BookCategories)
+		books.PUT("/:id/categories", apis.Category.SetBookCategories)
+		books.GET("/:id/translations", apis.Translation.List)
+		books.PUT("/:id/translations/:locale", apis.Translation.Put)
+		books.DELETE("/:id/translations/:locale", apis.Translation.Delete)
+
+		categories := apiv1.Group("/categories")
+		categories.Use(tenantScoped...)
+		categories.GET("", apis.Category.Roots)
+		categories.POST("", apis.Category.Create)
+		categories.GET("/:id", apis.Category.Get)
+		categories.PUT("/:id", apis.Category.Update)
+		categories.DELETE("/:id", apis.Category.Delete)
+		categories.GET("/:id/books", apis.Category.Books)
+
+		// 购物车和订单属于 X-Actor 指定的顾客
+		cart := apiv1.Group("/cart")
+		cart.Use(tenantScoped...)
+		cart.GET("", apis.Cart.Get)
+		cart.DELETE("", apis.Cart.Clear)
+		cart.POST("/items", apis.Cart.Add)
+		cart.PUT("/items/:bookID", apis.Cart.SetQuantity)
+		cart.DELETE("/items/:bookID", apis.Cart.Remove)
+
+		orders := apiv1.Group("/orders")
+		orders.Use(tenantScoped...)
+		orders.POST("", v1.Idempotency(idempotency, cfg.IdempotencyWindow, clk), apis.Order.Checkout)
+		orders.GET("", apis.Order.List)
+		orders.GET("/:id", apis.Order.Get)
+		orders.POST("/:id/cancel", apis.Order.Cancel)
+		orders.POST("/:id/pay", v1.Idempotency(idempotency, cfg.IdempotencyWindow, clk), apis.Payment.Pay)
+		orders.GET("/:id/payment", apis.Payment.Get)
+
+		// 网关回调不带店铺和顾客，靠签名认证
+		apiv1.POST("/payments/callback", apis.Payment.Callback)
+
+		// 订阅只会收到所属店铺的事件
+		webhooks := apiv1.Group("/webhooks")
+		webhooks.Use(tenantScoped...)
+		webhooks.POST("", webhookAPI.Create)
+		webhooks.GET("", webhookAPI.GetAll)
+		webhooks.DELETE("/:id", webhookAPI.Delete)
+		webhooks.GET("/:id/deliveries", webhookAPI.Deliveries)
+
+		admin := apiv1.Group("/admin")
+		admin.Use(v1.AdminAuth(cfg.AdminToken))
+		admin.POST("/tenants", tenantAPI.Create)
+		admin.GET("/tenants", tenantAPI.GetAll)
+		admin.PUT("/tenants/:id", tenantAPI.Update)
+		admin.GET("/breaker", apis.Breaker.Get)
+
+		// 优惠规则属于店铺，除了管理 token 还需要指定店铺
+		rules := admin.Group("/pricing-rules")
+		rules.Use(tenantScoped...)
+		rules.POST("", pricingAPI.Create)
+		rules.GET("", pricingAPI.GetAll)
+		rules.PUT("/:id", pricingAPI.Update)
+		rules.DELETE("/:id", pricingAPI.Delete)
+
+		adminBooks := admin.Group("/books")
+		adminBooks.Use(tenantScoped...)
+		adminBooks.GET("/:id/reviews", reviewAPI.ListAll)
+		adminBooks.PUT("/:id/reviews/:reviewID/status", reviewAPI.Moderate)
+
+		adminOrders := admin.Group("/orders")
+		adminOrders.Use(tenantScoped...)
+		adminOrders.GET("", apis.Order.ListAll)
+		adminOrders.PUT("/:id/status", apis.Order.UpdateStatus)
 	}
+
+	graphql := r.Group("/graphql")
+	graphql.Use(append([]gin.HandlerFunc{v1.Actor()}, tenantScoped...)...)
+	graphql.GET("", apis.GraphQL.Serve)
+	graphql.POST("", apis.GraphQL.Serve)
 	return r
 }
+
+func NewHTTPServer(cfg config.Config, r *gin.Engine) *http.Server {
+	return &http.Server{
+		Addr:    cfg.HTTPAddr,
+		Handler: r,
+	}
+}
@@ -0,0 +1,176 @@
+// Package scheduler 按 cron 表达式在进程内运行定时任务。
+// 同一个任务上一次还没结束时跳过本次执行；多个实例同时运行时，
+// 每次执行前先用计划执行的时间通过 LeaseRepository 占用租约，只有拿到租约的实例会执行。
+// 租约至少持有到下一次计划执行的时间，执行期间定期续期，这样同一次计划执行不会在
+// 晚触发的实例上再执行一遍，也不会和还没结束的上一次执行重叠。
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+const defaultTimeout = 5 * time.Minute
+
+// leaseTTL 是执行期间每次续期的时长，执行超过下一次计划时间的实例崩溃后，其他实例最多等这么久就能接手
+const leaseTTL = time.Minute
+
+// Job 是一个定时任务，Run 的 ctx 在超时或者调度器停止时取消
+type Job struct {
+	Name string
+	// Spec 是标准的 5 段 cron 表达式，也支持 @every 1h 这样的写法
+	Spec string
+	// Timeout 为 0 时使用 5 分钟
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+type entry struct {
+	Job
+	schedule cron.Schedule
+	running  int32
+}
+
+type Scheduler struct {
+	leases repository.LeaseRepository
+	clock  clock.Clock
+	holder string
+
+	entries []*entry
+}
+
+func New(leases repository.LeaseRepository, clk clock.Clock) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		leases: leases,
+		clock:  clk,
+		holder: fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Add 注册任务，需要在 Run 之前调用
+func (s *Scheduler) Add(job Job) error {
+	schedule, err := cron.ParseStandard(job.Spec)
+	if err != nil {
+		return fmt.Errorf("scheduler: job %s: %w", job.Name, err)
+	}
+	if job.Timeout == 0 {
+		job.Timeout = defaultTimeout
+	}
+	s.entries = append(s.entries, &entry{Job: job, schedule: schedule})
+	return nil
+}
+
+// Run 按计划运行所有任务，直到 ctx 被取消。取消后正在执行的任务也会收到取消，
+// Run 等它们都返回后才返回
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range s.entries {
+		wg.Add(1)
+		go func(e *entry) {
+			defer wg.Done()
+			s.loop(ctx, e, &wg)
+		}(e)
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry, wg *sync.WaitGroup) {
+	for {
+		next := e.schedule.Next(s.clock.Now())
+		timer := time.NewTimer(next.Sub(s.clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !atomic.CompareAndSwapInt32(&e.running, 0, 1) {
+			log.Printf("scheduler: %s is still running, skip %s", e.Name, next.Format(time.RFC3339))
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer atomic.StoreInt32(&e.running, 0)
+			s.run(ctx, e, next)
+		}()
+	}
+}
+
+// run 执行计划在 scheduled 的一次任务。租约以 scheduled 为准占用，至少持有到下一次计划执行的时间，
+// 结束后不释放：别的实例晚一点触发同一次计划时，租约还没有过期，不会重复执行
+func (s *Scheduler) run(ctx context.Context, e *entry, scheduled time.Time) {
+	hold := e.schedule.Next(scheduled)
+	now := s.clock.Now()
+	ok, err := s.leases.Acquire(e.Name, s.holder, scheduled, later(hold, now.Add(leaseTTL)))
+	if err != nil {
+		log.Printf("scheduler: acquire lease of %s err: %v", e.Name, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	// 任务不理会 ctx 超时的话会一直运行下去，所以租约不按超时时间一次占满，
+	// 而是在任务真正返回之前一直续期，其他实例不会同时执行同一个任务
+	ctx, cancel := context.WithTimeout(ctx, e.Timeout)
+	defer cancel()
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		s.renew(e.Name, hold, cancel, done)
+	}()
+	err = e.Run(ctx)
+	close(done)
+	<-renewed
+
+	if err != nil {
+		log.Printf("scheduler: %s failed after %v: %v", e.Name, s.clock.Now().Sub(now), err)
+		return
+	}
+	log.Printf("scheduler: %s finished in %v", e.Name, s.clock.Now().Sub(now))
+}
+
+// renew 每隔 leaseTTL/3 续期一次租约，直到 done 被关闭，续期不会让租约早于 hold 过期。
+// 续期出错时等下一次重试，租约已经被别的实例占用时取消任务
+func (s *Scheduler) renew(name string, hold time.Time, cancel context.CancelFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		ok, err := s.leases.Renew(name, s.holder, later(hold, s.clock.Now().Add(leaseTTL)))
+		if err != nil {
+			log.Printf("scheduler: renew lease of %s err: %v", name, err)
+			continue
+		}
+		if !ok {
+			log.Printf("scheduler: lost lease of %s, cancel it", name)
+			cancel()
+			return
+		}
+	}
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
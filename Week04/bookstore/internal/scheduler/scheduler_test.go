@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository/memory"
+)
+
+// TestEachTickRunsOnce 模拟两个实例的定时器先后触发同一次计划执行，
+// 先触发的实例执行完之后，晚触发的实例不能再执行一遍，下一次计划执行仍然只执行一次
+func TestEachTickRunsOnce(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	store := memory.NewStore(clk)
+
+	runs := make(map[time.Time]int)
+	var current time.Time
+	job := Job{Name: "purge", Spec: "*/5 * * * *", Run: func(ctx context.Context) error {
+		runs[current]++
+		// 任务很快结束
+		clk.Add(time.Second)
+		return nil
+	}}
+
+	schedulers := make([]*Scheduler, 2)
+	for i, holder := range []string{"a", "b"} {
+		s := New(store.Leases, clk)
+		s.holder = holder
+		if err := s.Add(job); err != nil {
+			t.Fatal(err)
+		}
+		schedulers[i] = s
+	}
+
+	tests := []struct {
+		name string
+		// delays 是每个实例的定时器比计划时间晚触发多久，按触发顺序执行
+		delays [2]time.Duration
+	}{
+		{name: "a first", delays: [2]time.Duration{0, 30 * time.Second}},
+		{name: "b first", delays: [2]time.Duration{3 * time.Minute, 10 * time.Millisecond}},
+		{name: "same time", delays: [2]time.Duration{0, 0}},
+		{name: "b late by almost an interval", delays: [2]time.Duration{time.Second, 4*time.Minute + 59*time.Second}},
+		{name: "a first again", delays: [2]time.Duration{0, time.Minute}},
+	}
+	scheduled := start
+	for _, tt := range tests {
+		scheduled = scheduled.Add(5 * time.Minute)
+		current = scheduled
+		order := []int{0, 1}
+		if tt.delays[1] < tt.delays[0] {
+			order = []int{1, 0}
+		}
+		for _, i := range order {
+			clk.Set(scheduled.Add(tt.delays[i]))
+			schedulers[i].run(context.Background(), schedulers[i].entries[0], scheduled)
+		}
+		if runs[scheduled] != 1 {
+			t.Fatalf("%s: tick %v ran %d times, want 1", tt.name, scheduled, runs[scheduled])
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+var (
+	// ErrInvalidBatchSize 表示批量操作为空，或者操作数超过 MaxBatchOperations
+	ErrInvalidBatchSize = errors.New("invalid batch size")
+	// ErrInvalidOperation 表示操作类型未知，或者缺少操作需要的图书 ID 或内容
+	ErrInvalidOperation = i18n.NewError("batch.invalid_operation")
+	// ErrBatchAborted 是全部成功模式下，因为其他操作失败而被回滚或者没有执行的操作的结果
+	ErrBatchAborted = i18n.NewError("batch.aborted")
+)
+
+// BookOperation 是批量操作中的一步，Action 是 create、update 或 delete。
+// update 和 delete 按 ID 定位图书，create 和 update 必须有 Book，update 用它覆盖图书的业务字段，和 REST 的 PUT 一致
+type BookOperation struct {
+	Action string
+	ID     uint
+	Book   *model.Book
+}
+
+// BookOperationResult 和 BookOperation 一一对应，Err 为空表示操作已经提交
+type BookOperationResult struct {
+	Book model.Book
+	Err  error
+}
+
+// Batch 在一个事务中按顺序执行 ops。atomic 为 true 时任何一个操作失败都回滚整个事务，
+// 返回这个操作的错误，其他操作的结果是 ErrBatchAborted；否则每个操作前设置保存点，
+// 失败的操作只撤销它自己，其余操作照常提交，这时只有事务本身失败才返回错误
+func (b *BookService) Batch(ctx context.Context, ops []BookOperation, atomic bool) ([]BookOperationResult, error) {
+	if len(ops) == 0 || len(ops) > b.MaxBatchOperations {
+		return nil, ErrInvalidBatchSize
+	}
+
+	results := make([]BookOperationResult, len(ops))
+	err := b.Transactor.Transaction(func(tx repository.Tx) error {
+		for i, op := range ops {
+			savepoint := "batch_op_" + strconv.Itoa(i)
+			if !atomic {
+				if err := tx.Savepoints.Savepoint(savepoint); err != nil {
+					return err
+				}
+			}
+			book, err := applyOperation(ctx, tx, op)
+			if err == nil {
+				results[i].Book = book
+				continue
+			}
+			results[i].Err = err
+			if atomic {
+				return err
+			}
+			if err := tx.Savepoints.RollbackTo(savepoint); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		// 超时返回时事务可能还在写 results，换成新的结果
+		results = make([]BookOperationResult, len(ops))
+		for i := range results {
+			results[i].Err = ErrBatchAborted
+		}
+		return results, err
+	}
+	if err != nil {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = BookOperationResult{Err: ErrBatchAborted}
+			}
+		}
+		return results, err
+	}
+
+	if b.Enricher != nil {
+		for i, op := range ops {
+			if op.Action == model.ActionCreate && results[i].Err == nil {
+				b.Enricher.Enqueue(ctx, results[i].Book)
+			}
+		}
+	}
+	return results, nil
+}
+
+func applyOperation(ctx context.Context, tx repository.Tx, op BookOperation) (model.Book, error) {
+	switch {
+	case op.Action == model.ActionCreate && op.Book != nil:
+		book := *op.Book
+		book.ID = 0
+		return saveBook(ctx, tx, book, model.ActionCreate)
+	case op.Action == model.ActionUpdate && op.Book != nil && op.ID != 0:
+		book, err := tx.Books.GetByID(ctx, op.ID)
+		if err != nil {
+			return book, err
+		}
+		return saveBook(ctx, tx, snapshotOf(*op.Book).applyTo(book), model.ActionUpdate)
+	case op.Action == model.ActionDelete && op.ID != 0:
+		book, err := tx.Books.GetByID(ctx, op.ID)
+		if err != nil {
+			return book, err
+		}
+		return book, deleteBook(ctx, tx, book)
+	default:
+		return model.Book{}, ErrInvalidOperation
+	}
+}
@@ -1,32 +1,249 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"log"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/pricing"
 	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
 )
 
+// ErrBookQuotaExceeded 表示店铺的图书数量已经达到上限
+var ErrBookQuotaExceeded = i18n.NewError("book.quota_exceeded")
+
 type BookService struct {
-	BookRepository repository.BookRepository
+	BookRepository        repository.BookRepository
+	HistoryRepository     repository.HistoryRepository
+	PricingRuleRepository repository.PricingRuleRepository
+	CategoryRepository    repository.CategoryRepository
+	Transactor            repository.Transactor
+	// Enricher 为空时不补全新建的图书
+	Enricher *Enricher
+	Clock    clock.Clock
+	// MaxBatchOperations 是一次 Batch 最多包含的操作数
+	MaxBatchOperations int
+}
+
+func NewBookService(cfg config.Config, b repository.BookRepository, h repository.HistoryRepository,
+	p repository.PricingRuleRepository, c repository.CategoryRepository, t repository.Transactor, e *Enricher,
+	clk clock.Clock) BookService {
+	return BookService{
+		BookRepository:        b,
+		HistoryRepository:     h,
+		PricingRuleRepository: p,
+		CategoryRepository:    c,
+		Transactor:            t,
+		Enricher:              e,
+		Clock:                 clk,
+		MaxBatchOperations:    cfg.MaxBatchOperations,
+	}
+}
+
+func (b *BookService) GetAll(ctx context.Context) ([]model.Book, error) {
+	return b.BookRepository.GetAll(ctx)
+}
+
+func (b *BookService) GetByID(ctx context.Context, id uint) (model.Book, error) {
+	return b.BookRepository.GetByID(ctx, id)
 }
 
-func NewBookService(b repository.BookRepository) BookService {
-	return BookService{BookRepository: b}
+// List 按 ID 顺序分页返回图书，afterID 为 0 时从头开始
+func (b *BookService) List(ctx context.Context, afterID uint, limit int) ([]model.Book, error) {
+	return b.BookRepository.ListAfter(ctx, afterID, limit)
 }
 
-func (b *BookService) GetAll() []model.Book {
-	return b.BookRepository.GetAll()
+func (b *BookService) Count(ctx context.Context) (int, error) {
+	return b.BookRepository.Count(ctx)
 }
 
-func (b *BookService) GetByID(id uint) model.Book {
-	return b.BookRepository.GetByID(id)
+// LastModified 返回店铺内图书列表最后一次变化的时间
+func (b *BookService) LastModified(ctx context.Context) (time.Time, error) {
+	return b.BookRepository.LastModified(ctx)
 }
 
-func (b *BookService) Save(book model.Book) model.Book {
+// Save 保存图书，并在同一个事务中写入变更记录和 created/updated/repriced 事件
+// 新建的图书在提交后交给 Enricher 异步补全
+func (b *BookService) Save(ctx context.Context, book model.Book) (model.Book, error) {
 	log.Println(book)
-	return b.BookRepository.Save(book)
+	if book.ID != 0 {
+		return b.save(ctx, book, model.ActionUpdate)
+	}
+	saved, err := b.save(ctx, book, model.ActionCreate)
+	if err == nil && b.Enricher != nil {
+		b.Enricher.Enqueue(ctx, saved)
+	}
+	return saved, err
+}
+
+func (b *BookService) save(ctx context.Context, book model.Book, action string) (model.Book, error) {
+	var saved model.Book
+	err := b.Transactor.Transaction(func(tx repository.Tx) error {
+		var err error
+		saved, err = saveBook(ctx, tx, book, action)
+		return err
+	})
+	if err != nil {
+		return model.Book{}, err
+	}
+	return saved, nil
+}
+
+// saveBook 在事务内保存图书，写入变更记录和 created/updated/repriced 事件
+func saveBook(ctx context.Context, tx repository.Tx, book model.Book, action string) (model.Book, error) {
+	var old model.Book
+	eventType := model.EventBookCreated
+	if book.ID == 0 {
+		if err := checkBookQuota(ctx, tx); err != nil {
+			return book, err
+		}
+	} else {
+		// 锁住图书，并发的修改排队写变更记录，不会算出同一个版本号
+		var err error
+		old, err = tx.Books.GetForUpdate(ctx, book.ID)
+		if err != nil {
+			return book, err
+		}
+		eventType = model.EventBookUpdated
+		if old.Price != book.Price {
+			eventType = model.EventBookRepriced
+		}
+	}
+
+	saved, err := tx.Books.Save(ctx, book)
+	if err != nil {
+		return saved, err
+	}
+	if err := addRevision(ctx, tx, action, old, saved); err != nil {
+		return saved, err
+	}
+	return saved, addBookEvent(tx, eventType, saved)
+}
+
+// Delete 删除图书，并在同一个事务中写入变更记录和 deleted 事件
+func (b *BookService) Delete(ctx context.Context, book model.Book) error {
+	return b.Transactor.Transaction(func(tx repository.Tx) error {
+		return deleteBook(ctx, tx, book)
+	})
+}
+
+func deleteBook(ctx context.Context, tx repository.Tx, book model.Book) error {
+	if _, err := tx.Books.GetForUpdate(ctx, book.ID); err != nil {
+		return err
+	}
+	if err := tx.Books.Delete(ctx, book); err != nil {
+		return err
+	}
+	if err := addRevision(ctx, tx, model.ActionDelete, book, book); err != nil {
+		return err
+	}
+	return addBookEvent(tx, model.EventBookDeleted, book)
+}
+
+// History 按版本顺序返回图书的变更记录
+func (b *BookService) History(ctx context.Context, id uint) ([]model.BookRevision, error) {
+	// 变更记录本身不区分店铺，先确认图书属于当前店铺
+	if _, err := b.BookRepository.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return b.HistoryRepository.ListByBook(id)
+}
+
+// Revert 把图书恢复到指定版本的状态，回滚本身也会产生一条新的变更记录
+func (b *BookService) Revert(ctx context.Context, id uint, version int) (model.Book, error) {
+	book, err := b.BookRepository.GetByID(ctx, id)
+	if err != nil {
+		return book, err
+	}
+	rev, err := b.HistoryRepository.GetVersion(id, version)
+	if err != nil {
+		return book, err
+	}
+	var snapshot bookSnapshot
+	if err := json.Unmarshal([]byte(rev.Snapshot), &snapshot); err != nil {
+		return book, err
+	}
+	return b.save(ctx, snapshot.applyTo(book), model.ActionRevert)
+}
+
+// Histories 一次取出多本书的变更记录，books 必须是已经按店铺查出来的图书
+func (b *BookService) Histories(books []model.Book) (map[uint][]model.BookRevision, error) {
+	ids := make([]uint, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	revs, err := b.HistoryRepository.ListByBooks(ids)
+	if err != nil {
+		return nil, err
+	}
+	byBook := make(map[uint][]model.BookRevision, len(books))
+	for _, rev := range revs {
+		byBook[rev.BookID] = append(byBook[rev.BookID], rev)
+	}
+	return byBook, nil
+}
+
+// Price 按当前生效的优惠规则计算图书的实际售价，coupon 为空表示不使用优惠码
+func (b *BookService) Price(ctx context.Context, id uint, coupon string) (pricing.Quote, error) {
+	book, err := b.BookRepository.GetByID(ctx, id)
+	if err != nil {
+		return pricing.Quote{}, err
+	}
+	quotes, err := b.Quotes(ctx, []model.Book{book}, coupon)
+	if err != nil {
+		return pricing.Quote{}, err
+	}
+	return quotes[0], nil
+}
+
+// Quotes 用同一批优惠规则计算多本书的售价，结果和 books 一一对应
+func (b *BookService) Quotes(ctx context.Context, books []model.Book, coupon string) ([]pricing.Quote, error) {
+	now := b.Clock.Now()
+	rules, err := b.PricingRuleRepository.ListEffective(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	paths, err := b.CategoryRepository.BookPaths(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	quotes := make([]pricing.Quote, len(books))
+	for i, book := range books {
+		quotes[i] = pricing.Evaluate(book, paths[book.ID], rules, now, coupon)
+	}
+	return quotes, nil
 }
 
-func (b *BookService) Delete(book model.Book) {
-	b.BookRepository.Delete(book)
+// checkBookQuota 在创建图书前检查店铺的图书数量上限。先锁住店铺这一行再计数，
+// 同一店铺并发创建图书的事务会在这里排队，不会都在达到上限前读到同样的数量
+func checkBookQuota(ctx context.Context, tx repository.Tx) error {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.ErrNoTenant
+	}
+	t, err := tx.Tenants.GetForUpdate(t.ID)
+	if err != nil {
+		return err
+	}
+	if t.MaxBooks <= 0 {
+		return nil
+	}
+	count, err := tx.Books.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count >= t.MaxBooks {
+		return ErrBookQuotaExceeded
+	}
+	return nil
 }
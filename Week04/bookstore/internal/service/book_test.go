@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository/memory"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+// TestConcurrentUpdatesGetDistinctVersions 并发修改同一本书，每次修改都应该成功，
+// 变更记录的版本号从 1 开始连续、不重复
+func TestConcurrentUpdatesGetDistinctVersions(t *testing.T) {
+	clk := clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := memory.NewStore(clk)
+	books := service.NewBookService(config.Config{}, store.Books, store.History, store.PricingRules,
+		store.Categories, store, nil, clk)
+	demo, err := store.Tenants.Save(model.Tenant{Slug: "demo", Name: "Demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := tenant.WithTenant(context.Background(), demo)
+
+	book, err := books.Save(ctx, model.Book{ISBN: "978-7-111", Title: "Go", Price: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const updates = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, updates)
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b := book
+			b.Title = "Go " + strconv.Itoa(i)
+			if _, err := books.Save(ctx, b); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Save: %v", err)
+	}
+
+	revs, err := books.History(ctx, book.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != updates+1 {
+		t.Fatalf("got %d revisions, want %d", len(revs), updates+1)
+	}
+	seen := make(map[int]bool)
+	for _, rev := range revs {
+		if rev.Version < 1 || rev.Version > updates+1 || seen[rev.Version] {
+			t.Fatalf("unexpected version %d in %+v", rev.Version, revs)
+		}
+		seen[rev.Version] = true
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"math"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+// MaxCartQuantity 是购物车中单本图书的数量上限
+const MaxCartQuantity = 99
+
+var (
+	// ErrCustomerRequired 表示匿名用户不能使用购物车和订单
+	ErrCustomerRequired = i18n.NewError("cart.customer_required")
+	// ErrInvalidQuantity 表示数量不在 1 到 MaxCartQuantity 之间
+	ErrInvalidQuantity = i18n.NewError("cart.invalid_quantity")
+)
+
+type CartService struct {
+	BookRepository repository.BookRepository
+	CartRepository repository.CartRepository
+	// BookService 用来按优惠规则计算购物车里图书的价格
+	BookService BookService
+}
+
+func NewCartService(b repository.BookRepository, c repository.CartRepository, s BookService) CartService {
+	return CartService{BookRepository: b, CartRepository: c, BookService: s}
+}
+
+// customerOf 返回当前操作人，匿名用户返回 ErrCustomerRequired。
+// 操作人来自没有认证过的 X-Actor，购物车、订单和支付的归属都以网关认证并设置了这个头为前提
+func customerOf(ctx context.Context) (string, error) {
+	customer := ActorFromContext(ctx)
+	if customer == AnonymousActor {
+		return "", ErrCustomerRequired
+	}
+	return customer, nil
+}
+
+// Get 返回购物车，价格和结算时一样按当前的优惠规则和优惠码 coupon 计算，coupon 可以为空
+func (c *CartService) Get(ctx context.Context, coupon string) (model.Cart, error) {
+	customer, err := customerOf(ctx)
+	if err != nil {
+		return model.Cart{}, err
+	}
+	items, err := c.CartRepository.List(ctx, customer)
+	if err != nil {
+		return model.Cart{}, err
+	}
+
+	var cart model.Cart
+	var books []model.Book
+	for _, item := range items {
+		line := model.CartLine{CartItem: item}
+		book, err := c.BookRepository.GetByID(ctx, item.BookID)
+		switch err {
+		case nil:
+			line.Book, line.Available = book, true
+			books = append(books, book)
+		case repository.ErrNotFound:
+		default:
+			return model.Cart{}, err
+		}
+		cart.Lines = append(cart.Lines, line)
+	}
+
+	quotes, err := c.BookService.Quotes(ctx, books, coupon)
+	if err != nil {
+		return model.Cart{}, err
+	}
+	var total int64
+	for i := range cart.Lines {
+		line := &cart.Lines[i]
+		if !line.Available {
+			continue
+		}
+		quote := quotes[0]
+		quotes = quotes[1:]
+		line.UnitPrice, line.Adjustments = quote.EffectivePrice, quote.Adjustments
+		line.Subtotal = fromCents(cents(quote.EffectivePrice) * int64(line.Quantity))
+		total += cents(quote.EffectivePrice) * int64(line.Quantity)
+	}
+	cart.Total = fromCents(total)
+	return cart, nil
+}
+
+// Add 把图书加入购物车，已经在购物车里时累加数量
+func (c *CartService) Add(ctx context.Context, bookID uint, quantity int) (model.CartItem, error) {
+	customer, err := customerOf(ctx)
+	if err != nil {
+		return model.CartItem{}, err
+	}
+	items, err := c.CartRepository.List(ctx, customer)
+	if err != nil {
+		return model.CartItem{}, err
+	}
+	for _, item := range items {
+		if item.BookID == bookID {
+			quantity += item.Quantity
+		}
+	}
+	return c.SetQuantity(ctx, bookID, quantity)
+}
+
+// SetQuantity 设置购物车中图书的数量，数量为 0 时移除
+func (c *CartService) SetQuantity(ctx context.Context, bookID uint, quantity int) (model.CartItem, error) {
+	customer, err := customerOf(ctx)
+	if err != nil {
+		return model.CartItem{}, err
+	}
+	if quantity == 0 {
+		return model.CartItem{}, c.CartRepository.Remove(ctx, customer, bookID)
+	}
+	if quantity < 0 || quantity > MaxCartQuantity {
+		return model.CartItem{}, ErrInvalidQuantity
+	}
+	if _, err := c.BookRepository.GetByID(ctx, bookID); err != nil {
+		return model.CartItem{}, err
+	}
+	return c.CartRepository.Put(ctx, model.CartItem{Customer: customer, BookID: bookID, Quantity: quantity})
+}
+
+func (c *CartService) Remove(ctx context.Context, bookID uint) error {
+	customer, err := customerOf(ctx)
+	if err != nil {
+		return err
+	}
+	return c.CartRepository.Remove(ctx, customer, bookID)
+}
+
+func (c *CartService) Clear(ctx context.Context) error {
+	customer, err := customerOf(ctx)
+	if err != nil {
+		return err
+	}
+	return c.CartRepository.Clear(ctx, customer)
+}
+
+// cents 把价格换算成分，金额在分上计算，避免浮点数累加的误差
+func cents(price float32) int64 {
+	return int64(math.Round(float64(price) * 100))
+}
+
+func fromCents(c int64) float32 {
+	return float32(c) / 100
+}
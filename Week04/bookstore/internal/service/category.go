@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+var (
+	// ErrInvalidParent 表示父分类不存在，或者要把分类移动到它自己的子树下
+	ErrInvalidParent = repository.ErrInvalidParent
+	// ErrCategoryNotEmpty 表示分类还有子分类，不能删除
+	ErrCategoryNotEmpty = i18n.NewError("category.not_empty")
+	// ErrInvalidCategory 表示给图书设置的分类不存在
+	ErrInvalidCategory = i18n.NewError("category.invalid")
+)
+
+// CategoryBrowse 是浏览分类时的一页：分类本身、从根到它的路径和带图书数的子分类。
+// 浏览根时 Category 为零值
+type CategoryBrowse struct {
+	Category  model.CategoryCount
+	Ancestors []model.Category
+	Children  []model.CategoryCount
+}
+
+type CategoryService struct {
+	CategoryRepository repository.CategoryRepository
+	BookRepository     repository.BookRepository
+}
+
+func NewCategoryService(c repository.CategoryRepository, b repository.BookRepository) CategoryService {
+	return CategoryService{CategoryRepository: c, BookRepository: b}
+}
+
+func (s *CategoryService) Get(ctx context.Context, id uint) (model.Category, error) {
+	return s.CategoryRepository.GetByID(ctx, id)
+}
+
+// Browse 返回分类和它的子分类，id 为 0 时返回根分类
+func (s *CategoryService) Browse(ctx context.Context, id uint) (CategoryBrowse, error) {
+	var browse CategoryBrowse
+	if id != 0 {
+		c, err := s.CategoryRepository.GetByID(ctx, id)
+		if err != nil {
+			return browse, err
+		}
+		browse.Category.Category = c
+		ancestors := pathIDs(c.Path)
+		if browse.Ancestors, err = s.CategoryRepository.GetByIDs(ctx, ancestors[:len(ancestors)-1]); err != nil {
+			return browse, err
+		}
+	}
+
+	children, err := s.CategoryRepository.Children(ctx, id)
+	if err != nil {
+		return browse, err
+	}
+	var ids []uint
+	if id != 0 {
+		ids = append(ids, id)
+	}
+	for _, c := range children {
+		ids = append(ids, c.ID)
+	}
+	counts, err := s.CategoryRepository.BookCounts(ctx, ids)
+	if err != nil {
+		return browse, err
+	}
+	browse.Category.BookCount = counts[id]
+	for _, c := range children {
+		browse.Children = append(browse.Children, model.CategoryCount{Category: c, BookCount: counts[c.ID]})
+	}
+	return browse, nil
+}
+
+func (s *CategoryService) Create(ctx context.Context, name string, parentID uint) (model.Category, error) {
+	return s.CategoryRepository.Create(ctx, model.Category{Name: name, ParentID: parentID})
+}
+
+// Update 重命名或移动分类，移动时整棵子树跟着移动
+func (s *CategoryService) Update(ctx context.Context, id uint, name string, parentID uint) (model.Category, error) {
+	return s.CategoryRepository.Update(ctx, id, name, parentID)
+}
+
+// Delete 删除没有子分类的分类，图书本身不受影响
+func (s *CategoryService) Delete(ctx context.Context, id uint) error {
+	if _, err := s.CategoryRepository.GetByID(ctx, id); err != nil {
+		return err
+	}
+	children, err := s.CategoryRepository.Children(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return ErrCategoryNotEmpty
+	}
+	return s.CategoryRepository.Delete(ctx, id)
+}
+
+// Books 返回属于任意一个分类子树的图书，不存在的分类被忽略
+func (s *CategoryService) Books(ctx context.Context, categoryIDs []uint) ([]model.Book, error) {
+	ids, err := s.CategoryRepository.BookIDs(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	in := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		in[id] = true
+	}
+	books, err := s.BookRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filtered := make([]model.Book, 0, len(ids))
+	for _, book := range books {
+		if in[book.ID] {
+			filtered = append(filtered, book)
+		}
+	}
+	return filtered, nil
+}
+
+func (s *CategoryService) BookCategories(ctx context.Context, bookID uint) ([]model.Category, error) {
+	if _, err := s.BookRepository.GetByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	return s.CategoryRepository.BookCategories(ctx, bookID)
+}
+
+// SetBookCategories 把图书的分类替换为 categoryIDs，分类都必须存在
+func (s *CategoryService) SetBookCategories(ctx context.Context, bookID uint, categoryIDs []uint) ([]model.Category, error) {
+	if _, err := s.BookRepository.GetByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	unique := make([]uint, 0, len(categoryIDs))
+	seen := make(map[uint]bool, len(categoryIDs))
+	for _, id := range categoryIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	cs, err := s.CategoryRepository.GetByIDs(ctx, unique)
+	if err != nil {
+		return nil, err
+	}
+	if len(cs) != len(unique) {
+		return nil, ErrInvalidCategory
+	}
+	if err := s.CategoryRepository.SetBookCategories(ctx, bookID, unique); err != nil {
+		return nil, err
+	}
+	return s.CategoryRepository.BookCategories(ctx, bookID)
+}
+
+// pathIDs 解析 /1/4/9/ 形式的 Path
+func pathIDs(path string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if id, err := strconv.ParseUint(part, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
@@ -0,0 +1,21 @@
+package service
+
+import "context"
+
+type actorKey struct{}
+
+// AnonymousActor 是没有标明操作人时记录的默认值
+const AnonymousActor = "anonymous"
+
+// WithActor 在 ctx 中记录本次操作的操作人
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext 取出操作人，没有时返回 AnonymousActor
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
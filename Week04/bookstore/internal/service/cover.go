@@ -0,0 +1,175 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/blob"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/cover"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+var (
+	// ErrNoCover 表示图书还没有上传封面
+	ErrNoCover = i18n.NewError("cover.missing")
+	// ErrUnknownCoverSize 表示请求的缩略图规格不存在
+	ErrUnknownCoverSize = i18n.NewError("cover.unknown_size")
+)
+
+// CoverImage 是读取出来的一张封面或缩略图
+type CoverImage struct {
+	Data        []byte
+	ContentType string
+	ModTime     time.Time
+}
+
+type CoverService struct {
+	BookRepository repository.BookRepository
+	Transactor     repository.Transactor
+	Blobs          blob.Store
+	Clock          clock.Clock
+}
+
+func NewCoverService(b repository.BookRepository, t repository.Transactor, blobs blob.Store, clk clock.Clock) CoverService {
+	return CoverService{BookRepository: b, Transactor: t, Blobs: blobs, Clock: clk}
+}
+
+// Upload 保存原图并生成各个规格的缩略图，封面不属于图书的业务字段，不记录变更历史
+func (c *CoverService) Upload(ctx context.Context, id uint, data []byte) (model.Book, error) {
+	book, err := c.BookRepository.GetByID(ctx, id)
+	if err != nil {
+		return book, err
+	}
+	img, format, err := cover.Decode(data)
+	if err != nil {
+		return book, err
+	}
+
+	original, err := cover.EncodeOriginal(data, img, format)
+	if err != nil {
+		return book, err
+	}
+	if err := c.put(ctx, book, cover.Original, format, original); err != nil {
+		return book, err
+	}
+	for _, size := range cover.Sizes {
+		var buf bytes.Buffer
+		if err := cover.Encode(&buf, cover.Thumbnail(img, size.Width), format); err != nil {
+			return book, err
+		}
+		if err := c.put(ctx, book, size.Name, format, buf.Bytes()); err != nil {
+			return book, err
+		}
+	}
+
+	now := c.Clock.Now()
+	old, err := c.setCover(ctx, id, format, &now)
+	if err != nil {
+		return book, err
+	}
+	// 格式变了以后旧文件的 key 不会被覆盖，需要单独删除
+	if old != "" && old != format {
+		c.remove(ctx, book, old)
+	}
+	return c.BookRepository.GetByID(ctx, id)
+}
+
+// Get 读取指定规格的封面，size 为空时返回原图
+func (c *CoverService) Get(ctx context.Context, id uint, size string) (CoverImage, error) {
+	if size == "" {
+		size = cover.Original
+	}
+	if !knownSize(size) {
+		return CoverImage{}, ErrUnknownCoverSize
+	}
+	book, err := c.BookRepository.GetByID(ctx, id)
+	if err != nil {
+		return CoverImage{}, err
+	}
+	if book.CoverType == "" {
+		return CoverImage{}, ErrNoCover
+	}
+
+	r, _, err := c.Blobs.Get(ctx, coverKey(book, size, book.CoverType))
+	if err == blob.ErrNotFound {
+		return CoverImage{}, ErrNoCover
+	}
+	if err != nil {
+		return CoverImage{}, err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return CoverImage{}, err
+	}
+	return CoverImage{
+		Data:        data,
+		ContentType: cover.ContentType(book.CoverType),
+		ModTime:     *book.CoverUpdatedAt,
+	}, nil
+}
+
+// Delete 删除封面和所有缩略图
+func (c *CoverService) Delete(ctx context.Context, id uint) error {
+	book, err := c.BookRepository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	format, err := c.setCover(ctx, id, "", nil)
+	if err != nil {
+		return err
+	}
+	if format == "" {
+		return ErrNoCover
+	}
+	c.remove(ctx, book, format)
+	return nil
+}
+
+// setCover 在事务中只修改图书的封面字段，返回修改前的封面格式
+func (c *CoverService) setCover(ctx context.Context, id uint, format string, updatedAt *time.Time) (string, error) {
+	var old string
+	err := c.Transactor.Transaction(func(tx repository.Tx) error {
+		var err error
+		old, err = tx.Books.SetCover(ctx, id, format, updatedAt)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return old, nil
+}
+
+func (c *CoverService) put(ctx context.Context, book model.Book, size, format string, data []byte) error {
+	return c.Blobs.Put(ctx, coverKey(book, size, format), bytes.NewReader(data), cover.ContentType(format))
+}
+
+// remove 尽力删除某种格式的全部文件，残留的文件不影响读取
+func (c *CoverService) remove(ctx context.Context, book model.Book, format string) {
+	c.Blobs.Delete(ctx, coverKey(book, cover.Original, format))
+	for _, size := range cover.Sizes {
+		c.Blobs.Delete(ctx, coverKey(book, size.Name, format))
+	}
+}
+
+func coverKey(book model.Book, size, format string) string {
+	return fmt.Sprintf("covers/%d/%d/%s%s", book.TenantID, book.ID, size, cover.Ext(format))
+}
+
+func knownSize(size string) bool {
+	if size == cover.Original {
+		return true
+	}
+	for _, s := range cover.Sizes {
+		if s.Name == size {
+			return true
+		}
+	}
+	return false
+}
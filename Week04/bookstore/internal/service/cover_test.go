@@ -0,0 +1,65 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/blob"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository/memory"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+func TestUploadGIFCover(t *testing.T) {
+	clk := clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := memory.NewStore(clk)
+	ctx := tenant.WithTenant(context.Background(), model.Tenant{Model: gorm.Model{ID: 1}, Slug: "demo"})
+	book, err := store.Books.Save(ctx, model.Book{ISBN: "978-7-111", Title: "Go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	covers := service.NewCoverService(store.Books, store, &blob.Local{Root: t.TempDir()}, clk)
+
+	img := image.NewPaletted(image.Rect(0, 0, 40, 20), color.Palette{color.White, color.Black})
+	img.SetColorIndex(1, 1, 1)
+	var data bytes.Buffer
+	if err := gif.Encode(&data, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	book, err = covers.Upload(ctx, book.ID, data.Bytes())
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if book.CoverType != "png" {
+		t.Fatalf("CoverType = %q, want png", book.CoverType)
+	}
+
+	// 原图和缩略图保存的都是 png 编码后的内容，不是上传的 gif 字节
+	for _, size := range []string{"", "small"} {
+		got, err := covers.Get(ctx, book.ID, size)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", size, err)
+		}
+		if got.ContentType != "image/png" || http.DetectContentType(got.Data) != "image/png" {
+			t.Fatalf("Get(%q) returned %s with %s content", size, got.ContentType, http.DetectContentType(got.Data))
+		}
+		decoded, err := png.Decode(bytes.NewReader(got.Data))
+		if err != nil {
+			t.Fatalf("Get(%q): decode png: %v", size, err)
+		}
+		if b := decoded.Bounds(); b.Dx() != 40 || b.Dy() != 20 {
+			t.Fatalf("Get(%q) size = %v, want 40x20", size, b)
+		}
+	}
+}
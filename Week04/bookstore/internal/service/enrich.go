@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/metadata"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+// EnrichActor 是自动补全在变更记录里的操作人
+const EnrichActor = "metadata"
+
+const enrichQueueSize = 1000
+
+type enrichJob struct {
+	tenant model.Tenant
+	bookID uint
+	isbn   string
+}
+
+// Enricher 在后台按 ISBN 补全新建图书的空字段和封面，已经填写的字段不会被覆盖。
+// 队列满了或者进程退出时未处理的图书会被放弃，补全只是尽力而为
+type Enricher struct {
+	Enabled      bool
+	Provider     metadata.Provider
+	Transactor   repository.Transactor
+	CoverService CoverService
+
+	queue chan enrichJob
+}
+
+func NewEnricher(cfg config.Config, p metadata.Provider, t repository.Transactor, covers CoverService) *Enricher {
+	return &Enricher{
+		Enabled:      cfg.EnrichBooks,
+		Provider:     p,
+		Transactor:   t,
+		CoverService: covers,
+		queue:        make(chan enrichJob, enrichQueueSize),
+	}
+}
+
+// Enqueue 把图书加入补全队列，不会阻塞
+func (e *Enricher) Enqueue(ctx context.Context, book model.Book) {
+	if !e.Enabled || metadata.Normalize(book.ISBN) == "" || !needsEnrichment(book) {
+		return
+	}
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return
+	}
+	select {
+	case e.queue <- enrichJob{tenant: t, bookID: book.ID, isbn: book.ISBN}:
+	default:
+		log.Printf("enrich: queue is full, skip book %d", book.ID)
+	}
+}
+
+// Run 处理补全队列，直到 ctx 被取消
+func (e *Enricher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-e.queue:
+			if err := e.enrich(ctx, job); err != nil {
+				log.Printf("enrich book %d err: %v", job.bookID, err)
+			}
+		}
+	}
+}
+
+func (e *Enricher) enrich(ctx context.Context, job enrichJob) error {
+	ctx = WithActor(tenant.WithTenant(ctx, job.tenant), EnrichActor)
+	md, err := e.Provider.Lookup(ctx, job.isbn)
+	if err != nil && err != metadata.ErrNotFound {
+		return err
+	}
+
+	var book model.Book
+	stale := false
+	err = e.Transactor.Transaction(func(tx repository.Tx) error {
+		old, err := tx.Books.GetForUpdate(ctx, job.bookID)
+		if err != nil {
+			return err
+		}
+		// 入队之后 ISBN 被改过，查到的信息已经不属于这本书
+		if metadata.Normalize(old.ISBN) != metadata.Normalize(job.isbn) {
+			stale = true
+			return nil
+		}
+		book = fillBlank(old, md)
+		if snapshotOf(book) == snapshotOf(old) {
+			return nil
+		}
+		if book, err = tx.Books.Save(ctx, book); err != nil {
+			return err
+		}
+		if err := addRevision(ctx, tx, model.ActionEnrich, old, book); err != nil {
+			return err
+		}
+		return addBookEvent(tx, model.EventBookUpdated, book)
+	})
+	if err != nil || stale || book.CoverType != "" {
+		return err
+	}
+
+	data, err := e.Provider.Cover(ctx, job.isbn)
+	if err == metadata.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = e.CoverService.Upload(ctx, job.bookID, data)
+	return err
+}
+
+func needsEnrichment(book model.Book) bool {
+	return book.Title == "" || book.Author == "" || book.Publisher == "" || book.CoverType == ""
+}
+
+// fillBlank 只填写图书中为空的字段
+func fillBlank(book model.Book, md metadata.Metadata) model.Book {
+	if book.Title == "" {
+		book.Title = md.Title
+	}
+	if book.Author == "" {
+		book.Author = strings.Join(md.Authors, ", ")
+	}
+	if book.Publisher == "" {
+		book.Publisher = md.Publisher
+	}
+	return book
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/dto"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+// addBookEvent 把图书变更事件写入 outbox，payload 和 API 返回的 BookDTO 保持一致
+func addBookEvent(tx repository.Tx, eventType string, book model.Book) error {
+	payload, err := json.Marshal(dto.ToBookDTO(book))
+	if err != nil {
+		return err
+	}
+	return tx.Outbox.Add(model.OutboxEvent{
+		AggregateID: book.ID,
+		TenantID:    book.TenantID,
+		EventType:   eventType,
+		Payload:     string(payload),
+	})
+}
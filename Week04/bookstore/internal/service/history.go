@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+// bookSnapshot 是变更记录里保存的图书字段，也是回滚时可以恢复的字段
+type bookSnapshot struct {
+	ISBN  string `json:"isbn"`
+	Title string `json:"title"`
+	// Description 和 Publisher 在旧的记录里没有，回滚到旧版本时会被清空
+	Description string  `json:"description"`
+	Author      string  `json:"author"`
+	Publisher   string  `json:"publisher"`
+	Category    string  `json:"category"`
+	Price       float32 `json:"price"`
+}
+
+func snapshotOf(book model.Book) bookSnapshot {
+	return bookSnapshot{
+		ISBN:        book.ISBN,
+		Title:       book.Title,
+		Description: book.Description,
+		Author:      book.Author,
+		Publisher:   book.Publisher,
+		Category:    book.Category,
+		Price:       book.Price,
+	}
+}
+
+func (s bookSnapshot) applyTo(book model.Book) model.Book {
+	book.ISBN = s.ISBN
+	book.Title = s.Title
+	book.Description = s.Description
+	book.Author = s.Author
+	book.Publisher = s.Publisher
+	book.Category = s.Category
+	book.Price = s.Price
+	return book
+}
+
+// diff 返回从 before 到 after 发生变化的字段
+func (s bookSnapshot) diff(after bookSnapshot) []model.FieldChange {
+	changes := []model.FieldChange{}
+	if s.ISBN != after.ISBN {
+		changes = append(changes, model.FieldChange{Field: "isbn", Before: s.ISBN, After: after.ISBN})
+	}
+	if s.Title != after.Title {
+		changes = append(changes, model.FieldChange{Field: "title", Before: s.Title, After: after.Title})
+	}
+	if s.Description != after.Description {
+		changes = append(changes, model.FieldChange{Field: "description", Before: s.Description, After: after.Description})
+	}
+	if s.Author != after.Author {
+		changes = append(changes, model.FieldChange{Field: "author", Before: s.Author, After: after.Author})
+	}
+	if s.Publisher != after.Publisher {
+		changes = append(changes, model.FieldChange{Field: "publisher", Before: s.Publisher, After: after.Publisher})
+	}
+	if s.Category != after.Category {
+		changes = append(changes, model.FieldChange{Field: "category", Before: s.Category, After: after.Category})
+	}
+	if s.Price != after.Price {
+		changes = append(changes, model.FieldChange{Field: "price", Before: s.Price, After: after.Price})
+	}
+	return changes
+}
+
+// addRevision 在事务内为图书追加一条变更记录，调用方需要先用 GetForUpdate 锁住图书，
+// 否则并发的事务会读到同一个最新版本
+func addRevision(ctx context.Context, tx repository.Tx, action string, before, after model.Book) error {
+	version, err := tx.History.LatestVersion(after.ID)
+	if err != nil {
+		return err
+	}
+	changes, err := json.Marshal(snapshotOf(before).diff(snapshotOf(after)))
+	if err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(snapshotOf(after))
+	if err != nil {
+		return err
+	}
+	return tx.History.Add(model.BookRevision{
+		BookID:   after.ID,
+		Version:  version + 1,
+		Action:   action,
+		Actor:    ActorFromContext(ctx),
+		Changes:  string(changes),
+		Snapshot: string(snapshot),
+	})
+}
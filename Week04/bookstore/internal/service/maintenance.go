@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+const purgeBatchSize = 100
+
+// MaintenanceService 提供定时执行的维护任务，每个方法都是一次完整的任务，可以重复执行
+type MaintenanceService struct {
+	MaintenanceRepository repository.MaintenanceRepository
+	IdempotencyRepository repository.IdempotencyRepository
+	CoverService          CoverService
+	Clock                 clock.Clock
+	// PurgeDeletedAfter 是软删除的图书保留多久后物理删除
+	PurgeDeletedAfter time.Duration
+}
+
+func NewMaintenanceService(cfg config.Config, m repository.MaintenanceRepository, i repository.IdempotencyRepository,
+	covers CoverService, clk clock.Clock) MaintenanceService {
+	return MaintenanceService{MaintenanceRepository: m, IdempotencyRepository: i, CoverService: covers, Clock: clk,
+		PurgeDeletedAfter: cfg.PurgeDeletedAfter}
+}
+
+// PurgeDeletedBooks 物理删除软删除超过 PurgeDeletedAfter 的图书，连同它们的评论和封面
+func (m *MaintenanceService) PurgeDeletedBooks(ctx context.Context) error {
+	before := m.Clock.Now().Add(-m.PurgeDeletedAfter)
+	for ctx.Err() == nil {
+		books, err := m.MaintenanceRepository.ListDeletedBooks(before, purgeBatchSize)
+		if err != nil || len(books) == 0 {
+			return err
+		}
+		ids := make([]uint, len(books))
+		for i, book := range books {
+			ids[i] = book.ID
+			if book.CoverType != "" {
+				m.CoverService.remove(ctx, book, book.CoverType)
+			}
+		}
+		if err := m.MaintenanceRepository.PurgeBooks(ids); err != nil {
+			return err
+		}
+		log.Printf("maintenance: purged %d deleted books", len(ids))
+	}
+	return ctx.Err()
+}
+
+// ExpireIdempotency 删除过期的 Idempotency-Key 记录
+func (m *MaintenanceService) ExpireIdempotency(ctx context.Context) error {
+	return m.IdempotencyRepository.DeleteExpired(m.Clock.Now())
+}
+
+// RecomputeRatings 按评论重新计算图书评分，修正增量更新可能产生的偏差
+func (m *MaintenanceService) RecomputeRatings(ctx context.Context) error {
+	fixed, err := m.MaintenanceRepository.RecomputeRatings(m.Clock.Now())
+	if fixed > 0 {
+		log.Printf("maintenance: recomputed ratings of %d books", fixed)
+	}
+	return err
+}
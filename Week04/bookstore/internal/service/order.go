@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+var (
+	// ErrEmptyCart 表示购物车是空的，不能结算
+	ErrEmptyCart = i18n.NewError("order.empty_cart")
+	// ErrBookUnavailable 表示购物车中有图书已经被删除
+	ErrBookUnavailable = i18n.NewError("order.book_unavailable")
+	// ErrInvalidTransition 表示订单不能从当前状态变为目标状态
+	ErrInvalidTransition = i18n.NewError("order.invalid_transition")
+)
+
+type OrderService struct {
+	OrderRepository repository.OrderRepository
+	Transactor      repository.Transactor
+	PaymentService  PaymentService
+	BookService     BookService
+}
+
+func NewOrderService(o repository.OrderRepository, t repository.Transactor, p PaymentService,
+	b BookService) OrderService {
+	return OrderService{OrderRepository: o, Transactor: t, PaymentService: p, BookService: b}
+}
+
+// Checkout 把当前顾客的购物车结算成订单：按当前的优惠规则和优惠码 coupon 计算每本书的实际售价，
+// 连同用到的优惠一起保存在订单行里，然后清空购物车。coupon 可以为空
+func (o *OrderService) Checkout(ctx context.Context, coupon string) (model.Order, error) {
+	customer, err := customerOf(ctx)
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	var order model.Order
+	err = o.Transactor.Transaction(func(tx repository.Tx) error {
+		items, err := tx.Carts.List(ctx, customer)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return ErrEmptyCart
+		}
+
+		books := make([]model.Book, len(items))
+		for i, item := range items {
+			book, err := tx.Books.GetByID(ctx, item.BookID)
+			if err == repository.ErrNotFound {
+				return ErrBookUnavailable
+			}
+			if err != nil {
+				return err
+			}
+			books[i] = book
+		}
+		quotes, err := o.BookService.Quotes(ctx, books, coupon)
+		if err != nil {
+			return err
+		}
+
+		order = model.Order{Customer: customer, Status: model.OrderPending}
+		var total int64
+		for i, item := range items {
+			adjustments, err := json.Marshal(quotes[i].Adjustments)
+			if err != nil {
+				return err
+			}
+			order.Items = append(order.Items, model.OrderItem{
+				BookID:      books[i].ID,
+				ISBN:        books[i].ISBN,
+				Title:       books[i].Title,
+				BasePrice:   quotes[i].BasePrice,
+				UnitPrice:   quotes[i].EffectivePrice,
+				Adjustments: string(adjustments),
+				Quantity:    item.Quantity,
+			})
+			total += cents(quotes[i].EffectivePrice) * int64(item.Quantity)
+		}
+		order.Total = fromCents(total)
+
+		if order, err = tx.Orders.Create(ctx, order); err != nil {
+			return err
+		}
+		return tx.Carts.Clear(ctx, customer)
+	})
+	if err != nil {
+		return model.Order{}, err
+	}
+	return order, nil
+}
+
+// List 返回当前顾客的订单
+func (o *OrderService) List(ctx context.Context) ([]model.Order, error) {
+	customer, err := customerOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return o.OrderRepository.List(ctx, customer, "")
+}
+
+// ListAll 返回店铺的所有订单，status 为空时不过滤
+func (o *OrderService) ListAll(ctx context.Context, status string) ([]model.Order, error) {
+	return o.OrderRepository.List(ctx, "", status)
+}
+
+// Get 返回当前顾客的订单，别人的订单当作不存在
+func (o *OrderService) Get(ctx context.Context, id uint) (model.Order, error) {
+	customer, err := customerOf(ctx)
+	if err != nil {
+		return model.Order{}, err
+	}
+	order, err := o.OrderRepository.GetByID(ctx, id)
+	if err == nil && order.Customer != customer {
+		return model.Order{}, repository.ErrNotFound
+	}
+	return order, err
+}
+
+// Cancel 取消当前顾客自己的订单，只能取消还没有支付的订单
+func (o *OrderService) Cancel(ctx context.Context, id uint) (model.Order, error) {
+	order, err := o.Get(ctx, id)
+	if err != nil {
+		return order, err
+	}
+	if order.Status != model.OrderPending {
+		return order, ErrInvalidTransition
+	}
+	return o.transition(ctx, order, model.OrderCancelled)
+}
+
+// UpdateStatus 是店铺管理订单状态的入口，状态只能按 model.CanTransition 允许的方向变化。
+// 发货时对在线支付请款，取消已支付的订单时退款
+func (o *OrderService) UpdateStatus(ctx context.Context, id uint, status string) (model.Order, error) {
+	order, err := o.OrderRepository.GetByID(ctx, id)
+	if err != nil {
+		return order, err
+	}
+	return o.transition(ctx, order, status)
+}
+
+// transition 先把已支付的订单改成 shipping 或 cancelling 占住这次状态变化，再调用网关请款或退款，
+// 最后改成目标状态。同时发起的发货和取消只有一个能占住订单，网关不会既请款又退款
+func (o *OrderService) transition(ctx context.Context, order model.Order, status string) (model.Order, error) {
+	if !model.CanTransition(order.Status, status) {
+		return order, ErrInvalidTransition
+	}
+	claim, charge := o.claimStatus(order, status)
+	if !charge {
+		return o.OrderRepository.UpdateStatus(ctx, order.ID, order.Status, status)
+	}
+	if order.Status != claim {
+		claimed, err := o.OrderRepository.UpdateStatus(ctx, order.ID, order.Status, claim)
+		if err != nil {
+			return claimed, err
+		}
+		order = claimed
+	}
+
+	var err error
+	if status == model.OrderShipped {
+		err = o.PaymentService.Capture(ctx, order.ID)
+	} else {
+		err = o.PaymentService.Refund(ctx, order.ID)
+	}
+	if err != nil {
+		// 放回 paid，店铺可以重试
+		if _, rerr := o.OrderRepository.UpdateStatus(ctx, order.ID, claim, model.OrderPaid); rerr != nil {
+			log.Printf("failed to release order %d from %s: %v", order.ID, claim, rerr)
+		}
+		return order, err
+	}
+	updated, err := o.OrderRepository.UpdateStatus(ctx, order.ID, claim, status)
+	// 退款的结果已经把订单改成了 cancelled
+	if err == repository.ErrConflict && updated.Status == status {
+		err = nil
+	}
+	return updated, err
+}
+
+// claimStatus 返回变为 status 之前需要占住订单的中间状态，不需要调用网关时 charge 为 false
+func (o *OrderService) claimStatus(order model.Order, status string) (claim string, charge bool) {
+	switch {
+	case status == model.OrderShipped:
+		return model.OrderShipping, true
+	case status == model.OrderCancelled && order.Status != model.OrderPending:
+		return model.OrderCancelling, true
+	}
+	return "", false
+}
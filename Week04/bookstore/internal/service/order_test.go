@@ -0,0 +1,145 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/payment"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository/memory"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/service"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+var errGateway = errors.New("gateway unavailable")
+
+// gateway 记录请款和退款的次数，设置了 block 时请款会等到 block 关闭
+type gateway struct {
+	mu       sync.Mutex
+	captures int
+	refunds  int
+	fail     bool
+	started  chan struct{}
+	block    chan struct{}
+}
+
+func (g *gateway) Authorize(ctx context.Context, req payment.AuthorizeRequest) (payment.Payment, error) {
+	return payment.Payment{}, errGateway
+}
+
+func (g *gateway) Capture(ctx context.Context, id string, amount int64) (payment.Payment, error) {
+	g.mu.Lock()
+	g.captures++
+	g.mu.Unlock()
+	if g.block != nil {
+		close(g.started)
+		<-g.block
+	}
+	if g.fail {
+		return payment.Payment{}, errGateway
+	}
+	return payment.Payment{ID: id, Status: payment.StatusCaptured, Amount: amount}, nil
+}
+
+func (g *gateway) Refund(ctx context.Context, id string, amount int64) (payment.Payment, error) {
+	g.mu.Lock()
+	g.refunds++
+	g.mu.Unlock()
+	if g.fail {
+		return payment.Payment{}, errGateway
+	}
+	return payment.Payment{ID: id, Status: payment.StatusRefunded, Amount: amount}, nil
+}
+
+func (g *gateway) VerifyWebhook(header http.Header, body []byte) (payment.Event, error) {
+	return payment.Event{}, errGateway
+}
+
+// paidOrder 创建一个已经授权支付的订单
+func paidOrder(t *testing.T, g *gateway) (context.Context, service.OrderService, model.Order) {
+	t.Helper()
+	store := memory.NewStore(clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)))
+	demo, err := store.Tenants.Save(model.Tenant{Slug: "demo", Name: "Demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := tenant.WithTenant(context.Background(), demo)
+	order, err := store.Orders.Create(ctx, model.Order{Customer: "bob", Status: model.OrderPaid, Total: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Payments.Create(ctx, model.Payment{OrderID: order.ID, ProviderRef: "pay_1", Reference: "order-1",
+		Status: payment.StatusAuthorized, Amount: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payments := service.NewPaymentService(config.Config{}, g, store.Payments, store.Orders, store.Tenants, store)
+	orders := service.NewOrderService(store.Orders, store, payments, service.BookService{})
+	return ctx, orders, order
+}
+
+// TestShipAndCancelClaimOrder 发货请款期间店铺又取消订单，取消应该失败，网关不能再退款
+func TestShipAndCancelClaimOrder(t *testing.T) {
+	g := &gateway{started: make(chan struct{}), block: make(chan struct{})}
+	ctx, orders, order := paidOrder(t, g)
+
+	shipped := make(chan error, 1)
+	go func() {
+		_, err := orders.UpdateStatus(ctx, order.ID, model.OrderShipped)
+		shipped <- err
+	}()
+	<-g.started
+
+	got, err := orders.UpdateStatus(ctx, order.ID, model.OrderCancelled)
+	if err == nil {
+		t.Fatalf("cancel during capture: UpdateStatus = %+v, want an error", got)
+	}
+	close(g.block)
+	if err := <-shipped; err != nil {
+		t.Fatalf("ship: UpdateStatus = %v", err)
+	}
+
+	got, err = orders.UpdateStatus(ctx, order.ID, model.OrderCancelled)
+	if err != service.ErrInvalidTransition {
+		t.Fatalf("cancel after ship: UpdateStatus = %v, want %v", err, service.ErrInvalidTransition)
+	}
+	if got.Status != model.OrderShipped || g.captures != 1 || g.refunds != 0 {
+		t.Fatalf("Status = %s, captures = %d, refunds = %d, want shipped, 1, 0", got.Status, g.captures, g.refunds)
+	}
+}
+
+func TestTransitionGatewayFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+	}{
+		{name: "ship", status: model.OrderShipped},
+		{name: "cancel", status: model.OrderCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &gateway{fail: true}
+			ctx, orders, order := paidOrder(t, g)
+			if _, err := orders.UpdateStatus(ctx, order.ID, tt.status); err != errGateway {
+				t.Fatalf("UpdateStatus = %v, want %v", err, errGateway)
+			}
+			// 网关调用失败后订单回到 paid，可以重试
+			got, err := orders.UpdateStatus(ctx, order.ID, model.OrderPaid)
+			if err != service.ErrInvalidTransition || got.Status != model.OrderPaid {
+				t.Fatalf("Status = %s, err = %v, want paid, %v", got.Status, err, service.ErrInvalidTransition)
+			}
+
+			g.fail = false
+			got, err = orders.UpdateStatus(ctx, order.ID, tt.status)
+			if err != nil || got.Status != tt.status {
+				t.Fatalf("retry: Status = %s, err = %v, want %s", got.Status, err, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/payment"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+)
+
+var (
+	// ErrPaymentDeclined 表示网关拒绝了这次支付，可以换一个 source 重新支付
+	ErrPaymentDeclined = i18n.NewError("payment.declined")
+	// ErrPaymentInProgress 表示订单已经有一笔在处理中或者已经授权的支付
+	ErrPaymentInProgress = i18n.NewError("payment.in_progress")
+)
+
+// paymentTransitions 是支付状态能前进的方向，回调可能重复或者乱序，不在这里的变化会被忽略
+var paymentTransitions = map[string][]string{
+	payment.StatusPending:    {payment.StatusAuthorized, payment.StatusDeclined},
+	payment.StatusAuthorized: {payment.StatusCaptured, payment.StatusRefunded},
+	payment.StatusCaptured:   {payment.StatusRefunded},
+}
+
+type PaymentService struct {
+	Provider          payment.Provider
+	PaymentRepository repository.PaymentRepository
+	OrderRepository   repository.OrderRepository
+	TenantRepository  repository.TenantRepository
+	Transactor        repository.Transactor
+	Currency          string
+}
+
+func NewPaymentService(cfg config.Config, p payment.Provider, pr repository.PaymentRepository,
+	o repository.OrderRepository, tr repository.TenantRepository, t repository.Transactor) PaymentService {
+	return PaymentService{
+		Provider:          p,
+		PaymentRepository: pr,
+		OrderRepository:   o,
+		TenantRepository:  tr,
+		Transactor:        t,
+		Currency:          cfg.PaymentCurrency,
+	}
+}
+
+// Pay 用 source 支付当前顾客待支付的订单。授权成功时订单变为 paid；网关返回 pending 时订单保持 pending，
+// 结果通过回调送达；被拒绝时返回支付和 ErrPaymentDeclined。
+// 检查和授权之间没有加锁，并发的 Pay 靠 Reference 去重：它们算出同一个 Reference，
+// 网关对同一个 Reference 只授权一次，后写入的请求遇到唯一索引冲突，返回 ErrPaymentInProgress
+func (s *PaymentService) Pay(ctx context.Context, orderID uint, source string) (model.Payment, error) {
+	order, err := s.ownOrder(ctx, orderID)
+	if err != nil {
+		return model.Payment{}, err
+	}
+	if order.Status != model.OrderPending {
+		return model.Payment{}, ErrInvalidTransition
+	}
+	latest, err := s.PaymentRepository.Latest(ctx, orderID)
+	if err == nil && (latest.Status == payment.StatusPending || latest.Status == payment.StatusAuthorized) {
+		return latest, ErrPaymentInProgress
+	}
+	if err != nil && err != repository.ErrNotFound {
+		return model.Payment{}, err
+	}
+	reference := paymentReference(order.ID, latest)
+
+	p, err := s.Provider.Authorize(ctx, payment.AuthorizeRequest{
+		Amount:    cents(order.Total),
+		Currency:  s.Currency,
+		Reference: reference,
+		Source:    source,
+	})
+	if err != nil {
+		return model.Payment{}, err
+	}
+
+	// 先按 pending 记下支付，再按网关同步返回的结果推进，和处理回调走同一条路径
+	var saved model.Payment
+	var refund bool
+	err = s.Transactor.Transaction(func(tx repository.Tx) error {
+		_, err := tx.Payments.Create(ctx, model.Payment{
+			OrderID:     order.ID,
+			ProviderRef: p.ID,
+			Reference:   reference,
+			Status:      payment.StatusPending,
+			Amount:      order.Total,
+			Currency:    s.Currency,
+		})
+		if err != nil {
+			return err
+		}
+		saved, refund, err = s.apply(ctx, tx, p)
+		return err
+	})
+	if err == repository.ErrDuplicate {
+		// 并发的 Pay 已经记下了网关返回的同一笔支付
+		latest, err := s.PaymentRepository.Latest(ctx, orderID)
+		if err != nil {
+			return model.Payment{}, err
+		}
+		return latest, ErrPaymentInProgress
+	}
+	if err != nil {
+		return model.Payment{}, err
+	}
+	if refund {
+		return s.refund(ctx, saved)
+	}
+	if saved.Status == payment.StatusDeclined {
+		return saved, ErrPaymentDeclined
+	}
+	return saved, nil
+}
+
+// Latest 返回当前顾客订单最新的一次支付
+func (s *PaymentService) Latest(ctx context.Context, orderID uint) (model.Payment, error) {
+	if _, err := s.ownOrder(ctx, orderID); err != nil {
+		return model.Payment{}, err
+	}
+	return s.PaymentRepository.Latest(ctx, orderID)
+}
+
+// Capture 对订单已授权的支付请款，订单没有在线支付（例如线下付款后由店铺标记为 paid）时什么也不做
+func (s *PaymentService) Capture(ctx context.Context, orderID uint) error {
+	latest, err := s.PaymentRepository.Latest(ctx, orderID)
+	if err == repository.ErrNotFound || (err == nil && latest.Status != payment.StatusAuthorized) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p, err := s.Provider.Capture(ctx, latest.ProviderRef, cents(latest.Amount))
+	if err != nil {
+		return err
+	}
+	_, err = s.reconcile(ctx, p)
+	return err
+}
+
+// Refund 退还订单已授权或已请款的支付，订单没有这样的支付时什么也不做
+func (s *PaymentService) Refund(ctx context.Context, orderID uint) error {
+	latest, err := s.PaymentRepository.Latest(ctx, orderID)
+	if err == repository.ErrNotFound ||
+		(err == nil && latest.Status != payment.StatusAuthorized && latest.Status != payment.StatusCaptured) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.refund(ctx, latest)
+	return err
+}
+
+// HandleWebhook 校验并处理网关回调。回调不带店铺信息，按支付找到店铺后再处理，
+// 还没有记录的支付返回 ErrNotFound，让网关稍后重试
+func (s *PaymentService) HandleWebhook(ctx context.Context, header http.Header, body []byte) error {
+	event, err := s.Provider.VerifyWebhook(header, body)
+	if err != nil {
+		return err
+	}
+	saved, err := s.PaymentRepository.GetByProviderRef(event.Payment.ID)
+	if err != nil {
+		return err
+	}
+	t, err := s.TenantRepository.GetByID(saved.TenantID)
+	if err != nil {
+		return err
+	}
+	_, err = s.reconcile(tenant.WithTenant(ctx, t), event.Payment)
+	return err
+}
+
+func (s *PaymentService) refund(ctx context.Context, saved model.Payment) (model.Payment, error) {
+	p, err := s.Provider.Refund(ctx, saved.ProviderRef, cents(saved.Amount))
+	if err != nil {
+		return saved, err
+	}
+	return s.reconcile(ctx, p)
+}
+
+// reconcile 在一个事务里按网关给出的状态更新支付和订单，需要退款时发起退款
+func (s *PaymentService) reconcile(ctx context.Context, p payment.Payment) (model.Payment, error) {
+	var saved model.Payment
+	var refund bool
+	err := s.Transactor.Transaction(func(tx repository.Tx) error {
+		var err error
+		saved, refund, err = s.apply(ctx, tx, p)
+		return err
+	})
+	if err != nil {
+		return model.Payment{}, err
+	}
+	if refund {
+		return s.refund(ctx, saved)
+	}
+	return saved, nil
+}
+
+// apply 把支付推进到网关给出的状态：授权成功时订单从 pending 变为 paid，退款后订单变为 cancelled。
+// 授权成功但订单已经不是 pending（例如顾客在等待结果时取消了订单）时返回 refund 为 true
+func (s *PaymentService) apply(ctx context.Context, tx repository.Tx, p payment.Payment) (saved model.Payment, refund bool, err error) {
+	current, err := tx.Payments.GetByProviderRef(p.ID)
+	if err != nil {
+		return current, false, err
+	}
+	if !canAdvance(current.Status, p.Status) {
+		return current, false, nil
+	}
+	from := current.Status
+	current.Status, current.DeclineCode = p.Status, p.DeclineCode
+	if saved, err = tx.Payments.UpdateStatus(ctx, current, from); err != nil {
+		return saved, false, err
+	}
+
+	switch p.Status {
+	case payment.StatusAuthorized:
+		_, err = tx.Orders.UpdateStatus(ctx, saved.OrderID, model.OrderPending, model.OrderPaid)
+		if err == repository.ErrConflict {
+			log.Printf("order %d is no longer pending, refunding payment %s", saved.OrderID, saved.ProviderRef)
+			return saved, true, nil
+		}
+	case payment.StatusRefunded:
+		// 店铺取消订单时订单已经是 cancelling，网关直接退款时还是 paid
+		_, err = tx.Orders.UpdateStatus(ctx, saved.OrderID, model.OrderCancelling, model.OrderCancelled)
+		if err == repository.ErrConflict {
+			_, err = tx.Orders.UpdateStatus(ctx, saved.OrderID, model.OrderPaid, model.OrderCancelled)
+		}
+		if err == repository.ErrConflict {
+			err = nil
+		}
+	}
+	return saved, false, err
+}
+
+// paymentReference 返回这次支付的幂等键。订单的第一次支付是 order-<id>，
+// 之前的支付被拒绝后换卡重试时带上最新一次支付的 ID，每次重试都是新的支付
+func paymentReference(orderID uint, latest model.Payment) string {
+	if latest.ID == 0 {
+		return fmt.Sprintf("order-%d", orderID)
+	}
+	return fmt.Sprintf("order-%d-after-%d", orderID, latest.ID)
+}
+
+func canAdvance(from, to string) bool {
+	for _, s := range paymentTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ownOrder 返回当前顾客的订单，别人的订单当作不存在
+func (s *PaymentService) ownOrder(ctx context.Context, orderID uint) (model.Order, error) {
+	customer, err := customerOf(ctx)
+	if err != nil {
+		return model.Order{}, err
+	}
+	order, err := s.OrderRepository.GetByID(ctx, orderID)
+	if err == nil && order.Customer != customer {
+		return model.Order{}, repository.ErrNotFound
+	}
+	return order, err
+}
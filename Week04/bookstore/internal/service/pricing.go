@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+// ErrInvalidPricingRule 表示优惠规则的取值不合法
+var ErrInvalidPricingRule = i18n.NewError("pricing.invalid_rule")
+
+type PricingService struct {
+	PricingRuleRepository repository.PricingRuleRepository
+	CategoryRepository    repository.CategoryRepository
+}
+
+func NewPricingService(p repository.PricingRuleRepository, c repository.CategoryRepository) PricingService {
+	return PricingService{PricingRuleRepository: p, CategoryRepository: c}
+}
+
+func (p *PricingService) GetAll(ctx context.Context) ([]model.PricingRule, error) {
+	return p.PricingRuleRepository.GetAll(ctx)
+}
+
+func (p *PricingService) GetByID(ctx context.Context, id uint) (model.PricingRule, error) {
+	return p.PricingRuleRepository.GetByID(ctx, id)
+}
+
+// Save 校验并保存优惠规则，分类规则的目标必须是店铺内存在的分类
+func (p *PricingService) Save(ctx context.Context, rule model.PricingRule) (model.PricingRule, error) {
+	if err := validatePricingRule(rule); err != nil {
+		return rule, err
+	}
+	if rule.Scope == model.ScopeCategory {
+		id, err := strconv.ParseUint(rule.Target, 10, 64)
+		if err != nil {
+			return rule, ErrInvalidPricingRule
+		}
+		_, err = p.CategoryRepository.GetByID(ctx, uint(id))
+		if err == repository.ErrNotFound {
+			return rule, ErrInvalidPricingRule
+		}
+		if err != nil {
+			return rule, err
+		}
+	}
+	return p.PricingRuleRepository.Save(ctx, rule)
+}
+
+func (p *PricingService) Delete(ctx context.Context, rule model.PricingRule) error {
+	return p.PricingRuleRepository.Delete(ctx, rule)
+}
+
+func validatePricingRule(rule model.PricingRule) error {
+	switch rule.Kind {
+	case model.DiscountPercentage:
+		if rule.Amount <= 0 || rule.Amount > 100 {
+			return ErrInvalidPricingRule
+		}
+	case model.DiscountFixed:
+		if rule.Amount <= 0 {
+			return ErrInvalidPricingRule
+		}
+	default:
+		return ErrInvalidPricingRule
+	}
+
+	switch rule.Scope {
+	case model.ScopeAll:
+	case model.ScopeBook, model.ScopeAuthor, model.ScopeCategory:
+		if rule.Target == "" {
+			return ErrInvalidPricingRule
+		}
+	default:
+		return ErrInvalidPricingRule
+	}
+
+	if rule.StartsAt != nil && rule.EndsAt != nil && !rule.EndsAt.After(*rule.StartsAt) {
+		return ErrInvalidPricingRule
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package service
+
+import "github.com/google/wire"
+
+var ProviderSet = wire.NewSet(NewBookService, NewWebhookService, NewTenantService, NewPricingService,
+	NewCoverService, NewReviewService, NewMaintenanceService,
+	NewEnricher, NewCartService, NewOrderService, NewPaymentService,
+	NewRecommendationService, NewCategoryService, NewTranslationService)
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+// MaxRecommendations 是一次最多返回的推荐数
+const MaxRecommendations = 20
+
+// RecommendationService 计算"买了这本书的顾客也买了"。推荐由定时任务 Compute 批量计算，
+// 一起被买过的图书不够时用店铺的畅销书补足
+type RecommendationService struct {
+	BookRepository           repository.BookRepository
+	TenantRepository         repository.TenantRepository
+	RecommendationRepository repository.RecommendationRepository
+	Clock                    clock.Clock
+	// MinSupport 是两本书至少被多少个顾客一起买过才算相关，过滤偶然的组合
+	MinSupport int
+}
+
+func NewRecommendationService(cfg config.Config, b repository.BookRepository, t repository.TenantRepository,
+	r repository.RecommendationRepository, clk clock.Clock) RecommendationService {
+	return RecommendationService{BookRepository: b, TenantRepository: t, RecommendationRepository: r, Clock: clk,
+		MinSupport: cfg.RecommendationMinSupport}
+}
+
+// Compute 按已支付和已发货的订单重新计算所有店铺的推荐。两本书的相关度是余弦相似度：
+// 一起买过它们的顾客数除以分别买过它们的顾客数之积的平方根
+func (r *RecommendationService) Compute(ctx context.Context) error {
+	purchases, err := r.RecommendationRepository.Purchases()
+	if err != nil {
+		return err
+	}
+	// 店铺 -> 顾客 -> 买过的书
+	baskets := make(map[uint]map[string][]uint)
+	for _, p := range purchases {
+		if baskets[p.TenantID] == nil {
+			baskets[p.TenantID] = make(map[string][]uint)
+		}
+		baskets[p.TenantID][p.Customer] = append(baskets[p.TenantID][p.Customer], p.BookID)
+	}
+
+	// 遍历所有店铺，没有订单的店铺也要清掉旧的推荐
+	tenants, err := r.TenantRepository.GetAll()
+	if err != nil {
+		return err
+	}
+	now := r.Clock.Now()
+	for _, t := range tenants {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		recs := r.related(baskets[t.ID], now)
+		if err := r.RecommendationRepository.Replace(t.ID, recs); err != nil {
+			return err
+		}
+		log.Printf("recommendations: %d recommendations for tenant %s", len(recs), t.Slug)
+	}
+	return nil
+}
+
+type bookPair struct {
+	book, related uint
+}
+
+// related 计算一个店铺的推荐，每本书保留相关度最高的 MaxRecommendations 本
+func (r *RecommendationService) related(baskets map[string][]uint, now time.Time) []model.BookRecommendation {
+	buyers := make(map[uint]int)
+	together := make(map[bookPair]int)
+	for _, books := range baskets {
+		for _, a := range books {
+			buyers[a]++
+			for _, b := range books {
+				if a != b {
+					together[bookPair{a, b}]++
+				}
+			}
+		}
+	}
+
+	byBook := make(map[uint][]model.BookRecommendation)
+	for pair, n := range together {
+		if n < r.MinSupport {
+			continue
+		}
+		byBook[pair.book] = append(byBook[pair.book], model.BookRecommendation{
+			BookID:        pair.book,
+			RelatedBookID: pair.related,
+			Score:         float64(n) / math.Sqrt(float64(buyers[pair.book]*buyers[pair.related])),
+			ComputedAt:    now,
+		})
+	}
+
+	var recs []model.BookRecommendation
+	for _, related := range byBook {
+		sort.Slice(related, func(i, j int) bool {
+			if related[i].Score != related[j].Score {
+				return related[i].Score > related[j].Score
+			}
+			return related[i].RelatedBookID < related[j].RelatedBookID
+		})
+		if len(related) > MaxRecommendations {
+			related = related[:MaxRecommendations]
+		}
+		recs = append(recs, related...)
+	}
+	return recs
+}
+
+// Related 返回和图书相关的至多 limit 本书，先按相关度，不够时用畅销书补足，已删除的图书跳过
+func (r *RecommendationService) Related(ctx context.Context, bookID uint, limit int) ([]model.Recommendation, error) {
+	if _, err := r.BookRepository.GetByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+
+	seen := map[uint]bool{bookID: true}
+	var recs []model.Recommendation
+	add := func(id uint, reason string, score float64) error {
+		if seen[id] || len(recs) >= limit {
+			return nil
+		}
+		seen[id] = true
+		book, err := r.BookRepository.GetByID(ctx, id)
+		if err == repository.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		recs = append(recs, model.Recommendation{Book: book, Reason: reason, Score: score})
+		return nil
+	}
+
+	related, err := r.RecommendationRepository.Related(ctx, bookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range related {
+		if err := add(rec.RelatedBookID, model.RecommendAlsoBought, rec.Score); err != nil {
+			return nil, err
+		}
+	}
+	if len(recs) >= limit {
+		return recs, nil
+	}
+
+	// 多取一些，跳过本书、已经推荐过的和已删除的图书后还能补足
+	best, err := r.RecommendationRepository.BestSellers(ctx, 2*limit+1)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range best {
+		if err := add(id, model.RecommendBestSeller, 0); err != nil {
+			return nil, err
+		}
+	}
+	return recs, nil
+}
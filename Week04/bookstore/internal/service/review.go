@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+var (
+	// ErrInvalidReview 表示评分不在 1 到 5 之间或者审核状态不合法
+	ErrInvalidReview = i18n.NewError("review.invalid")
+	// ErrReviewAuthorRequired 表示匿名用户不能发表评论
+	ErrReviewAuthorRequired = i18n.NewError("review.author_required")
+	// ErrReviewForbidden 表示只有评论作者可以修改或删除评论
+	ErrReviewForbidden = i18n.NewError("review.forbidden")
+)
+
+type ReviewService struct {
+	BookRepository   repository.BookRepository
+	ReviewRepository repository.ReviewRepository
+	Transactor       repository.Transactor
+}
+
+func NewReviewService(b repository.BookRepository, r repository.ReviewRepository, t repository.Transactor) ReviewService {
+	return ReviewService{BookRepository: b, ReviewRepository: r, Transactor: t}
+}
+
+// List 返回图书的评论，status 为空时返回所有状态
+func (r *ReviewService) List(ctx context.Context, bookID uint, status string) ([]model.Review, error) {
+	if _, err := r.BookRepository.GetByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	return r.ReviewRepository.ListByBook(bookID, status)
+}
+
+// Create 以当前操作人的身份发表评论，新评论需要审核后才会公开
+func (r *ReviewService) Create(ctx context.Context, bookID uint, rating int, text string) (model.Review, error) {
+	author := ActorFromContext(ctx)
+	if author == AnonymousActor {
+		return model.Review{}, ErrReviewAuthorRequired
+	}
+	if rating < 1 || rating > 5 {
+		return model.Review{}, ErrInvalidReview
+	}
+	if _, err := r.BookRepository.GetByID(ctx, bookID); err != nil {
+		return model.Review{}, err
+	}
+	return r.ReviewRepository.Save(model.Review{
+		BookID: bookID,
+		Author: author,
+		Rating: rating,
+		Text:   text,
+		Status: model.ReviewPending,
+	})
+}
+
+// Update 修改自己的评论，修改后重新进入待审核状态
+func (r *ReviewService) Update(ctx context.Context, bookID, id uint, rating int, text string) (model.Review, error) {
+	if rating < 1 || rating > 5 {
+		return model.Review{}, ErrInvalidReview
+	}
+	return r.change(ctx, bookID, id, true, func(review *model.Review) {
+		review.Rating = rating
+		review.Text = text
+		review.Status = model.ReviewPending
+	})
+}
+
+// Moderate 修改评论的审核状态
+func (r *ReviewService) Moderate(ctx context.Context, bookID, id uint, status string) (model.Review, error) {
+	if status != model.ReviewPending && status != model.ReviewApproved && status != model.ReviewHidden {
+		return model.Review{}, ErrInvalidReview
+	}
+	return r.change(ctx, bookID, id, false, func(review *model.Review) {
+		review.Status = status
+	})
+}
+
+// Delete 删除评论，ownOnly 为 true 时只允许作者本人删除
+func (r *ReviewService) Delete(ctx context.Context, bookID, id uint, ownOnly bool) error {
+	return r.Transactor.Transaction(func(tx repository.Tx) error {
+		review, err := r.get(ctx, tx, bookID, id, ownOnly)
+		if err != nil {
+			return err
+		}
+		if err := tx.Reviews.Delete(review); err != nil {
+			return err
+		}
+		sum, count := ratingOf(review)
+		return addRating(ctx, tx, bookID, -sum, -count)
+	})
+}
+
+// change 在一个事务里锁住并修改评论，并按修改前后的差值更新图书的评分，
+// 并发审核同一条评论时差值不会重复计入
+func (r *ReviewService) change(ctx context.Context, bookID, id uint, ownOnly bool,
+	apply func(review *model.Review)) (model.Review, error) {
+	var saved model.Review
+	err := r.Transactor.Transaction(func(tx repository.Tx) error {
+		review, err := r.get(ctx, tx, bookID, id, ownOnly)
+		if err != nil {
+			return err
+		}
+		oldSum, oldCount := ratingOf(review)
+		apply(&review)
+		if saved, err = tx.Reviews.Save(review); err != nil {
+			return err
+		}
+		sum, count := ratingOf(saved)
+		return addRating(ctx, tx, bookID, sum-oldSum, count-oldCount)
+	})
+	if err != nil {
+		return model.Review{}, err
+	}
+	return saved, nil
+}
+
+func (r *ReviewService) get(ctx context.Context, tx repository.Tx, bookID, id uint, ownOnly bool) (model.Review, error) {
+	if _, err := tx.Books.GetByID(ctx, bookID); err != nil {
+		return model.Review{}, err
+	}
+	review, err := tx.Reviews.GetForUpdate(bookID, id)
+	if err != nil {
+		return review, err
+	}
+	if ownOnly && review.Author != ActorFromContext(ctx) {
+		return review, ErrReviewForbidden
+	}
+	return review, nil
+}
+
+// ratingOf 返回评论对图书评分的贡献，只有审核通过的评论计入
+func ratingOf(review model.Review) (sum, count int) {
+	if review.Status != model.ReviewApproved {
+		return 0, 0
+	}
+	return review.Rating, 1
+}
+
+func addRating(ctx context.Context, tx repository.Tx, bookID uint, sum, count int) error {
+	if sum == 0 && count == 0 {
+		return nil
+	}
+	return tx.Books.AddRating(ctx, bookID, sum, count)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"regexp"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+var (
+	ErrInvalidTenantSlug  = i18n.NewError("tenant.invalid_slug")
+	ErrInvalidTenantLimit = i18n.NewError("tenant.invalid_limit")
+)
+
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+type TenantService struct {
+	TenantRepository repository.TenantRepository
+}
+
+func NewTenantService(t repository.TenantRepository) TenantService {
+	return TenantService{TenantRepository: t}
+}
+
+func (s *TenantService) GetAll() ([]model.Tenant, error) {
+	return s.TenantRepository.GetAll()
+}
+
+func (s *TenantService) GetByID(id uint) (model.Tenant, error) {
+	return s.TenantRepository.GetByID(id)
+}
+
+// GetBySlug 用于根据请求头或 token 中的店铺标识解析店铺
+func (s *TenantService) GetBySlug(slug string) (model.Tenant, error) {
+	return s.TenantRepository.GetBySlug(slug)
+}
+
+// Save 校验并保存店铺，slug 重复时返回 repository.ErrDuplicate
+func (s *TenantService) Save(t model.Tenant) (model.Tenant, error) {
+	if !tenantSlugPattern.MatchString(t.Slug) {
+		return t, ErrInvalidTenantSlug
+	}
+	if t.MaxBooks < 0 || t.RequestsPerSecond < 0 {
+		return t, ErrInvalidTenantLimit
+	}
+	return s.TenantRepository.Save(t)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+var (
+	// ErrInvalidLocale 表示语言标签不合法，或者是图书本身内容的语言
+	ErrInvalidLocale = i18n.NewError("translation.invalid_locale")
+	// ErrEmptyTranslation 表示翻译的书名和简介都为空
+	ErrEmptyTranslation = i18n.NewError("translation.empty")
+)
+
+// Localization 是按语言回退链替换过书名和简介的图书。
+// Locales 是实际用到的语言，按回退链的顺序排列；LastModified 是回退链上的翻译中最新的修改时间
+type Localization struct {
+	Books        []model.Book
+	Locales      []string
+	LastModified time.Time
+}
+
+type TranslationService struct {
+	BookRepository        repository.BookRepository
+	TranslationRepository repository.TranslationRepository
+	// DefaultLocale 是图书本身的书名和简介使用的语言
+	DefaultLocale string
+}
+
+func NewTranslationService(cfg config.Config, b repository.BookRepository, t repository.TranslationRepository) TranslationService {
+	return TranslationService{BookRepository: b, TranslationRepository: t, DefaultLocale: cfg.DefaultLocale}
+}
+
+func (s *TranslationService) List(ctx context.Context, bookID uint) ([]model.BookTranslation, error) {
+	if _, err := s.BookRepository.GetByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	return s.TranslationRepository.List(ctx, bookID)
+}
+
+// Put 新建或者覆盖图书在 t.Locale 下的翻译，Locale 会被规范化
+func (s *TranslationService) Put(ctx context.Context, t model.BookTranslation) (model.BookTranslation, error) {
+	locale, ok := i18n.Canonical(t.Locale)
+	if !ok || locale == s.DefaultLocale {
+		return t, ErrInvalidLocale
+	}
+	t.Locale = locale
+	if t.Title == "" && t.Description == "" {
+		return t, ErrEmptyTranslation
+	}
+	if _, err := s.BookRepository.GetByID(ctx, t.BookID); err != nil {
+		return t, err
+	}
+	return s.TranslationRepository.Put(ctx, t)
+}
+
+func (s *TranslationService) Delete(ctx context.Context, bookID uint, locale string) error {
+	locale, ok := i18n.Canonical(locale)
+	if !ok {
+		return repository.ErrNotFound
+	}
+	return s.TranslationRepository.Delete(ctx, bookID, locale)
+}
+
+// Localize 按 ctx 中的语言回退链逐个字段选择最靠前的非空翻译，都没有时沿用图书本身的内容。
+// 回退链走到 DefaultLocale 时停止
+func (s *TranslationService) Localize(ctx context.Context, books []model.Book) (Localization, error) {
+	l := Localization{Books: books}
+	var chain []string
+	for _, locale := range i18n.LocalesFromContext(ctx) {
+		if locale == s.DefaultLocale {
+			break
+		}
+		chain = append(chain, locale)
+	}
+	if len(chain) == 0 || len(books) == 0 {
+		l.Locales = []string{s.DefaultLocale}
+		return l, nil
+	}
+
+	ids := make([]uint, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	ts, err := s.TranslationRepository.Find(ctx, ids, chain)
+	if err != nil {
+		return l, err
+	}
+	byBook := make(map[uint]map[string]model.BookTranslation)
+	for _, t := range ts {
+		if byBook[t.BookID] == nil {
+			byBook[t.BookID] = make(map[string]model.BookTranslation)
+		}
+		byBook[t.BookID][t.Locale] = t
+	}
+
+	used := make(map[string]bool)
+	l.Books = make([]model.Book, len(books))
+	for i, book := range books {
+		titled, described := false, false
+		for _, c := range chain {
+			t, ok := byBook[book.ID][c]
+			if !ok {
+				continue
+			}
+			if !titled && t.Title != "" {
+				book.Title, titled = t.Title, true
+				used[c] = true
+			}
+			if !described && t.Description != "" {
+				book.Description, described = t.Description, true
+				used[c] = true
+			}
+			if t.UpdatedAt.After(l.LastModified) {
+				l.LastModified = t.UpdatedAt
+			}
+		}
+		if !titled || !described {
+			used[s.DefaultLocale] = true
+		}
+		l.Books[i] = book
+	}
+	for _, locale := range append(chain, s.DefaultLocale) {
+		if used[locale] {
+			l.Locales = append(l.Locales, locale)
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/i18n"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+var (
+	ErrInvalidWebhookURL = i18n.NewError("webhook.invalid_url")
+	ErrUnknownEventType  = i18n.NewError("webhook.unknown_event_type")
+)
+
+// defaultDeliveryLogLimit 是查询投递日志时默认返回的条数
+const defaultDeliveryLogLimit = 100
+
+type WebhookService struct {
+	SubscriptionRepository repository.SubscriptionRepository
+	DeliveryRepository     repository.DeliveryRepository
+	Clock                  clock.Clock
+}
+
+func NewWebhookService(s repository.SubscriptionRepository, d repository.DeliveryRepository, clk clock.Clock) WebhookService {
+	return WebhookService{SubscriptionRepository: s, DeliveryRepository: d, Clock: clk}
+}
+
+// Subscribe 校验并为 ctx 中的店铺保存订阅，没有指定 secret 时生成一个随机 secret
+func (w *WebhookService) Subscribe(ctx context.Context, sub model.WebhookSubscription) (model.WebhookSubscription, error) {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return sub, ErrInvalidWebhookURL
+	}
+	if sub.EventTypes != "" {
+		for _, t := range strings.Split(sub.EventTypes, ",") {
+			if !model.IsBookEventType(t) {
+				return sub, ErrUnknownEventType
+			}
+		}
+	}
+	if sub.Secret == "" {
+		sub.Secret, err = newSecret()
+		if err != nil {
+			return sub, err
+		}
+	}
+	return w.SubscriptionRepository.Save(ctx, sub)
+}
+
+// GetAll 返回店铺的全部订阅，eventType 不为空时只返回关心该事件的订阅
+func (w *WebhookService) GetAll(ctx context.Context, eventType string) ([]model.WebhookSubscription, error) {
+	subs, err := w.SubscriptionRepository.GetAll(ctx)
+	if err != nil || eventType == "" {
+		return subs, err
+	}
+	filtered := subs[:0]
+	for _, s := range subs {
+		if s.Accepts(eventType) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered, nil
+}
+
+func (w *WebhookService) GetByID(ctx context.Context, id uint) (model.WebhookSubscription, error) {
+	return w.SubscriptionRepository.GetByID(ctx, id)
+}
+
+func (w *WebhookService) Unsubscribe(ctx context.Context, sub model.WebhookSubscription) error {
+	return w.SubscriptionRepository.Delete(ctx, sub)
+}
+
+// Deliveries 返回订阅的投递日志，按时间倒序，status 为空表示不过滤
+func (w *WebhookService) Deliveries(subscriptionID uint, status string) ([]model.WebhookDelivery, error) {
+	return w.DeliveryRepository.ListBySubscription(subscriptionID, status, defaultDeliveryLogLimit)
+}
+
+// webhookPayload 是 POST 给订阅方的请求体
+type webhookPayload struct {
+	EventID   uint            `json:"event_id"`
+	EventType string          `json:"event_type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Enqueue 为事件所属店铺中关心该事件的每个订阅生成一条待推送的投递记录，
+// 签名和推送交给 webhook.Worker。它的签名满足 outbox.PublisherFunc
+func (w *WebhookService) Enqueue(ctx context.Context, event model.OutboxEvent) error {
+	subs, err := w.SubscriptionRepository.ListByTenant(event.TenantID)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(webhookPayload{
+		EventID:   event.ID,
+		EventType: event.EventType,
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return err
+	}
+	for _, s := range subs {
+		if !s.Accepts(event.EventType) {
+			continue
+		}
+		err := w.DeliveryRepository.AddOnce(model.WebhookDelivery{
+			SubscriptionID: s.ID,
+			EventID:        event.ID,
+			EventType:      event.EventType,
+			Payload:        string(body),
+			Status:         model.DeliveryPending,
+			NextAttemptAt:  w.Clock.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
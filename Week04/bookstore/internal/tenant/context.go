@@ -0,0 +1,24 @@
+// Package tenant 在 context 中传递当前请求所属的店铺。
+// 仓储层从 context 取店铺并自动限定查询范围，context 中没有店铺时拒绝访问。
+package tenant
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+)
+
+// ErrNoTenant 表示 context 中没有店铺，仓储层不会执行任何不限定店铺的查询
+var ErrNoTenant = errors.New("no tenant in context")
+
+type tenantKey struct{}
+
+func WithTenant(ctx context.Context, t model.Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+func FromContext(ctx context.Context) (model.Tenant, bool) {
+	t, ok := ctx.Value(tenantKey{}).(model.Tenant)
+	return t, ok && t.ID != 0
+}
@@ -0,0 +1,56 @@
+package tenant
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken 表示 token 格式、签名或有效期不正确
+var ErrInvalidToken = errors.New("invalid token")
+
+// ClaimFromToken 校验 HS256 签名的 JWT，返回其中的 tenant claim
+func ClaimFromToken(secret []byte, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(secret) == 0 || len(parts) != 3 {
+		return "", ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return "", ErrInvalidToken
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", ErrInvalidToken
+	}
+
+	var claims struct {
+		Tenant string `json:"tenant"`
+		Exp    int64  `json:"exp"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", ErrInvalidToken
+	}
+	if claims.Exp != 0 && time.Now().Unix() >= claims.Exp {
+		return "", ErrInvalidToken
+	}
+	return claims.Tenant, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
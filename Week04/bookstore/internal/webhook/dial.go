@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress 表示订阅地址解析到了回环、内网或链路本地地址
+var ErrForbiddenAddress = errors.New("webhook: destination address is not allowed")
+
+// privateNets 是 IsLoopback、IsLinkLocalUnicast 等方法之外不允许访问的网段
+var privateNets = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// forbidden 判断推送能不能连接 ip
+func forbidden(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAddress 是 net.Dialer 的 Control，在 DNS 解析之后、建立连接之前检查要连接的 IP，
+// 域名在检查之后重新解析到其他地址（DNS rebinding）也绕不过去
+func checkAddress(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || forbidden(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// newClient 返回推送用的 http.Client。allowPrivate 为 false 时不允许连接回环、内网和链路本地地址，
+// 防止注册订阅的人借服务访问内网（SSRF）。不使用环境变量中的代理，否则检查的是代理的地址
+func newClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = checkAddress
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
@@ -0,0 +1,4 @@
+package webhook
+
+// CheckAddress 导出给测试使用
+var CheckAddress = checkAddress
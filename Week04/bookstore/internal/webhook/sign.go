@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// 推送请求携带的头部
+const (
+	HeaderSignature = "X-Bookstore-Signature"
+	HeaderEvent     = "X-Bookstore-Event"
+	HeaderDelivery  = "X-Bookstore-Delivery"
+)
+
+// Sign 用订阅的 secret 对请求体计算 HMAC-SHA256，结果形如 sha256=<hex>
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 供接收方校验签名
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
@@ -0,0 +1,183 @@
+// Package webhook 负责把投递记录推送给订阅方。
+// 每次推送都带 HMAC-SHA256 签名，非 2xx 或网络错误按指数退避重试，
+// 超过最大次数后进入死信（dead）状态，不再重试，可以通过投递日志查到。
+// 多个实例同时运行时，每个实例先认领投递再推送，同一个投递不会被同时推送。
+// 推送不会连接回环、内网和链路本地地址，见 newClient。
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository"
+)
+
+const (
+	defaultInterval    = time.Second
+	defaultBatchSize   = 50
+	defaultMaxAttempts = 8
+	defaultBaseBackoff = 10 * time.Second
+	defaultMaxBackoff  = time.Hour
+	defaultClaimTTL    = time.Minute
+)
+
+// claims 为每次认领生成不同的 token
+var claims uint64
+
+// Worker 轮询到期的投递并推送
+type Worker struct {
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// ClaimTTL 是认领的租期，需要比 Client 的超时长
+	ClaimTTL time.Duration
+	Client   *http.Client
+
+	subs       repository.SubscriptionRepository
+	deliveries repository.DeliveryRepository
+	clock      clock.Clock
+	holder     string
+}
+
+func NewWorker(cfg config.Config, subs repository.SubscriptionRepository, deliveries repository.DeliveryRepository,
+	clk clock.Clock) *Worker {
+	host, _ := os.Hostname()
+	return &Worker{
+		Interval:    defaultInterval,
+		BatchSize:   defaultBatchSize,
+		MaxAttempts: defaultMaxAttempts,
+		BaseBackoff: defaultBaseBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+		ClaimTTL:    defaultClaimTTL,
+		Client:      newClient(10*time.Second, cfg.WebhookAllowPrivate),
+		subs:        subs,
+		deliveries:  deliveries,
+		clock:       clk,
+		holder:      fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Run 周期性地推送到期的投递，直到 ctx 被取消
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Deliver(ctx); err != nil {
+			log.Printf("webhook deliver err: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deliver 认领并推送一批到期的投递
+func (w *Worker) Deliver(ctx context.Context) error {
+	token := fmt.Sprintf("%s-%d", w.holder, atomic.AddUint64(&claims, 1))
+	now := w.clock.Now()
+	due, err := w.deliveries.Claim(token, now, now.Add(w.ClaimTTL), w.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func(d model.WebhookDelivery) {
+			defer wg.Done()
+			w.attempt(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (w *Worker) attempt(ctx context.Context, d model.WebhookDelivery) {
+	sub, err := w.subs.Find(d.SubscriptionID)
+	if err == repository.ErrNotFound {
+		d.Status = model.DeliveryDead
+		d.LastError = "subscription deleted"
+		w.save(d)
+		return
+	}
+	if err != nil {
+		log.Printf("load webhook subscription %d err: %v", d.SubscriptionID, err)
+		return
+	}
+
+	d.Attempts++
+	d.LastStatusCode, err = w.post(ctx, sub, d)
+	if err != nil && ctx.Err() != nil {
+		// 正在退出，这次不算失败，租期过后重新推送
+		return
+	}
+	switch {
+	case err == nil:
+		d.Status = model.DeliverySucceeded
+		d.LastError = ""
+	case d.Attempts >= w.MaxAttempts:
+		d.Status = model.DeliveryDead
+		d.LastError = err.Error()
+	default:
+		d.NextAttemptAt = w.clock.Now().Add(w.backoff(d.Attempts))
+		d.LastError = err.Error()
+	}
+	w.save(d)
+}
+
+func (w *Worker) post(ctx context.Context, sub model.WebhookSubscription, d model.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, body))
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(d.ID), 10))
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff 计算第 attempts 次失败后的等待时间：BaseBackoff * 2^(attempts-1)，不超过 MaxBackoff
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= w.MaxBackoff {
+			return w.MaxBackoff
+		}
+	}
+	return d
+}
+
+func (w *Worker) save(d model.WebhookDelivery) {
+	if err := w.deliveries.Update(d); err != nil {
+		log.Printf("update webhook delivery %d err: %v", d.ID, err)
+	}
+}
@@ -0,0 +1,148 @@
+package webhook_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/clock"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/config"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/model"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/repository/memory"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/tenant"
+	"github.com/yngwiewang/Go-000/Week04/bookstore/internal/webhook"
+)
+
+// newStore 创建一个订阅 url 的店铺，并为它写入 n 个待推送的投递
+func newStore(t *testing.T, clk clock.Clock, url string, n int) *memory.Store {
+	t.Helper()
+	store := memory.NewStore(clk)
+	demo, err := store.Tenants.Save(model.Tenant{Slug: "demo", Name: "Demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := store.Subscriptions.Save(tenant.WithTenant(context.Background(), demo),
+		model.WebhookSubscription{URL: url, Secret: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= n; i++ {
+		err := store.Deliveries.AddOnce(model.WebhookDelivery{SubscriptionID: sub.ID, EventID: uint(i),
+			EventType: model.EventBookCreated, Payload: "{}", Status: model.DeliveryPending, NextAttemptAt: clk.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestPrivateAddressRejected(t *testing.T) {
+	var received int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		allowPrivate bool
+		want         string
+	}{
+		{name: "rejected", allowPrivate: false, want: model.DeliveryPending},
+		{name: "allowed for local development", allowPrivate: true, want: model.DeliverySucceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&received, 0)
+			clk := clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+			store := newStore(t, clk, srv.URL, 1)
+			w := webhook.NewWorker(config.Config{WebhookAllowPrivate: tt.allowPrivate}, store.Subscriptions, store.Deliveries, clk)
+			if err := w.Deliver(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			got, err := store.Deliveries.ListBySubscription(1, "", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].Status != tt.want {
+				t.Fatalf("deliveries = %+v, want one %s", got, tt.want)
+			}
+			if n := atomic.LoadInt32(&received); (n == 1) != tt.allowPrivate {
+				t.Fatalf("server received %d requests", n)
+			}
+		})
+	}
+}
+
+// TestWorkersClaimDeliveries 多个实例同时推送时每个投递只推送一次
+func TestWorkersClaimDeliveries(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.Header.Get(webhook.HeaderDelivery)]++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	clk := clock.NewFake(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	const deliveries = 30
+	store := newStore(t, clk, srv.URL, deliveries)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		w := webhook.NewWorker(config.Config{WebhookAllowPrivate: true}, store.Subscriptions, store.Deliveries, clk)
+		w.BatchSize = 4
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := w.Deliver(context.Background()); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(received) != deliveries {
+		t.Fatalf("received %d deliveries, want %d", len(received), deliveries)
+	}
+	for id, n := range received {
+		if n != 1 {
+			t.Fatalf("delivery %s received %d times", id, n)
+		}
+	}
+}
+
+func TestForbiddenAddresses(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "127.0.0.1", want: true},
+		{ip: "::1", want: true},
+		{ip: "10.1.2.3", want: true},
+		{ip: "172.20.0.1", want: true},
+		{ip: "192.168.1.1", want: true},
+		{ip: "169.254.169.254", want: true},
+		{ip: "fe80::1", want: true},
+		{ip: "fd00::1", want: true},
+		{ip: "::ffff:127.0.0.1", want: true},
+		{ip: "0.0.0.0", want: true},
+		{ip: "93.184.216.34", want: false},
+		{ip: "2606:2800:220:1::1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			err := webhook.CheckAddress("tcp", net.JoinHostPort(tt.ip, "443"), nil)
+			if got := err == webhook.ErrForbiddenAddress; got != tt.want {
+				t.Fatalf("CheckAddress(%s) = %v, want forbidden %v", tt.ip, err, tt.want)
+			}
+		})
+	}
+}
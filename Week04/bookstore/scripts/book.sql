@@ -3,10 +3,21 @@ CREATE TABLE `books` (
 	`created_at` DATETIME NULL DEFAULT NULL,
 	`updated_at` DATETIME NULL DEFAULT NULL,
 	`deleted_at` DATETIME NULL DEFAULT NULL,
+	`tenant_id` INT(10) UNSIGNED NOT NULL DEFAULT '0',
 	`isbn` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`title` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`author` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`category` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
 	`price` INT(10) UNSIGNED NULL DEFAULT NULL,
+	`cover_type` VARCHAR(15) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`cover_updated_at` DATETIME NULL DEFAULT NULL,
+	`rating_sum` INT(10) NOT NULL DEFAULT '0',
+	`rating_count` INT(10) NOT NULL DEFAULT '0',
 	PRIMARY KEY (`id`) USING BTREE,
-	INDEX `idx_books_deleted_at` (`deleted_at`) USING BTREE
+	INDEX `idx_books_deleted_at` (`deleted_at`) USING BTREE,
+	INDEX `idx_books_tenant_id` (`tenant_id`) USING BTREE,
+	INDEX `idx_books_author` (`author`) USING BTREE,
+	INDEX `idx_books_category` (`category`) USING BTREE
 )
 COLLATE='utf8mb4_unicode_ci'
 ENGINE=InnoDB
@@ -0,0 +1,27 @@
+CREATE TABLE `categories` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`tenant_id` INT(10) UNSIGNED NOT NULL,
+	`parent_id` INT(10) UNSIGNED NOT NULL DEFAULT '0',
+	`name` VARCHAR(255) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`path` VARCHAR(1000) NOT NULL CHARACTER SET ascii COLLATE 'ascii_bin',
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `idx_categories_sibling_name` (`tenant_id`, `parent_id`, `name`) USING BTREE,
+	INDEX `idx_categories_path` (`path`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
+
+CREATE TABLE `book_categories` (
+	`book_id` INT(10) UNSIGNED NOT NULL,
+	`category_id` INT(10) UNSIGNED NOT NULL,
+	`tenant_id` INT(10) UNSIGNED NOT NULL,
+	PRIMARY KEY (`book_id`, `category_id`) USING BTREE,
+	INDEX `idx_book_categories_category_id` (`category_id`) USING BTREE,
+	INDEX `idx_book_categories_tenant_id` (`tenant_id`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,3 @@
+ALTER TABLE `books`
+	ADD COLUMN `cover_type` VARCHAR(15) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci' AFTER `price`,
+	ADD COLUMN `cover_updated_at` DATETIME NULL DEFAULT NULL AFTER `cover_type`;
@@ -0,0 +1,15 @@
+CREATE TABLE `book_revisions` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`book_id` INT(10) UNSIGNED NOT NULL,
+	`version` INT(10) NOT NULL,
+	`action` VARCHAR(16) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`actor` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`changes` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`snapshot` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`created_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `idx_book_revisions_book_version` (`book_id`, `version`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
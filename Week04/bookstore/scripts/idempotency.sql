@@ -0,0 +1,15 @@
+CREATE TABLE `idempotency_records` (
+	`idempotency_key` VARCHAR(255) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`fingerprint` CHAR(64) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`status_code` INT(10) NOT NULL DEFAULT '0',
+	`content_type` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`body` MEDIUMTEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`completed_at` DATETIME NULL DEFAULT NULL,
+	`expires_at` DATETIME NOT NULL,
+	`created_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`idempotency_key`) USING BTREE,
+	INDEX `idx_idempotency_records_expires_at` (`expires_at`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,2 @@
+ALTER TABLE `books`
+	ADD COLUMN `publisher` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci' AFTER `author`;
@@ -0,0 +1,48 @@
+CREATE TABLE `cart_items` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`tenant_id` INT(10) UNSIGNED NOT NULL,
+	`customer` VARCHAR(255) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`book_id` INT(10) UNSIGNED NOT NULL,
+	`quantity` INT(10) NOT NULL,
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `idx_cart_items_owner_book` (`tenant_id`, `customer`, `book_id`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
+
+CREATE TABLE `orders` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`tenant_id` INT(10) UNSIGNED NOT NULL,
+	`customer` VARCHAR(255) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`status` VARCHAR(15) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`total` DECIMAL(10,2) NOT NULL DEFAULT '0.00',
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	INDEX `idx_orders_tenant_id` (`tenant_id`) USING BTREE,
+	INDEX `idx_orders_customer` (`customer`) USING BTREE,
+	INDEX `idx_orders_status` (`status`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
+
+CREATE TABLE `order_items` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`order_id` INT(10) UNSIGNED NOT NULL,
+	`book_id` INT(10) UNSIGNED NOT NULL,
+	`isbn` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`title` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`base_price` DECIMAL(10,2) NOT NULL DEFAULT '0.00',
+	`unit_price` DECIMAL(10,2) NOT NULL DEFAULT '0.00',
+	`adjustments` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`quantity` INT(10) NOT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	INDEX `idx_order_items_order_id` (`order_id`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,6 @@
+ALTER TABLE `order_items`
+	ADD COLUMN `base_price` DECIMAL(10,2) NOT NULL DEFAULT '0.00' AFTER `title`,
+	ADD COLUMN `adjustments` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci' AFTER `unit_price`;
+
+-- 已有订单结算时没有使用优惠，标价就是单价
+UPDATE `order_items` SET `base_price` = `unit_price`;
@@ -0,0 +1,16 @@
+CREATE TABLE `outbox_events` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`aggregate_id` INT(10) UNSIGNED NOT NULL,
+	`event_type` VARCHAR(64) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`payload` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`attempts` INT(10) NOT NULL DEFAULT '0',
+	`last_error` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`published_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	INDEX `idx_outbox_events_aggregate_id` (`aggregate_id`) USING BTREE,
+	INDEX `idx_outbox_events_published_at` (`published_at`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,6 @@
+ALTER TABLE `outbox_events`
+	ADD COLUMN `next_attempt_at` DATETIME NULL DEFAULT NULL AFTER `last_error`,
+	ADD COLUMN `claim_token` VARCHAR(255) NOT NULL DEFAULT '' COLLATE 'utf8mb4_unicode_ci' AFTER `next_attempt_at`,
+	ADD COLUMN `claimed_until` DATETIME NULL DEFAULT NULL AFTER `claim_token`,
+	ADD COLUMN `dead_at` DATETIME NULL DEFAULT NULL AFTER `published_at`,
+	ADD INDEX `idx_outbox_events_claim_token` (`claim_token`) USING BTREE;
@@ -0,0 +1,21 @@
+CREATE TABLE `payments` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`tenant_id` INT(10) UNSIGNED NOT NULL,
+	`order_id` INT(10) UNSIGNED NOT NULL,
+	`provider_ref` VARCHAR(64) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`reference` VARCHAR(64) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`status` VARCHAR(15) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`amount` DECIMAL(10,2) NOT NULL,
+	`currency` VARCHAR(3) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`decline_code` VARCHAR(64) NOT NULL DEFAULT '' COLLATE 'utf8mb4_unicode_ci',
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `uix_payments_provider_ref` (`provider_ref`) USING BTREE,
+	UNIQUE INDEX `uix_payments_reference` (`reference`) USING BTREE,
+	INDEX `idx_payments_tenant_id` (`tenant_id`) USING BTREE,
+	INDEX `idx_payments_order_id` (`order_id`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
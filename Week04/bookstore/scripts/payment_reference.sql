@@ -0,0 +1,9 @@
+ALTER TABLE `payments`
+	ADD COLUMN `reference` VARCHAR(64) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci' AFTER `provider_ref`;
+
+-- 已有的支付没有记录发给网关的幂等键，用网关上的支付 ID 填充以满足唯一索引
+UPDATE `payments` SET `reference` = `provider_ref`;
+
+ALTER TABLE `payments`
+	MODIFY COLUMN `reference` VARCHAR(64) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	ADD UNIQUE INDEX `uix_payments_reference` (`reference`) USING BTREE;
@@ -0,0 +1,30 @@
+CREATE TABLE `pricing_rules` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	`deleted_at` DATETIME NULL DEFAULT NULL,
+	`tenant_id` INT(10) UNSIGNED NOT NULL DEFAULT '0',
+	`name` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`kind` VARCHAR(31) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`amount` FLOAT NOT NULL DEFAULT '0',
+	`scope` VARCHAR(31) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`target` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`coupon_code` VARCHAR(63) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`priority` INT(10) NOT NULL DEFAULT '0',
+	`exclusive` TINYINT(1) NOT NULL DEFAULT '0',
+	`starts_at` DATETIME NULL DEFAULT NULL,
+	`ends_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	INDEX `idx_pricing_rules_tenant_id` (`tenant_id`) USING BTREE,
+	INDEX `idx_pricing_rules_deleted_at` (`deleted_at`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
+
+ALTER TABLE `books`
+	ADD COLUMN `title` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci' AFTER `isbn`,
+	ADD COLUMN `author` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci' AFTER `title`,
+	ADD COLUMN `category` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci' AFTER `author`,
+	ADD INDEX `idx_books_author` (`author`) USING BTREE,
+	ADD INDEX `idx_books_category` (`category`) USING BTREE;
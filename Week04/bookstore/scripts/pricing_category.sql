@@ -0,0 +1,8 @@
+-- 分类优惠规则的 target 从分类名改为分类 ID，按同一店铺内同名的分类迁移，
+-- 同名分类有多个时取 ID 最小的一个，找不到分类的规则需要手动处理
+UPDATE `pricing_rules` r
+	JOIN (
+		SELECT `tenant_id`, `name`, MIN(`id`) AS `id` FROM `categories` GROUP BY `tenant_id`, `name`
+	) c ON c.`tenant_id` = r.`tenant_id` AND c.`name` = r.`target`
+	SET r.`target` = c.`id`
+	WHERE r.`scope` = 'category';
@@ -0,0 +1,14 @@
+CREATE TABLE `book_recommendations` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`tenant_id` INT(10) UNSIGNED NOT NULL,
+	`book_id` INT(10) UNSIGNED NOT NULL,
+	`related_book_id` INT(10) UNSIGNED NOT NULL,
+	`score` DOUBLE NOT NULL,
+	`computed_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	INDEX `idx_book_recommendations_tenant_id` (`tenant_id`) USING BTREE,
+	INDEX `idx_book_recommendations_book_id` (`book_id`, `score`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,20 @@
+CREATE TABLE `reviews` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`book_id` INT(10) UNSIGNED NOT NULL,
+	`author` VARCHAR(255) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`rating` INT(10) NOT NULL,
+	`text` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`status` VARCHAR(15) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `idx_reviews_book_author` (`book_id`, `author`) USING BTREE,
+	INDEX `idx_reviews_status` (`status`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
+
+ALTER TABLE `books`
+	ADD COLUMN `rating_sum` INT(10) NOT NULL DEFAULT '0' AFTER `cover_updated_at`,
+	ADD COLUMN `rating_count` INT(10) NOT NULL DEFAULT '0' AFTER `rating_sum`;
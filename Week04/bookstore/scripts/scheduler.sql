@@ -0,0 +1,10 @@
+CREATE TABLE `job_leases` (
+	`name` VARCHAR(255) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`holder` VARCHAR(255) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`expires_at` DATETIME NOT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`name`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
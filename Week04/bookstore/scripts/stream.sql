@@ -0,0 +1,2 @@
+ALTER TABLE `outbox_events`
+	ADD COLUMN `tenant_id` INT(10) UNSIGNED NOT NULL DEFAULT '0' AFTER `aggregate_id`;
@@ -0,0 +1,20 @@
+CREATE TABLE `tenants` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	`deleted_at` DATETIME NULL DEFAULT NULL,
+	`slug` VARCHAR(63) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`name` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`max_books` INT(10) NOT NULL DEFAULT '0',
+	`requests_per_second` INT(10) NOT NULL DEFAULT '0',
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `idx_tenants_slug` (`slug`) USING BTREE,
+	INDEX `idx_tenants_deleted_at` (`deleted_at`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
+
+ALTER TABLE `books`
+	ADD COLUMN `tenant_id` INT(10) UNSIGNED NOT NULL DEFAULT '0' AFTER `deleted_at`,
+	ADD INDEX `idx_books_tenant_id` (`tenant_id`) USING BTREE;
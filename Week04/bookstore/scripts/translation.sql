@@ -0,0 +1,19 @@
+ALTER TABLE `books`
+	ADD COLUMN `description` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci' AFTER `title`;
+
+CREATE TABLE `book_translations` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`tenant_id` INT(10) UNSIGNED NOT NULL,
+	`book_id` INT(10) UNSIGNED NOT NULL,
+	`locale` VARCHAR(35) NOT NULL CHARACTER SET ascii COLLATE 'ascii_bin',
+	`title` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`description` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `idx_book_translations_locale` (`book_id`, `locale`) USING BTREE,
+	INDEX `idx_book_translations_tenant_id` (`tenant_id`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,36 @@
+CREATE TABLE `webhook_subscriptions` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	`deleted_at` DATETIME NULL DEFAULT NULL,
+	`url` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`secret` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`event_types` VARCHAR(255) NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	PRIMARY KEY (`id`) USING BTREE,
+	INDEX `idx_webhook_subscriptions_deleted_at` (`deleted_at`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
+
+CREATE TABLE `webhook_deliveries` (
+	`id` INT(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+	`subscription_id` INT(10) UNSIGNED NOT NULL,
+	`event_id` INT(10) UNSIGNED NOT NULL,
+	`event_type` VARCHAR(64) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`payload` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`status` VARCHAR(16) NOT NULL COLLATE 'utf8mb4_unicode_ci',
+	`attempts` INT(10) NOT NULL DEFAULT '0',
+	`next_attempt_at` DATETIME NULL DEFAULT NULL,
+	`last_status_code` INT(10) NOT NULL DEFAULT '0',
+	`last_error` TEXT NULL DEFAULT NULL COLLATE 'utf8mb4_unicode_ci',
+	`created_at` DATETIME NULL DEFAULT NULL,
+	`updated_at` DATETIME NULL DEFAULT NULL,
+	PRIMARY KEY (`id`) USING BTREE,
+	UNIQUE INDEX `idx_webhook_deliveries_subscription_event` (`subscription_id`, `event_id`) USING BTREE,
+	INDEX `idx_webhook_deliveries_status` (`status`) USING BTREE,
+	INDEX `idx_webhook_deliveries_next_attempt_at` (`next_attempt_at`) USING BTREE
+)
+COLLATE='utf8mb4_unicode_ci'
+ENGINE=InnoDB
+;
@@ -0,0 +1,3 @@
+ALTER TABLE `webhook_deliveries`
+	ADD COLUMN `claim_token` VARCHAR(255) NOT NULL DEFAULT '' COLLATE 'utf8mb4_unicode_ci' AFTER `next_attempt_at`,
+	ADD INDEX `idx_webhook_deliveries_claim_token` (`claim_token`) USING BTREE;
@@ -0,0 +1,3 @@
+ALTER TABLE `webhook_subscriptions`
+	ADD COLUMN `tenant_id` INT(10) UNSIGNED NOT NULL DEFAULT '0' AFTER `deleted_at`,
+	ADD INDEX `idx_webhook_subscriptions_tenant_id` (`tenant_id`) USING BTREE;